@@ -0,0 +1,312 @@
+// Package client is the Go client for a cachy cluster.
+//
+// By default it talks to the server's /v1 HTTP API. In direct mode it
+// fetches the ring topology from the server instead and sends every
+// operation straight to the owning cache nodes over gRPC. That saves a
+// network hop and the JSON round trip, but it bypasses the server's loaders.
+//
+//	c, err := client.New(client.Config{Server: "http://localhost:8080", Direct: true})
+//	...
+//	err = c.Set(ctx, "user:42", data, time.Minute)
+//	item, err := c.Get(ctx, "user:42")
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+// The errors returned by the client, in both modes. Test for them with
+// errors.Is.
+var (
+	ErrNotFound      = coordinator.ErrNotFound
+	ErrUnavailable   = coordinator.ErrNodeUnavailable
+	ErrNoNodes       = coordinator.ErrNoNodes
+	ErrEntryTooLarge = coordinator.ErrEntryTooLarge
+	ErrBackend       = coordinator.ErrBackend
+	ErrConflict      = coordinator.ErrConflict
+)
+
+// Consistency is how many replicas must answer a request. Set it per
+// request with WithConsistency; the server's default applies otherwise.
+type Consistency = coordinator.Consistency
+
+const (
+	One    = coordinator.One
+	Quorum = coordinator.Quorum
+	All    = coordinator.All
+)
+
+// WithConsistency returns a context under which requests use level.
+func WithConsistency(ctx context.Context, level Consistency) context.Context {
+	return coordinator.WithConsistency(ctx, level)
+}
+
+type (
+	// Item is a value read from the cache.
+	Item = coordinator.Item
+	// Entry is a key/value pair to write. Flags are only kept in direct
+	// mode.
+	Entry = coordinator.Entry
+	// GetResult is the outcome of one key in an MGet.
+	GetResult = coordinator.GetResult
+	// DeleteResult is the outcome of one key in an MDelete.
+	DeleteResult = coordinator.DeleteResult
+)
+
+// Config configures a Client. Only Server is required.
+type Config struct {
+	// Server is the base URL of the cachy server, e.g.
+	// http://localhost:8080.
+	Server string
+	// Direct routes operations to the cache nodes over gRPC using the ring
+	// topology fetched from Server, which is then only polled for changes
+	// every RefreshInterval (10s) and whenever a node cannot be reached.
+	Direct          bool
+	RefreshInterval time.Duration
+	// HealthInterval is how often each node is health checked in direct
+	// mode (1s). Nodes that miss three checks in a row are skipped.
+	HealthInterval time.Duration
+
+	// Timeout bounds each attempt at an operation (2s). Operations that
+	// fail because no node could be reached are tried Retries more times
+	// (2; negative for none), waiting RetryBackoff (50ms) before the first
+	// retry and twice as long before each further one.
+	Timeout      time.Duration
+	Retries      int
+	RetryBackoff time.Duration
+
+	// PoolSize is the number of idle HTTP connections kept open to the
+	// server (64). In direct mode each node gets a single multiplexed gRPC
+	// connection.
+	PoolSize int
+
+	Logger *slog.Logger // slog.Default() if nil
+}
+
+// transport carries out operations, either through the server or directly
+// against the nodes.
+type transport interface {
+	get(ctx context.Context, key string) (Item, error)
+	set(ctx context.Context, e Entry) error
+	del(ctx context.Context, key string) (bool, error)
+	mget(ctx context.Context, keys []string) []GetResult
+	mset(ctx context.Context, entries []Entry) []error
+	mdelete(ctx context.Context, keys []string) []DeleteResult
+	// setIf writes e only if cond holds, with expected the version
+	// required by ifVersion, and returns the version it was stored at.
+	setIf(ctx context.Context, e Entry, cond condition, expected int64) (int64, error)
+	// unreachable is told when an operation found no node to serve it.
+	unreachable()
+	close()
+}
+
+// condition is what a conditional write requires of the stored key.
+type condition int
+
+const (
+	ifAbsent  condition = iota // not stored
+	ifPresent                  // stored
+	ifVersion                  // stored at the expected version
+)
+
+// Client is safe for concurrent use.
+type Client struct {
+	t       transport
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+}
+
+// New returns a client for cfg. In direct mode it fetches the topology
+// first and fails if the server cannot be reached.
+func New(cfg Config) (*Client, error) {
+	if cfg.Server == "" {
+		return nil, errors.New("client: no server given")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	} else if cfg.Retries == 0 {
+		cfg.Retries = 2
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 50 * time.Millisecond
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 64
+	}
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = 10 * time.Second
+	}
+	if cfg.HealthInterval <= 0 {
+		cfg.HealthInterval = time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	h, err := newHTTPTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{t: h, timeout: cfg.Timeout, retries: cfg.Retries, backoff: cfg.RetryBackoff}
+	if cfg.Direct {
+		if c.t, err = newDirectTransport(cfg, h); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Close releases the client's connections.
+func (c *Client) Close() {
+	c.t.close()
+}
+
+// Get returns the item stored under key, or ErrNotFound.
+func (c *Client) Get(ctx context.Context, key string) (Item, error) {
+	var it Item
+	err := c.do(ctx, func(ctx context.Context) (err error) {
+		it, err = c.t.get(ctx, key)
+		return err
+	})
+	return it, err
+}
+
+// Set stores value under key. A ttl of 0 means the key never expires.
+func (c *Client) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.SetEntry(ctx, Entry{Key: key, Value: value, TTL: ttl})
+}
+
+// SetEntry is Set with a soft ttl and client flags.
+func (c *Client) SetEntry(ctx context.Context, e Entry) error {
+	return c.do(ctx, func(ctx context.Context) error {
+		return c.t.set(ctx, e)
+	})
+}
+
+// Delete removes key and reports whether it was stored.
+func (c *Client) Delete(ctx context.Context, key string) (bool, error) {
+	var found bool
+	err := c.do(ctx, func(ctx context.Context) (err error) {
+		found, err = c.t.del(ctx, key)
+		return err
+	})
+	return found, err
+}
+
+// CompareAndSet stores e only if its key is still at version expected, the
+// Version of an earlier Get, and returns the version it was stored at. It
+// fails with ErrConflict if the key has been written since, and in direct
+// mode with ErrNotFound if it is no longer stored (through the server
+// that is reported as ErrConflict too).
+//
+//	it, err := c.Get(ctx, "counter")
+//	...
+//	_, err = c.CompareAndSet(ctx, client.Entry{Key: "counter", Value: next(it.Value)}, it.Version)
+//	if errors.Is(err, client.ErrConflict) {
+//		// someone else got there first; read again and retry
+//	}
+func (c *Client) CompareAndSet(ctx context.Context, e Entry, expected int64) (int64, error) {
+	return c.setIf(ctx, e, ifVersion, expected)
+}
+
+// SetIfAbsent stores e only if its key is not stored, and returns the
+// version it was stored at, or ErrConflict.
+func (c *Client) SetIfAbsent(ctx context.Context, e Entry) (int64, error) {
+	return c.setIf(ctx, e, ifAbsent, 0)
+}
+
+// SetIfPresent stores e only if its key is stored, and returns the version
+// it was stored at, or ErrNotFound.
+func (c *Client) SetIfPresent(ctx context.Context, e Entry) (int64, error) {
+	return c.setIf(ctx, e, ifPresent, 0)
+}
+
+func (c *Client) setIf(ctx context.Context, e Entry, cond condition, expected int64) (int64, error) {
+	var version int64
+	err := c.do(ctx, func(ctx context.Context) (err error) {
+		version, err = c.t.setIf(ctx, e, cond, expected)
+		return err
+	})
+	return version, err
+}
+
+// MGet reads keys in one batch and returns their results in order. Keys
+// that could not be reached are retried on their own.
+func (c *Client) MGet(ctx context.Context, keys []string) []GetResult {
+	results := once(ctx, c, func(ctx context.Context) []GetResult { return c.t.mget(ctx, keys) })
+	for i := range results {
+		if c.retryable(results[i].Err) {
+			results[i].Item, results[i].Err = c.Get(ctx, keys[i])
+		}
+	}
+	return results
+}
+
+// MSet writes entries in one batch and returns their errors in order.
+func (c *Client) MSet(ctx context.Context, entries []Entry) []error {
+	errs := once(ctx, c, func(ctx context.Context) []error { return c.t.mset(ctx, entries) })
+	for i := range errs {
+		if c.retryable(errs[i]) {
+			errs[i] = c.SetEntry(ctx, entries[i])
+		}
+	}
+	return errs
+}
+
+// MDelete removes keys in one batch and returns their results in order.
+func (c *Client) MDelete(ctx context.Context, keys []string) []DeleteResult {
+	results := once(ctx, c, func(ctx context.Context) []DeleteResult { return c.t.mdelete(ctx, keys) })
+	for i := range results {
+		if c.retryable(results[i].Err) {
+			results[i].Deleted, results[i].Err = c.Delete(ctx, keys[i])
+		}
+	}
+	return results
+}
+
+// once runs a single attempt at a batch operation within the timeout.
+func once[R any](ctx context.Context, c *Client, fn func(ctx context.Context) R) R {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	return fn(ctx)
+}
+
+// do runs fn with a timeout per attempt, retrying while no node can be
+// reached.
+func (c *Client) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	wait := c.backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		actx, cancel := context.WithTimeout(ctx, c.timeout)
+		err = fn(actx)
+		cancel()
+		if !c.retryable(err) || attempt == c.retries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// retryable reports whether err means no node could serve the request, in
+// which case the topology may be out of date.
+func (c *Client) retryable(err error) bool {
+	if errors.Is(err, ErrUnavailable) || errors.Is(err, ErrNoNodes) {
+		c.t.unreachable()
+		return true
+	}
+	return false
+}
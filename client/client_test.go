@@ -0,0 +1,354 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/cache"
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+	"google.golang.org/grpc"
+)
+
+func TestHTTPMode(t *testing.T) {
+	// Each case answers one request the same way however often it is
+	// sent, and checks the request and what the client made of the answer.
+	tests := []struct {
+		name   string
+		call   func(ctx context.Context, c *Client) (any, error)
+		status int
+		header map[string]string
+		body   string
+
+		wantReq string // method and URI of the request
+		wantHdr map[string]string
+		want    any
+		err     error
+	}{
+		{
+			name:    "get",
+			call:    func(ctx context.Context, c *Client) (any, error) { return c.Get(ctx, "a/b") },
+			status:  200,
+			header:  map[string]string{"X-Cache-TTL": "60", "X-Cache-Version": "7", "X-Cache-Stale": "true"},
+			body:    "value",
+			wantReq: "GET /v1/keys/a%2Fb",
+			want:    Item{Value: []byte("value"), TTL: time.Minute, Version: 7, Stale: true},
+		},
+		{
+			name:    "get miss",
+			call:    func(ctx context.Context, c *Client) (any, error) { return c.Get(ctx, "k") },
+			status:  404,
+			body:    `{"error":"key not found"}`,
+			wantReq: "GET /v1/keys/k",
+			want:    Item{},
+			err:     ErrNotFound,
+		},
+		{
+			name: "set",
+			call: func(ctx context.Context, c *Client) (any, error) {
+				return nil, c.SetEntry(ctx, Entry{Key: "k", Value: []byte("v"), TTL: 1500 * time.Millisecond, SoftTTL: time.Second})
+			},
+			status:  204,
+			wantReq: "PUT /v1/keys/k?soft_ttl=1&ttl=2",
+			wantHdr: map[string]string{"Content-Type": "application/octet-stream", "X-Body": "v"},
+		},
+		{
+			name: "set at quorum",
+			call: func(ctx context.Context, c *Client) (any, error) {
+				return nil, c.Set(WithConsistency(ctx, Quorum), "k", []byte("v"), 0)
+			},
+			status:  204,
+			wantReq: "PUT /v1/keys/k",
+			wantHdr: map[string]string{"X-Cache-Consistency": "quorum"},
+		},
+		{
+			name: "compare and set",
+			call: func(ctx context.Context, c *Client) (any, error) {
+				return c.CompareAndSet(ctx, Entry{Key: "k", Value: []byte("v")}, 7)
+			},
+			status:  204,
+			header:  map[string]string{"ETag": `"8"`},
+			wantReq: "PUT /v1/keys/k",
+			wantHdr: map[string]string{"If-Match": `"7"`},
+			want:    int64(8),
+		},
+		{
+			name: "compare and set conflict",
+			call: func(ctx context.Context, c *Client) (any, error) {
+				return c.CompareAndSet(ctx, Entry{Key: "k", Value: []byte("v")}, 7)
+			},
+			status:  412,
+			wantReq: "PUT /v1/keys/k",
+			want:    int64(0),
+			err:     ErrConflict,
+		},
+		{
+			name: "set if absent",
+			call: func(ctx context.Context, c *Client) (any, error) {
+				return c.SetIfAbsent(ctx, Entry{Key: "k", Value: []byte("v")})
+			},
+			status:  412,
+			wantReq: "PUT /v1/keys/k",
+			wantHdr: map[string]string{"If-None-Match": "*"},
+			want:    int64(0),
+			err:     ErrConflict,
+		},
+		{
+			name: "set if present",
+			call: func(ctx context.Context, c *Client) (any, error) {
+				return c.SetIfPresent(ctx, Entry{Key: "k", Value: []byte("v")})
+			},
+			status:  412,
+			wantReq: "PUT /v1/keys/k",
+			wantHdr: map[string]string{"If-Match": "*"},
+			want:    int64(0),
+			err:     ErrNotFound,
+		},
+		{
+			name:    "delete",
+			call:    func(ctx context.Context, c *Client) (any, error) { return c.Delete(ctx, "k") },
+			status:  204,
+			wantReq: "DELETE /v1/keys/k",
+			want:    true,
+		},
+		{
+			name:    "delete miss",
+			call:    func(ctx context.Context, c *Client) (any, error) { return c.Delete(ctx, "k") },
+			status:  404,
+			wantReq: "DELETE /v1/keys/k",
+			want:    false,
+		},
+		{
+			name: "too large",
+			call: func(ctx context.Context, c *Client) (any, error) {
+				return nil, c.Set(ctx, "k", []byte("v"), 0)
+			},
+			status:  413,
+			wantReq: "PUT /v1/keys/k",
+			err:     ErrEntryTooLarge,
+		},
+		{
+			name:    "backend down",
+			call:    func(ctx context.Context, c *Client) (any, error) { return c.Get(ctx, "k") },
+			status:  502,
+			wantReq: "GET /v1/keys/k",
+			want:    Item{},
+			err:     ErrBackend,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			var body []byte
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				body, _ = io.ReadAll(r.Body)
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer ts.Close()
+			c, err := New(Config{Server: ts.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			res, err := tt.call(context.Background(), c)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(res, tt.want) {
+				t.Errorf("result = %+v, want %+v", res, tt.want)
+			}
+			if req := got.Method + " " + got.RequestURI; req != tt.wantReq {
+				t.Errorf("request = %s, want %s", req, tt.wantReq)
+			}
+			for k, v := range tt.wantHdr {
+				h := got.Header.Get(k)
+				if k == "X-Body" {
+					h = string(body)
+				}
+				if h != v {
+					t.Errorf("%s = %q, want %q", k, h, v)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPModeRetries(t *testing.T) {
+	tests := []struct {
+		status   int
+		retries  int
+		requests int32
+		err      error
+	}{
+		{http.StatusServiceUnavailable, 2, 3, ErrUnavailable},
+		{http.StatusServiceUnavailable, -1, 1, ErrUnavailable},
+		{http.StatusBadGateway, 2, 1, ErrBackend},
+		{http.StatusNotFound, 2, 1, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tt.status, tt.retries), func(t *testing.T) {
+			var requests atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer ts.Close()
+			c, err := New(Config{Server: ts.URL, Retries: tt.retries, RetryBackoff: time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			if _, err := c.Get(context.Background(), "k"); !errors.Is(err, tt.err) {
+				t.Errorf("Get = %v, want %v", err, tt.err)
+			}
+			if n := requests.Load(); n != tt.requests {
+				t.Errorf("%d requests, want %d", n, tt.requests)
+			}
+		})
+	}
+}
+
+func TestNewRejectsBadServer(t *testing.T) {
+	for _, server := range []string{"", "localhost:8080", "ftp://host", "http://"} {
+		if _, err := New(Config{Server: server}); err == nil {
+			t.Errorf("New(%q): no error", server)
+		}
+	}
+}
+
+// startCluster serves n cache nodes in-process, and a topology endpoint
+// for them, and returns the nodes and the endpoint's URL.
+func startCluster(t *testing.T, n, replicas int) ([]*cache.CacheNode, string) {
+	t.Helper()
+	var top topology
+	top.Replicas, top.Vnodes = replicas, 16
+	nodes := make([]*cache.CacheNode, n)
+	for i := range nodes {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes[i] = cache.NewCacheNode(cache.Options{Capacity: 1000})
+		srv := grpc.NewServer()
+		cacheNodepb.RegisterCacheServer(srv, nodes[i])
+		go srv.Serve(lis)
+		t.Cleanup(srv.Stop)
+		top.Nodes = append(top.Nodes, struct {
+			Address string `json:"address"`
+			Weight  int    `json:"weight"`
+		}{lis.Addr().String(), 1})
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/topology" {
+			t.Errorf("direct client sent %s %s to the server", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(top)
+	}))
+	t.Cleanup(ts.Close)
+	return nodes, ts.URL
+}
+
+// copies counts the nodes holding key.
+func copies(nodes []*cache.CacheNode, key string) int {
+	n := 0
+	for _, node := range nodes {
+		if res, err := node.Get(context.Background(), &cacheNodepb.GetRequest{Key: key}); err == nil && res.Found {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDirectMode(t *testing.T) {
+	nodes, server := startCluster(t, 3, 2)
+	c, err := New(Config{Server: server, Direct: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ctx := context.Background()
+
+	// Single keys go straight to the nodes, on as many as the server's
+	// replication factor.
+	if err := c.Set(WithConsistency(ctx, All), "k", []byte("v"), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if n := copies(nodes, "k"); n != 2 {
+		t.Errorf("k stored on %d nodes, want 2", n)
+	}
+	it, err := c.Get(ctx, "k")
+	if err != nil || string(it.Value) != "v" || it.TTL <= 0 || it.Version == 0 {
+		t.Fatalf("Get = %+v, %v", it, err)
+	}
+	if _, err := c.CompareAndSet(ctx, Entry{Key: "k", Value: []byte("w")}, it.Version-1); !errors.Is(err, ErrConflict) {
+		t.Errorf("CompareAndSet at an old version = %v, want %v", err, ErrConflict)
+	}
+	if _, err := c.CompareAndSet(ctx, Entry{Key: "k", Value: []byte("w")}, it.Version); err != nil {
+		t.Errorf("CompareAndSet at the current version = %v", err)
+	}
+	if _, err := c.SetIfAbsent(ctx, Entry{Key: "k", Value: []byte("x")}); !errors.Is(err, ErrConflict) {
+		t.Errorf("SetIfAbsent of a stored key = %v, want %v", err, ErrConflict)
+	}
+	if found, err := c.Delete(ctx, "k"); err != nil || !found {
+		t.Errorf("Delete = %v, %v", found, err)
+	}
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want %v", err, ErrNotFound)
+	}
+
+	keys := []string{"a", "b", "c", "d"}
+	entries := make([]Entry, len(keys))
+	for i, k := range keys {
+		entries[i] = Entry{Key: k, Value: []byte(k)}
+	}
+	for i, err := range c.MSet(ctx, entries) {
+		if err != nil {
+			t.Errorf("MSet %s: %v", keys[i], err)
+		}
+	}
+	for i, res := range c.MGet(ctx, append(keys, "missing")) {
+		if i == len(keys) {
+			if !errors.Is(res.Err, ErrNotFound) {
+				t.Errorf("MGet missing = %v, want %v", res.Err, ErrNotFound)
+			}
+			continue
+		}
+		if res.Err != nil || string(res.Value) != keys[i] {
+			t.Errorf("MGet %s = %q, %v", keys[i], res.Value, res.Err)
+		}
+	}
+	for i, res := range c.MDelete(ctx, keys) {
+		if res.Err != nil || !res.Deleted {
+			t.Errorf("MDelete %s = %+v", keys[i], res)
+		}
+	}
+
+	type user struct{ Name string }
+	for _, codec := range []Codec{JSON, Gob} {
+		users := NewTyped[user](c, codec)
+		if err := users.Set(ctx, "user", user{"ann"}, 0); err != nil {
+			t.Fatal(err)
+		}
+		if u, err := users.Get(ctx, "user"); err != nil || u.Name != "ann" {
+			t.Errorf("typed Get = %+v, %v", u, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"time"
+)
+
+// Codec turns typed values into the bytes stored in the cache and back.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSON encodes values with encoding/json, readable by clients in other
+// languages. Gob encodes them with encoding/gob, which is more compact for
+// Go-only use.
+var (
+	JSON Codec = jsonCodec{}
+	Gob  Codec = gobCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// Typed reads and writes values of type T through a codec.
+//
+//	users := client.NewTyped[User](c, client.JSON)
+//	err := users.Set(ctx, "user:42", u, time.Hour)
+//	u, err := users.Get(ctx, "user:42")
+type Typed[T any] struct {
+	c     *Client
+	codec Codec
+}
+
+func NewTyped[T any](c *Client, codec Codec) *Typed[T] {
+	return &Typed[T]{c: c, codec: codec}
+}
+
+// Get decodes the value stored under key, or returns ErrNotFound.
+func (t *Typed[T]) Get(ctx context.Context, key string) (T, error) {
+	var v T
+	it, err := t.c.Get(ctx, key)
+	if err != nil {
+		return v, err
+	}
+	err = t.codec.Unmarshal(it.Value, &v)
+	return v, err
+}
+
+// Set encodes v and stores it under key. A ttl of 0 means the key never
+// expires.
+func (t *Typed[T]) Set(ctx context.Context, key string, v T, ttl time.Duration) error {
+	data, err := t.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return t.c.Set(ctx, key, data, ttl)
+}
+
+// Delete removes key and reports whether it was stored.
+func (t *Typed[T]) Delete(ctx context.Context, key string) (bool, error) {
+	return t.c.Delete(ctx, key)
+}
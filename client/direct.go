@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+// directTransport routes operations to the cache nodes itself, with a copy
+// of the server's ring kept up to date by polling /v1/topology.
+type directTransport struct {
+	*coordinator.Coordinator
+	h        *httpTransport
+	log      *slog.Logger
+	interval time.Duration
+
+	etag             string
+	replicas, vnodes int
+
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// topology is the body of GET /v1/topology.
+type topology struct {
+	Replicas int `json:"replicas"`
+	Vnodes   int `json:"vnodes"`
+	Nodes    []struct {
+		Address string `json:"address"`
+		Weight  int    `json:"weight"`
+	} `json:"nodes"`
+}
+
+func (t *topology) members() []coordinator.Member {
+	members := make([]coordinator.Member, len(t.Nodes))
+	for i, n := range t.Nodes {
+		members[i] = coordinator.Member{Addr: n.Address, Weight: n.Weight}
+	}
+	return members
+}
+
+func newDirectTransport(cfg Config, h *httpTransport) (*directTransport, error) {
+	d := &directTransport{
+		h:        h,
+		log:      cfg.Logger,
+		interval: cfg.RefreshInterval,
+		kick:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	t, err := d.fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("client: fetching topology: %w", err)
+	}
+	d.replicas, d.vnodes = t.Replicas, t.Vnodes
+	d.Coordinator, err = coordinator.NewCoordinator(coordinator.Config{
+		Nodes:          t.members(),
+		Replicas:       t.Replicas,
+		Vnodes:         t.Vnodes,
+		HealthInterval: cfg.HealthInterval,
+		HealthTimeout:  cfg.HealthInterval / 2,
+		DownAfter:      3,
+		Logger:         cfg.Logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("client: %w", err)
+	}
+	go d.run()
+	return d, nil
+}
+
+// fetch reads the topology, or returns nil if it has not changed since the
+// last fetch.
+func (d *directTransport) fetch(ctx context.Context) (*topology, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.h.base+"/v1/topology", nil)
+	if err != nil {
+		return nil, err
+	}
+	if d.etag != "" {
+		req.Header.Set("If-None-Match", d.etag)
+	}
+	res, err := d.h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		return nil, nil
+	case http.StatusOK:
+	default:
+		return nil, statusError(res)
+	}
+	var t topology
+	if err := json.NewDecoder(res.Body).Decode(&t); err != nil {
+		return nil, err
+	}
+	d.etag = res.Header.Get("ETag")
+	return &t, nil
+}
+
+// run refreshes the ring every interval, or sooner when a node could not be
+// reached, until the transport is closed.
+func (d *directTransport) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+		case <-d.kick:
+		}
+		d.refresh()
+	}
+}
+
+func (d *directTransport) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), d.interval)
+	defer cancel()
+	t, err := d.fetch(ctx)
+	if err != nil {
+		d.log.Warn("topology refresh failed", "err", err)
+		return
+	}
+	if t == nil {
+		return
+	}
+	if t.Replicas != d.replicas || t.Vnodes != d.vnodes {
+		// Placement no longer matches the server's; routing may send keys
+		// to the wrong nodes until the client is recreated.
+		d.log.Error("ring parameters changed, recreate the client", "replicas", t.Replicas, "vnodes", t.Vnodes)
+	}
+	d.SetMembers(t.members())
+	d.log.Info("topology updated", "nodes", len(t.Nodes))
+}
+
+func (d *directTransport) get(ctx context.Context, key string) (Item, error) {
+	return d.Get(ctx, key)
+}
+
+func (d *directTransport) set(ctx context.Context, e Entry) error {
+	return d.SetEntry(ctx, e)
+}
+
+func (d *directTransport) del(ctx context.Context, key string) (bool, error) {
+	return d.Delete(ctx, key)
+}
+
+func (d *directTransport) mget(ctx context.Context, keys []string) []GetResult {
+	return d.MGet(ctx, keys)
+}
+
+func (d *directTransport) mset(ctx context.Context, entries []Entry) []error {
+	return d.MSet(ctx, entries)
+}
+
+func (d *directTransport) mdelete(ctx context.Context, keys []string) []DeleteResult {
+	return d.MDelete(ctx, keys)
+}
+
+func (d *directTransport) setIf(ctx context.Context, e Entry, cond condition, expected int64) (int64, error) {
+	switch cond {
+	case ifAbsent:
+		return d.SetIfAbsent(ctx, e)
+	case ifPresent:
+		return d.SetIfPresent(ctx, e)
+	}
+	return d.CompareAndSet(ctx, e, expected)
+}
+
+func (d *directTransport) unreachable() {
+	select {
+	case d.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (d *directTransport) close() {
+	close(d.stop)
+	<-d.done
+	d.Coordinator.Close()
+	d.h.close()
+}
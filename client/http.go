@@ -0,0 +1,243 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+// batchConcurrency bounds the requests one batch operation has in flight
+// against the server. The server's batch endpoints report neither versions
+// nor flags, so batches are sent key by key.
+const batchConcurrency = 16
+
+// httpTransport goes through the server's /v1 API.
+type httpTransport struct {
+	base   string
+	client *http.Client
+}
+
+func newHTTPTransport(cfg Config) (*httpTransport, error) {
+	u, err := url.Parse(cfg.Server)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("client: invalid server url %q", cfg.Server)
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.MaxIdleConns = cfg.PoolSize
+	tr.MaxIdleConnsPerHost = cfg.PoolSize
+	return &httpTransport{
+		base:   strings.TrimSuffix(cfg.Server, "/"),
+		client: &http.Client{Transport: tr},
+	}, nil
+}
+
+func (h *httpTransport) keyURL(key string) string {
+	return h.base + "/v1/keys/" + url.PathEscape(key)
+}
+
+func (h *httpTransport) get(ctx context.Context, key string) (Item, error) {
+	res, err := h.do(ctx, http.MethodGet, h.keyURL(key), nil, nil)
+	if err != nil {
+		return Item{}, err
+	}
+	defer res.Body.Close()
+	value, err := io.ReadAll(res.Body)
+	if err != nil {
+		return Item{}, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	it := Item{
+		Value:      value,
+		Stale:      res.Header.Get("X-Cache-Stale") == "true",
+		Revalidate: res.Header.Get("X-Cache-Revalidate") == "true",
+	}
+	if s, err := strconv.ParseInt(res.Header.Get("X-Cache-TTL"), 10, 64); err == nil {
+		it.TTL = time.Duration(s) * time.Second
+	}
+	it.Version, _ = strconv.ParseInt(res.Header.Get("X-Cache-Version"), 10, 64)
+	return it, nil
+}
+
+func (h *httpTransport) set(ctx context.Context, e Entry) error {
+	res, err := h.put(ctx, e, nil)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (h *httpTransport) setIf(ctx context.Context, e Entry, cond condition, expected int64) (int64, error) {
+	header := http.Header{}
+	switch cond {
+	case ifAbsent:
+		header.Set("If-None-Match", "*")
+	case ifPresent:
+		header.Set("If-Match", "*")
+	default:
+		header.Set("If-Match", `"`+strconv.FormatInt(expected, 10)+`"`)
+	}
+	res, err := h.put(ctx, e, header)
+	if cond == ifPresent && errors.Is(err, ErrConflict) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	res.Body.Close()
+	version, _ := strconv.ParseInt(strings.Trim(res.Header.Get("ETag"), `"`), 10, 64)
+	return version, nil
+}
+
+// put writes e with the given extra request headers.
+func (h *httpTransport) put(ctx context.Context, e Entry, header http.Header) (*http.Response, error) {
+	q := url.Values{}
+	if e.TTL > 0 {
+		q.Set("ttl", strconv.FormatInt(seconds(e.TTL), 10))
+	}
+	if e.SoftTTL > 0 {
+		q.Set("soft_ttl", strconv.FormatInt(seconds(e.SoftTTL), 10))
+	}
+	u := h.keyURL(e.Key)
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return h.do(ctx, http.MethodPut, u, e.Value, header)
+}
+
+func (h *httpTransport) del(ctx context.Context, key string) (bool, error) {
+	res, err := h.do(ctx, http.MethodDelete, h.keyURL(key), nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	return true, nil
+}
+
+func (h *httpTransport) mget(ctx context.Context, keys []string) []GetResult {
+	results := make([]GetResult, len(keys))
+	each(len(keys), func(i int) {
+		results[i].Item, results[i].Err = h.get(ctx, keys[i])
+	})
+	return results
+}
+
+func (h *httpTransport) mset(ctx context.Context, entries []Entry) []error {
+	errs := make([]error, len(entries))
+	each(len(entries), func(i int) {
+		errs[i] = h.set(ctx, entries[i])
+	})
+	return errs
+}
+
+func (h *httpTransport) mdelete(ctx context.Context, keys []string) []DeleteResult {
+	results := make([]DeleteResult, len(keys))
+	each(len(keys), func(i int) {
+		results[i].Deleted, results[i].Err = h.del(ctx, keys[i])
+	})
+	return results
+}
+
+func (h *httpTransport) unreachable() {}
+
+func (h *httpTransport) close() {
+	h.client.CloseIdleConnections()
+}
+
+// do sends a request with the given extra headers and returns the response
+// if it succeeded. Failures are mapped onto the client's errors.
+func (h *httpTransport) do(ctx context.Context, method, u string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	if level, ok := coordinator.ConsistencyFrom(ctx); ok {
+		req.Header.Set("X-Cache-Consistency", level.String())
+	}
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if res.StatusCode/100 == 2 {
+		return res, nil
+	}
+	defer res.Body.Close()
+	return nil, statusError(res)
+}
+
+// serverError is an error reported by the server. It matches the sentinel
+// for its status with errors.Is.
+type serverError struct {
+	sentinel error
+	msg      string
+}
+
+func (e *serverError) Error() string { return e.msg }
+func (e *serverError) Unwrap() error { return e.sentinel }
+
+func statusError(res *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(res.Body, 64<<10)).Decode(&body)
+	if body.Error == "" {
+		body.Error = res.Status
+	}
+
+	var sentinel error
+	switch res.StatusCode {
+	case http.StatusNotFound:
+		sentinel = ErrNotFound
+	case http.StatusRequestEntityTooLarge:
+		sentinel = ErrEntryTooLarge
+	case http.StatusServiceUnavailable:
+		sentinel = ErrUnavailable
+	case http.StatusBadGateway:
+		sentinel = ErrBackend
+	case http.StatusPreconditionFailed:
+		sentinel = ErrConflict
+	default:
+		return fmt.Errorf("server returned %s: %s", res.Status, body.Error)
+	}
+	return &serverError{sentinel: sentinel, msg: body.Error}
+}
+
+// each calls fn for 0 <= i < n, at most batchConcurrency at a time.
+func each(n int, fn func(i int)) {
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// seconds rounds d up to whole seconds, the resolution of the HTTP API.
+func seconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
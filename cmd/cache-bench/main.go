@@ -0,0 +1,199 @@
+// cache-bench measures cache nodes in-process.
+//
+// In the default hitratio mode it replays a key access trace against every
+// eviction policy and reports the hit ratio each one achieves. Each line of a
+// trace file is one request; the first whitespace separated field is the key
+// and lines starting with # are ignored. Without -trace a synthetic workload
+// is generated: a Zipf-distributed hot set interrupted by sequential scans
+// over cold keys, which is the pattern that flushes a plain LRU.
+//
+// In throughput mode it hammers a node from GOMAXPROCS goroutines with a
+// 90/10 get/set mix for each -cpu value, once with a single shard and once
+// with -shards, to show how sharding lets throughput scale with cores.
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/cache"
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+)
+
+func main() {
+	mode := flag.String("mode", "hitratio", "hitratio or throughput")
+	cpus := flag.String("cpu", "1,2,4,8", "throughput: comma separated GOMAXPROCS values")
+	shards := flag.Int("shards", 16, "throughput: shard count compared against a single shard")
+	duration := flag.Duration("duration", time.Second, "throughput: how long to run each configuration")
+	tracePath := flag.String("trace", "", "trace file to replay (default: synthetic zipf+scan workload)")
+	writeTrace := flag.String("write-trace", "", "write the synthetic trace to this file and exit")
+	capacity := flag.Int("capacity", 1000, "cache capacity in entries")
+	requests := flag.Int("requests", 200000, "synthetic trace length")
+	keys := flag.Int("keys", 10000, "distinct keys in the synthetic hot set")
+	scanLen := flag.Int("scan", 5000, "length of each synthetic scan over cold keys")
+	scanEvery := flag.Int("scan-every", 20000, "requests between synthetic scans")
+	seed := flag.Int64("seed", 1, "random seed for the synthetic trace")
+	flag.Parse()
+
+	// Keep the report readable.
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+
+	if *mode == "throughput" {
+		throughput(*cpus, *shards, *capacity, *keys, *duration)
+		return
+	}
+
+	var trace []string
+	if *tracePath != "" {
+		t, err := readTrace(*tracePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "reading trace: %v\n", err)
+			os.Exit(1)
+		}
+		trace = t
+	} else {
+		trace = syntheticTrace(*requests, *keys, *scanLen, *scanEvery, *seed)
+	}
+
+	if *writeTrace != "" {
+		if err := os.WriteFile(*writeTrace, []byte(strings.Join(trace, "\n")+"\n"), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "writing trace: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Printf("requests=%d capacity=%d\n", len(trace), *capacity)
+	fmt.Printf("%-8s %10s %10s %9s\n", "policy", "hits", "misses", "hit ratio")
+	for _, p := range cache.EvictionPolicies {
+		hits, misses := replay(trace, cache.Options{Capacity: *capacity, Eviction: p})
+		fmt.Printf("%-8s %10d %10d %8.2f%%\n", p, hits, misses, 100*float64(hits)/float64(hits+misses))
+	}
+}
+
+// replay runs trace against a fresh node as a cache-aside client would,
+// setting every key that misses.
+func replay(trace []string, opts cache.Options) (hits, misses int) {
+	ctx := context.Background()
+	node := cache.NewCacheNode(opts)
+	value := []byte("x")
+	for _, key := range trace {
+		res, err := node.Get(ctx, &cacheNodepb.GetRequest{Key: key})
+		if err == nil && res.Found {
+			hits++
+			continue
+		}
+		misses++
+		node.Set(ctx, &cacheNodepb.SetRequest{Key: key, Value: value})
+	}
+	return hits, misses
+}
+
+func readTrace(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var trace []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		trace = append(trace, strings.Fields(line)[0])
+	}
+	return trace, sc.Err()
+}
+
+func syntheticTrace(requests, keys, scanLen, scanEvery int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	zipf := rand.NewZipf(rng, 1.1, 1, uint64(keys-1))
+	trace := make([]string, 0, requests)
+	cold := 0
+	for len(trace) < requests {
+		if scanEvery > 0 && len(trace) > 0 && len(trace)%scanEvery == 0 {
+			for i := 0; i < scanLen && len(trace) < requests; i++ {
+				trace = append(trace, fmt.Sprintf("cold:%d", cold))
+				cold++
+			}
+		}
+		trace = append(trace, fmt.Sprintf("hot:%d", zipf.Uint64()))
+	}
+	return trace
+}
+
+func throughput(cpus string, shards, capacity, keys int, d time.Duration) {
+	names := make([]string, keys)
+	for i := range names {
+		names[i] = "key:" + strconv.Itoa(i)
+	}
+
+	fmt.Printf("capacity=%d keys=%d duration=%s\n", capacity, keys, d)
+	fmt.Printf("%-10s %-8s %14s\n", "GOMAXPROCS", "shards", "ops/sec")
+	for _, field := range strings.Split(cpus, ",") {
+		procs, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || procs < 1 {
+			fmt.Fprintf(os.Stderr, "invalid -cpu value %q\n", field)
+			os.Exit(1)
+		}
+		prev := runtime.GOMAXPROCS(procs)
+		for _, n := range []int{1, shards} {
+			ops := hammer(cache.Options{Capacity: capacity, Shards: n}, names, procs, d)
+			fmt.Printf("%-10d %-8d %14.0f\n", procs, n, float64(ops)/d.Seconds())
+		}
+		runtime.GOMAXPROCS(prev)
+	}
+}
+
+// hammer runs workers goroutines against a fresh node for d and returns the
+// number of operations completed.
+func hammer(opts cache.Options, names []string, workers int, d time.Duration) int64 {
+	ctx := context.Background()
+	node := cache.NewCacheNode(opts)
+	value := []byte("x")
+	for _, k := range names {
+		node.Set(ctx, &cacheNodepb.SetRequest{Key: k, Value: value})
+	}
+
+	var (
+		ops  atomic.Int64
+		stop atomic.Bool
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			var n int64
+			for !stop.Load() {
+				k := names[rng.Intn(len(names))]
+				if rng.Intn(10) == 0 {
+					node.Set(ctx, &cacheNodepb.SetRequest{Key: k, Value: value})
+				} else {
+					node.Get(ctx, &cacheNodepb.GetRequest{Key: k})
+				}
+				n++
+			}
+			ops.Add(n)
+		}(int64(w))
+	}
+	time.Sleep(d)
+	stop.Store(true)
+	wg.Wait()
+	return ops.Load()
+}
@@ -3,30 +3,168 @@ package main
 import (
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sakshamg567/cachy/internal/cache"
+	"github.com/sakshamg567/cachy/internal/logging"
 	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func main() {
 	port := flag.String("port", "50051", "port to run cache node on")
+	sweepInterval := flag.Duration("sweep-interval", 100*time.Millisecond, "how often to expire keys in the background (0 disables)")
+	capacity := flag.Int("capacity", 100, "maximum number of entries (0 for no limit)")
+	maxMemory := byteSize(64 << 20)
+	flag.Var(&maxMemory, "max-memory", "maximum memory for keys, values and per-entry overhead, e.g. 512MB (0 for no limit)")
+	maxEntrySize := byteSize(1 << 20)
+	flag.Var(&maxEntrySize, "max-entry-size", "largest single entry accepted, e.g. 1MB (0 for no limit)")
+	tombstoneGrace := flag.Duration("tombstone-grace", time.Hour, "how long deletes are remembered so replicas that missed them are repaired; keep it above the server's anti-entropy interval (0 keeps none)")
+	shards := flag.Int("shards", 16, "number of independently locked cache partitions")
+	snapshotPath := flag.String("snapshot", "", "file to save the cache to and warm it from on startup (disabled if empty)")
+	snapshotInterval := flag.Duration("snapshot-interval", 5*time.Minute, "how often to write the snapshot (0 writes it only on shutdown)")
+	aofPath := flag.String("aof", "", "append-only log recording every write, replayed on startup (disabled if empty)")
+	aofFsync := flag.String("aof-fsync", string(cache.FsyncEverySec), "when to fsync the append-only log: always, everysec or never")
+	aofRewriteSize := byteSize(64 << 20)
+	flag.Var(&aofRewriteSize, "aof-rewrite-size", "compact the append-only log once it is this big and has doubled (0 disables)")
+	metricsAddr := flag.String("metrics-addr", "", "address to serve Prometheus metrics on at /metrics, e.g. :9100 (disabled if empty)")
+	eviction := flag.String("eviction", string(cache.PolicyLRU), "eviction policy: lru, lfu, arc or tinylfu")
+	logFlags := logging.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
-	// Add a distinctive prefix; keep standard flags (date/time)
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-	log.SetPrefix(fmt.Sprintf("[cache-%s] ", *port))
+	logger, err := logFlags.Logger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid logging flags: %v\n", err)
+		os.Exit(2)
+	}
+	logger = logger.With("service", "cache-node", "port", *port)
+	slog.SetDefault(logger)
+
+	policy, err := cache.ParseEvictionPolicy(*eviction)
+	if err != nil {
+		logging.Fatal(logger, "invalid --eviction", "err", err)
+	}
+
+	fsync, err := cache.ParseFsyncPolicy(*aofFsync)
+	if err != nil {
+		logging.Fatal(logger, "invalid --aof-fsync", "err", err)
+	}
+
+	if *shards > 1 && maxMemory > 0 && (maxEntrySize == 0 || int64(maxEntrySize) > int64(maxMemory)/int64(*shards)) {
+		logger.Warn("entries larger than max-memory/shards will be rejected", "limit_bytes", int64(maxMemory)/int64(*shards))
+	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", *port))
 	if err != nil {
-		log.Fatalf("failed to listen : %v", err)
+		logging.Fatal(logger, "failed to listen", "err", err)
 	}
 
-	grpcServer := grpc.NewServer()
-	node := cache.NewCacheNode(100)
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(cache.UnaryServerInterceptor), grpc.StreamInterceptor(cache.StreamServerInterceptor))
+	node := cache.NewCacheNode(cache.Options{
+		Capacity:      *capacity,
+		MaxMemory:     int64(maxMemory),
+		MaxEntrySize:  int64(maxEntrySize),
+		Shards:        *shards,
+		Eviction:      policy,
+		SweepInterval: *sweepInterval,
+
+		TombstoneGrace: *tombstoneGrace,
+
+		SnapshotPath:     *snapshotPath,
+		SnapshotInterval: *snapshotInterval,
+
+		AOFPath:        *aofPath,
+		AOFFsync:       fsync,
+		AOFRewriteSize: int64(aofRewriteSize),
+
+		Logger: logger,
+	})
 	cacheNodepb.RegisterCacheServer(grpcServer, node)
-	log.Printf("starting capacity=%d", 100)
-	log.Fatal(grpcServer.Serve(lis))
+
+	// The coordinator heartbeats nodes through the standard health service.
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(cacheNodepb.Cache_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
+
+	if *metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		go func() {
+			err := http.ListenAndServe(*metricsAddr, mux)
+			logging.Fatal(logger, "metrics listener failed", "err", err)
+		}()
+	}
+
+	// On SIGINT/SIGTERM stop taking requests, then save the cache so the
+	// next start is warm.
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+		<-sig
+		logger.Info("shutting down")
+		healthServer.Shutdown()
+		grpcServer.GracefulStop()
+	}()
+
+	logger.Info("starting", "capacity", *capacity, "max_memory", maxMemory.String(), "max_entry_size", maxEntrySize.String(), "shards", *shards, "eviction", string(policy), "sweep_interval", sweepInterval.String())
+	if err := grpcServer.Serve(lis); err != nil {
+		logging.Fatal(logger, "grpc server failed", "err", err)
+	}
+	if err := node.Snapshot(); err != nil {
+		logging.Fatal(logger, "snapshot failed", "err", err)
+	}
+	if err := node.Close(); err != nil {
+		logging.Fatal(logger, "closing append-only log failed", "err", err)
+	}
+}
+
+// byteSize is a flag value accepting plain byte counts or sizes with a KB,
+// MB or GB suffix (powers of 1024).
+type byteSize int64
+
+var sizeUnits = []struct {
+	suffix string
+	mult   int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+func (b *byteSize) String() string {
+	for _, u := range sizeUnits {
+		if int64(*b) >= u.mult && int64(*b)%u.mult == 0 {
+			return strconv.FormatInt(int64(*b)/u.mult, 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(s string) error {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSuffix(s, u.suffix), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q", s)
+	}
+	*b = byteSize(n * mult)
+	return nil
 }
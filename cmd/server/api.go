@@ -0,0 +1,679 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+const (
+	// maxKeyLen bounds key size so a key can never dominate an entry's memory.
+	maxKeyLen = 1024
+	// maxBatchKeys bounds the number of keys in one batch request.
+	maxBatchKeys = 1000
+	// maxTTL is the longest ttl, in seconds, that fits a time.Duration.
+	maxTTL = int64(math.MaxInt64 / time.Second)
+)
+
+// api serves the versioned, resource-oriented HTTP interface under /v1.
+type api struct {
+	cd           *coordinator.Coordinator
+	maxBodyBytes int64
+}
+
+func (a *api) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/keys/{key}", a.getKey)
+	mux.HandleFunc("HEAD /v1/keys/{key}", a.headKey)
+	mux.HandleFunc("PUT /v1/keys/{key}", a.putKey)
+	mux.HandleFunc("DELETE /v1/keys/{key}", a.deleteKey)
+	mux.HandleFunc("/v1/keys/{key}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "GET, HEAD, PUT, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	})
+
+	mux.HandleFunc("POST /v1/keys/{key}/incr", a.incrKey)
+	mux.HandleFunc("POST /v1/keys/{key}/decr", a.decrKey)
+	for _, op := range []string{"incr", "decr"} {
+		mux.HandleFunc("/v1/keys/{key}/"+op, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", "POST")
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		})
+	}
+
+	mux.HandleFunc("POST /v1/mget", a.mget)
+	mux.HandleFunc("POST /v1/mset", a.mset)
+	mux.HandleFunc("POST /v1/mdelete", a.mdelete)
+
+	mux.HandleFunc("GET /v1/topology", a.topology)
+}
+
+// GET /v1/keys/{key} returns the raw value. The remaining ttl, in seconds, is
+// reported in X-Cache-TTL for keys that expire, and the write version in
+// X-Cache-Version and, quoted, as the ETag. A request whose If-None-Match
+// names the current version gets 304. A value past its soft ttl is marked
+// with X-Cache-Stale, and the one caller expected to refresh it also gets
+// X-Cache-Revalidate.
+func (a *api) getKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	item, err := a.cd.Get(r.Context(), key)
+	if err != nil {
+		writeCacheError(w, err)
+		return
+	}
+	if item.Version != 0 {
+		if v, ok := parseETag(r.Header.Get("If-None-Match")); ok && v == item.Version {
+			w.Header().Set("ETag", etag(item.Version))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	writeItem(w, item)
+}
+
+// writeItem sends item's value with the headers GET describes.
+func writeItem(w http.ResponseWriter, item coordinator.Item) {
+	w.Header().Set("Content-Type", octetStream)
+	if s := item.TTLSeconds(); s > 0 {
+		w.Header().Set("X-Cache-TTL", strconv.FormatInt(s, 10))
+	}
+	if item.Version != 0 {
+		w.Header().Set("X-Cache-Version", strconv.FormatInt(item.Version, 10))
+		w.Header().Set("ETag", etag(item.Version))
+	}
+	if item.Stale {
+		w.Header().Set("X-Cache-Stale", "true")
+	}
+	if item.Revalidate {
+		w.Header().Set("X-Cache-Revalidate", "true")
+	}
+	w.Write(item.Value)
+}
+
+// HEAD /v1/keys/{key} answers 200 or 404 without transferring the value.
+func (a *api) headKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	found, err := a.cd.Exists(r.Context(), key)
+	if err != nil {
+		writeCacheError(w, err)
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// PUT /v1/keys/{key} stores the request body. An application/json body is
+// read as {"value": "...", "ttl": seconds, "soft_ttl": seconds}, with
+// "encoding": "base64" for a base64 value; anything else is stored as is,
+// with the ttls taken from the query string. After
+// soft_ttl the value is still served, but marked stale.
+//
+// The write can be made conditional: If-Match with the ETag of an earlier
+// GET only writes if the key is still at that version, If-Match: * only if
+// the key is stored and If-None-Match: * only if it is not. A conditional
+// write that does not go ahead answers 412; one that does returns the new
+// ETag.
+func (a *api) putKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	ifMatch, ifNoneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+	var expected int64
+	switch {
+	case ifMatch != "" && ifNoneMatch != "":
+		writeError(w, http.StatusBadRequest, errors.New("If-Match and If-None-Match are exclusive"))
+		return
+	case ifNoneMatch != "" && ifNoneMatch != "*":
+		writeError(w, http.StatusBadRequest, errors.New("If-None-Match on a write must be *"))
+		return
+	case ifMatch != "" && ifMatch != "*":
+		if expected, ok = parseETag(ifMatch); !ok {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid If-Match %q", ifMatch))
+			return
+		}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, a.maxBodyBytes)
+
+	var (
+		value        []byte
+		ttl, softTTL int64
+		err          error
+	)
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "application/json" {
+		var body struct {
+			Value    string `json:"value"`
+			Encoding string `json:"encoding"`
+			TTL      int64  `json:"ttl"`
+			SoftTTL  int64  `json:"soft_ttl"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeBodyError(w, err)
+			return
+		}
+		if value, err = decodeValue(body.Value, body.Encoding); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		ttl, softTTL = body.TTL, body.SoftTTL
+	} else {
+		if ttl, err = queryInt(r, "ttl"); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if softTTL, err = queryInt(r, "soft_ttl"); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		value, err = io.ReadAll(r.Body)
+		if err != nil {
+			writeBodyError(w, err)
+			return
+		}
+	}
+	e := coordinator.Entry{Key: key, Value: value}
+	if e.TTL, err = ttlSeconds("ttl", ttl); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if e.SoftTTL, err = ttlSeconds("soft_ttl", softTTL); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var version int64
+	switch {
+	case ifNoneMatch == "*":
+		version, err = a.cd.SetIfAbsent(r.Context(), e)
+	case ifMatch == "*":
+		version, err = a.cd.SetIfPresent(r.Context(), e)
+	case ifMatch != "":
+		version, err = a.cd.CompareAndSet(r.Context(), e, expected)
+	default:
+		err = a.cd.SetEntry(r.Context(), e)
+	}
+	if err != nil {
+		if (ifMatch != "" || ifNoneMatch != "") && (errors.Is(err, coordinator.ErrNotFound) || errors.Is(err, coordinator.ErrConflict)) {
+			writeError(w, http.StatusPreconditionFailed, err)
+			return
+		}
+		writeCacheError(w, err)
+		return
+	}
+	if version != 0 {
+		w.Header().Set("ETag", etag(version))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /v1/keys/{key}/incr and /v1/keys/{key}/decr atomically add by, 1 if
+// absent, to the integer stored under key, or subtract it, and return the
+// new value as GET does. by may be negative; a fractional by or initial
+// treats the value as a floating-point number. A missing key counts as
+// initial, 0 if absent, and is created to expire after ttl seconds, unless
+// create=false makes it a 404. A value that is not a number answers 409,
+// and a result that does not fit a signed 64-bit integer, or is not
+// finite, 422.
+func (a *api) incrKey(w http.ResponseWriter, r *http.Request) {
+	a.counter(w, r, false)
+}
+
+func (a *api) decrKey(w http.ResponseWriter, r *http.Request) {
+	a.counter(w, r, true)
+}
+
+func (a *api) counter(w http.ResponseWriter, r *http.Request, decr bool) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	opts := coordinator.CounterOptions{Create: true}
+	if v := r.URL.Query().Get("create"); v != "" {
+		create, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("invalid create"))
+			return
+		}
+		opts.Create = create
+	}
+	ttl, err := queryInt(r, "ttl")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if opts.TTL, err = ttlSeconds("ttl", ttl); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	by, initial := r.URL.Query().Get("by"), r.URL.Query().Get("initial")
+	if by == "" {
+		by = "1"
+	}
+	if initial == "" {
+		initial = "0"
+	}
+	n, berr := strconv.ParseInt(by, 10, 64)
+	start, ierr := strconv.ParseInt(initial, 10, 64)
+	var item coordinator.Item
+	if berr == nil && ierr == nil {
+		opts.Initial = start
+		neg := n < 0
+		delta := uint64(n)
+		if neg {
+			delta = -delta
+		}
+		if neg != decr {
+			item, err = a.cd.Decr(r.Context(), key, delta, opts)
+		} else {
+			item, err = a.cd.Incr(r.Context(), key, delta, opts)
+		}
+	} else {
+		f, ok := parseFinite(by)
+		if !ok {
+			writeError(w, http.StatusBadRequest, errors.New("invalid by"))
+			return
+		}
+		start, ok := parseFinite(initial)
+		if !ok {
+			writeError(w, http.StatusBadRequest, errors.New("invalid initial"))
+			return
+		}
+		if decr {
+			f = -f
+		}
+		item, err = a.cd.IncrByFloat(r.Context(), key, f, coordinator.FloatCounterOptions{Create: opts.Create, Initial: start, TTL: opts.TTL})
+	}
+	if err != nil {
+		writeCacheError(w, err)
+		return
+	}
+	writeItem(w, item)
+}
+
+// parseFinite parses s as a finite floating-point number.
+func parseFinite(s string) (float64, bool) {
+	f, err := strconv.ParseFloat(s, 64)
+	return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// etag is the entity tag for a value at version.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// parseETag reads the version out of a single entity tag.
+func parseETag(tag string) (int64, bool) {
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64)
+	return v, err == nil
+}
+
+// DELETE /v1/keys/{key} answers 204 if the key was removed and 404 if it
+// was not stored.
+func (a *api) deleteKey(w http.ResponseWriter, r *http.Request) {
+	key, ok := pathKey(w, r)
+	if !ok {
+		return
+	}
+	found, err := a.cd.Delete(r.Context(), key)
+	if err != nil {
+		writeCacheError(w, err)
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, coordinator.ErrNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// batchResult is the per-key outcome in a batch response. Status is the
+// HTTP status the equivalent single-key request would have returned.
+type batchResult struct {
+	Key      string `json:"key"`
+	Status   int    `json:"status"`
+	Value    string `json:"value,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	TTL      int64  `json:"ttl,omitempty"`
+	Stale    bool   `json:"stale,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func newBatchResult(key string, okStatus int, err error) batchResult {
+	if err != nil {
+		return batchResult{Key: key, Status: statusFor(err), Error: err.Error()}
+	}
+	return batchResult{Key: key, Status: okStatus}
+}
+
+// POST /v1/mget with {"keys": [...]} answers {"results": [...]}, one result
+// per key in request order. With "encoding": "base64" every value is sent
+// in base64; otherwise only values that are not valid UTF-8 are, and their
+// results say "encoding": "base64".
+func (a *api) mget(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Keys     []string `json:"keys"`
+		Encoding string   `json:"encoding"`
+	}
+	if !a.decodeBatch(w, r, &body, func() []string { return body.Keys }) {
+		return
+	}
+	if body.Encoding != "" && body.Encoding != base64Encoding {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown encoding %q", body.Encoding))
+		return
+	}
+
+	items := a.cd.MGet(r.Context(), body.Keys)
+	results := make([]batchResult, len(items))
+	for i, it := range items {
+		res := newBatchResult(body.Keys[i], http.StatusOK, it.Err)
+		if it.Err == nil {
+			res.Value, res.Encoding = encodeValue(it.Value, body.Encoding == base64Encoding)
+			res.TTL, res.Stale = it.TTLSeconds(), it.Stale
+		}
+		results[i] = res
+	}
+	writeBatch(w, results)
+}
+
+// POST /v1/mset with {"items": [{"key", "value", "encoding", "ttl",
+// "soft_ttl"}, ...]}, where an item's encoding is "base64" for a base64
+// value.
+func (a *api) mset(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Items []struct {
+			Key      string `json:"key"`
+			Value    string `json:"value"`
+			Encoding string `json:"encoding"`
+			TTL      int64  `json:"ttl"`
+			SoftTTL  int64  `json:"soft_ttl"`
+		} `json:"items"`
+	}
+	keys := func() []string {
+		keys := make([]string, len(body.Items))
+		for i, it := range body.Items {
+			keys[i] = it.Key
+		}
+		return keys
+	}
+	if !a.decodeBatch(w, r, &body, keys) {
+		return
+	}
+
+	entries := make([]coordinator.Entry, len(body.Items))
+	for i, it := range body.Items {
+		e := coordinator.Entry{Key: it.Key}
+		var err error
+		if e.TTL, err = ttlSeconds("ttl", it.TTL); err == nil {
+			if e.SoftTTL, err = ttlSeconds("soft_ttl", it.SoftTTL); err == nil {
+				e.Value, err = decodeValue(it.Value, it.Encoding)
+			}
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("items[%d]: %w", i, err))
+			return
+		}
+		entries[i] = e
+	}
+
+	errs := a.cd.MSet(r.Context(), entries)
+	results := make([]batchResult, len(errs))
+	for i, err := range errs {
+		results[i] = newBatchResult(entries[i].Key, http.StatusNoContent, err)
+	}
+	writeBatch(w, results)
+}
+
+// POST /v1/mdelete with {"keys": [...]}.
+func (a *api) mdelete(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Keys []string `json:"keys"`
+	}
+	if !a.decodeBatch(w, r, &body, func() []string { return body.Keys }) {
+		return
+	}
+
+	deleted := a.cd.MDelete(r.Context(), body.Keys)
+	results := make([]batchResult, len(deleted))
+	for i, d := range deleted {
+		err := d.Err
+		if err == nil && !d.Deleted {
+			err = coordinator.ErrNotFound
+		}
+		results[i] = newBatchResult(body.Keys[i], http.StatusNoContent, err)
+	}
+	writeBatch(w, results)
+}
+
+// GET /v1/topology describes the ring for clients that route to the nodes
+// themselves: {"replicas", "vnodes", "nodes": [{"address", "weight",
+// "state"}]}. The ETag changes only with the placement of keys, so clients
+// can poll it cheaply with If-None-Match.
+func (a *api) topology(w http.ResponseWriter, r *http.Request) {
+	type nodeInfo struct {
+		Address string `json:"address"`
+		Weight  int    `json:"weight"`
+		State   string `json:"state"`
+	}
+	t := a.cd.Topology()
+	out := struct {
+		Replicas int        `json:"replicas"`
+		Vnodes   int        `json:"vnodes"`
+		Nodes    []nodeInfo `json:"nodes"`
+	}{Replicas: t.Replicas, Vnodes: t.Vnodes, Nodes: make([]nodeInfo, len(t.Nodes))}
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d/%d", t.Replicas, t.Vnodes)
+	for i, n := range t.Nodes {
+		out.Nodes[i] = nodeInfo{Address: n.Addr, Weight: n.Weight, State: n.State.String()}
+		fmt.Fprintf(h, ",%s=%d", n.Addr, n.Weight)
+	}
+	etag := fmt.Sprintf(`"%x"`, h.Sum64())
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// decodeBatch reads a batch request body into v and validates the keys it
+// names, writing an error response and returning false if it is unusable.
+func (a *api) decodeBatch(w http.ResponseWriter, r *http.Request, v any, keys func() []string) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, a.maxBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeBodyError(w, err)
+		return false
+	}
+	ks := keys()
+	switch {
+	case len(ks) == 0:
+		writeError(w, http.StatusBadRequest, errors.New("no keys given"))
+		return false
+	case len(ks) > maxBatchKeys:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("more than %d keys in one batch", maxBatchKeys))
+		return false
+	}
+	for i, key := range ks {
+		if err := validateKey(key); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("keys[%d]: %w", i, err))
+			return false
+		}
+	}
+	return true
+}
+
+func writeBatch(w http.ResponseWriter, results []batchResult) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Results []batchResult `json:"results"`
+	}{results})
+}
+
+// base64Encoding marks a value carried in a JSON body as base64. JSON
+// strings cannot hold bytes that are not valid UTF-8, so binary values
+// need it.
+const base64Encoding = "base64"
+
+// decodeValue reads a value from a JSON body in the given encoding.
+func decodeValue(value, encoding string) ([]byte, error) {
+	switch encoding {
+	case "":
+		return []byte(value), nil
+	case base64Encoding:
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 value: %v", err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", encoding)
+}
+
+// encodeValue returns value for a JSON body, and its encoding. It is base64
+// if asked for, or if it is not valid UTF-8.
+func encodeValue(value []byte, asBase64 bool) (string, string) {
+	if asBase64 || !utf8.Valid(value) {
+		return base64.StdEncoding.EncodeToString(value), base64Encoding
+	}
+	return string(value), ""
+}
+
+func pathKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := r.PathValue("key")
+	if err := validateKey(key); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return "", false
+	}
+	return key, true
+}
+
+func validateKey(key string) error {
+	switch {
+	case key == "":
+		return errors.New("key must not be empty")
+	case len(key) > maxKeyLen:
+		return fmt.Errorf("key longer than %d bytes", maxKeyLen)
+	}
+	return nil
+}
+
+// queryInt parses the named query parameter, 0 if it is absent.
+func queryInt(r *http.Request, name string) (int64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s", name)
+	}
+	return n, nil
+}
+
+// ttlSeconds converts the named ttl from seconds, rejecting one that is
+// negative or too long to represent.
+func ttlSeconds(name string, n int64) (time.Duration, error) {
+	switch {
+	case n < 0:
+		return 0, fmt.Errorf("%s must not be negative", name)
+	case n > maxTTL:
+		return 0, fmt.Errorf("%s longer than %d seconds", name, maxTTL)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// writeError sends {"error": "..."} with the given status.
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+// withConsistency applies the consistency level named in the
+// X-Cache-Consistency header, if any, to the request.
+func withConsistency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if name := r.Header.Get("X-Cache-Consistency"); name != "" {
+			level, err := coordinator.ParseConsistency(name)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			r = r.WithContext(coordinator.WithConsistency(r.Context(), level))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusFor maps a coordinator error onto an HTTP status.
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, coordinator.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, coordinator.ErrEntryTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, coordinator.ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, coordinator.ErrConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, coordinator.ErrNotNumeric):
+		return http.StatusConflict
+	case errors.Is(err, coordinator.ErrOverflow):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, coordinator.ErrNoNodes), errors.Is(err, coordinator.ErrNodeUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// The caller gave up, or the request ran out of time, before the
+		// nodes answered.
+		return http.StatusServiceUnavailable
+	case errors.Is(err, coordinator.ErrBackend):
+		return http.StatusBadGateway
+	}
+	slog.Error("request failed", "err", err)
+	return http.StatusInternalServerError
+}
+
+func writeCacheError(w http.ResponseWriter, err error) {
+	writeError(w, statusFor(err), err)
+}
+
+// writeBodyError reports a body that could not be read, distinguishing one
+// that was over the size limit.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("body larger than %d bytes", tooLarge.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sakshamg567/cachy/internal/cache"
+	"github.com/sakshamg567/cachy/internal/coordinator"
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+	"google.golang.org/grpc"
+)
+
+// newTestServer serves the /v1 API over a coordinator for addr, with bodies
+// limited to 16 bytes.
+func newTestServer(t *testing.T, addr string) *httptest.Server {
+	t.Helper()
+	cd, err := coordinator.NewCoordinator(coordinator.Config{
+		Nodes:    []coordinator.Member{{Addr: addr, Weight: 1}},
+		Replicas: 1,
+		Vnodes:   16,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cd.Close)
+	mux := http.NewServeMux()
+	(&api{cd: cd, maxBodyBytes: 16}).register(mux)
+	ts := httptest.NewServer(withConsistency(mux))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// startNode serves a cache node on a free local port and returns its
+// address.
+func startNode(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	cacheNodepb.RegisterCacheServer(srv, cache.NewCacheNode(cache.Options{Capacity: 100}))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String()
+}
+
+func TestKeysAPI(t *testing.T) {
+	ts := newTestServer(t, startNode(t))
+
+	// Each request runs against the state the ones before it left. {etag}
+	// in a header is the last ETag answered.
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		header  map[string]string
+		body    string
+		status  int
+		wantErr string // in the JSON error body
+	}{
+		{"put", "PUT", "/v1/keys/a?ttl=60", nil, "one", http.StatusNoContent, ""},
+		{"get", "GET", "/v1/keys/a", nil, "", http.StatusOK, ""},
+		{"get unchanged", "GET", "/v1/keys/a", map[string]string{"If-None-Match": "{etag}"}, "", http.StatusNotModified, ""},
+		{"get missing", "GET", "/v1/keys/nope", nil, "", http.StatusNotFound, "key not found"},
+		{"negative ttl", "PUT", "/v1/keys/a?ttl=-1", nil, "x", http.StatusBadRequest, "ttl must not be negative"},
+		{"huge ttl", "PUT", "/v1/keys/a?ttl=9223372036854775807", nil, "x", http.StatusBadRequest, "ttl longer than"},
+		{"huge soft ttl", "PUT", "/v1/keys/a?soft_ttl=9223372036854775807", nil, "x", http.StatusBadRequest, "soft_ttl longer than"},
+		{"huge counter ttl", "POST", "/v1/keys/n/incr?ttl=9223372036854775807", nil, "", http.StatusBadRequest, "ttl longer than"},
+		{"bad consistency", "GET", "/v1/keys/a", map[string]string{"X-Cache-Consistency": "some"}, "", http.StatusBadRequest, ""},
+		{"method not allowed", "POST", "/v1/keys/a", nil, "", http.StatusMethodNotAllowed, "method POST not allowed"},
+		{"incr not a number", "POST", "/v1/keys/a/incr", nil, "", http.StatusConflict, ""},
+		{"stale if-match", "PUT", "/v1/keys/a", map[string]string{"If-Match": `"12345"`}, "two", http.StatusPreconditionFailed, ""},
+		{"body too large", "PUT", "/v1/keys/a", nil, strings.Repeat("x", 17), http.StatusRequestEntityTooLarge, "body larger than 16 bytes"},
+		{"counter overflow", "POST", "/v1/keys/n/incr?by=9223372036854775807&initial=1", nil, "", http.StatusUnprocessableEntity, ""},
+		{"head", "HEAD", "/v1/keys/a", nil, "", http.StatusOK, ""},
+		{"delete", "DELETE", "/v1/keys/a", nil, "", http.StatusNoContent, ""},
+		{"delete missing", "DELETE", "/v1/keys/a", nil, "", http.StatusNotFound, ""},
+		{"head missing", "HEAD", "/v1/keys/a", nil, "", http.StatusNotFound, ""},
+	}
+	etag := ""
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, ts.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range tt.header {
+			req.Header.Set(k, strings.ReplaceAll(v, "{etag}", etag))
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if e := res.Header.Get("ETag"); e != "" {
+			etag = e
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != tt.status {
+			t.Fatalf("%s: %s %s answered %d %s, want %d", tt.name, tt.method, tt.path, res.StatusCode, body, tt.status)
+		}
+		if !strings.Contains(string(body), tt.wantErr) {
+			t.Errorf("%s: body %s, want an error containing %q", tt.name, body, tt.wantErr)
+		}
+	}
+}
+
+func TestKeysAPINodeDown(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+	ts := newTestServer(t, addr)
+
+	res, err := http.Get(ts.URL + "/v1/keys/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("GET with the node down answered %d, want 503", res.StatusCode)
+	}
+}
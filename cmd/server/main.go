@@ -1,69 +1,382 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"mime"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sakshamg567/cachy/internal/backend"
 	"github.com/sakshamg567/cachy/internal/coordinator"
+	"github.com/sakshamg567/cachy/internal/logging"
+	"github.com/sakshamg567/cachy/internal/memcache"
+	"github.com/sakshamg567/cachy/internal/resp"
 )
 
+const octetStream = "application/octet-stream"
+
 func main() {
 	port := flag.String("port", "8080", "port to run server on")
+	replicas := flag.Int("replicas", 1, "number of distinct cache nodes each key is stored on")
+	vnodes := flag.Int("vnodes", 128, "virtual nodes per unit of node weight")
+	healthInterval := flag.Duration("health-interval", time.Second, "how often to health check cache nodes (0 disables)")
+	healthTimeout := flag.Duration("health-timeout", 500*time.Millisecond, "timeout for a single health check")
+	downAfter := flag.Int("down-after", 3, "consecutive failed health checks before a node stops receiving traffic")
+	readLevelName := flag.String("read-consistency", "one", "replicas that must answer a read unless the request says otherwise: one, quorum or all")
+	writeLevelName := flag.String("write-consistency", "one", "replicas that must acknowledge a write unless the request says otherwise: one, quorum or all")
+	maxHints := flag.Int("max-hints", 100000, "writes kept for unavailable nodes and replayed when they recover (0 disables hinted handoff)")
+	hintReplayInterval := flag.Duration("hint-replay-interval", 10*time.Second, "how often hints are replayed to nodes not known to be down")
+	antiEntropyInterval := flag.Duration("anti-entropy-interval", 5*time.Minute, "how often replicas are compared and repaired (0 disables)")
+	maxBodyBytes := flag.Int64("max-body-bytes", 2<<20, "largest request body accepted when writing a value")
+	respAddr := flag.String("resp-addr", "", "address to serve the Redis protocol on, e.g. :6379 (disabled if empty)")
+	memcacheAddr := flag.String("memcache-addr", "", "address to serve the memcached protocol on, e.g. :11211 (disabled if empty)")
+	nodeList := flag.String("nodes", "localhost:50051,localhost:50052,localhost:50053", "comma separated cache node addresses, each optionally suffixed with =weight")
+	loaderSpec := flag.String("loader", "", "backend read on a miss: an http(s) origin URL or driver:dsn for a SQL database (disabled if empty)")
+	loaderTable := flag.String("loader-table", "cachy", "table used by a SQL loader")
+	loaderTimeout := flag.Duration("loader-timeout", 5*time.Second, "timeout for a single request to an HTTP origin")
+	loadTTL := flag.Duration("loader-ttl", 0, "ttl of values cached from the loader (0 means no expiry)")
+	loadSoftTTL := flag.Duration("loader-soft-ttl", 0, "age after which a loaded value is refreshed from the loader in the background (0 disables)")
+	writeModeName := flag.String("write-mode", string(coordinator.WriteNone), "whether writes also go to the loader: none, through or behind")
+	writeRetries := flag.Int("write-retries", 3, "attempts for each write to the loader")
+	writeBatch := flag.Int("write-behind-batch", 100, "most keys written to the loader at once under write-behind")
+	writeDelay := flag.Duration("write-behind-interval", time.Second, "longest a write waits before it is sent to the loader under write-behind")
+	logFlags := logging.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
-	log.SetPrefix("[server] ")
+	logger, err := logFlags.Logger()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid logging flags: %v\n", err)
+		os.Exit(2)
+	}
+	logger = logger.With("service", "server")
+	slog.SetDefault(logger)
+
+	members, err := parseMembers(*nodeList)
+	if err != nil {
+		logging.Fatal(logger, "invalid --nodes", "err", err)
+	}
+
+	readLevel, err := coordinator.ParseConsistency(*readLevelName)
+	if err != nil {
+		logging.Fatal(logger, "invalid --read-consistency", "err", err)
+	}
+	writeLevel, err := coordinator.ParseConsistency(*writeLevelName)
+	if err != nil {
+		logging.Fatal(logger, "invalid --write-consistency", "err", err)
+	}
+
+	writeMode, err := coordinator.ParseWriteMode(*writeModeName)
+	if err != nil {
+		logging.Fatal(logger, "invalid --write-mode", "err", err)
+	}
+	var loader coordinator.Loader
+	if *loaderSpec != "" {
+		loader, err = newLoader(*loaderSpec, *loaderTable, *loaderTimeout)
+		if err != nil {
+			logging.Fatal(logger, "invalid --loader", "err", err)
+		}
+	} else if writeMode != coordinator.WriteNone {
+		logging.Fatal(logger, "--write-mode needs a --loader")
+	}
+
+	cd, err := coordinator.NewCoordinator(coordinator.Config{
+		Nodes:    members,
+		Replicas: *replicas,
+		Vnodes:   *vnodes,
+
+		ReadConsistency:  readLevel,
+		WriteConsistency: writeLevel,
+
+		HealthInterval: *healthInterval,
+		HealthTimeout:  *healthTimeout,
+		DownAfter:      *downAfter,
 
-	addresses := []string{"localhost:50051", "localhost:50052", "localhost:50053"}
+		MaxHints:            *maxHints,
+		HintReplayInterval:  *hintReplayInterval,
+		AntiEntropyInterval: *antiEntropyInterval,
 
-	cd := coordinator.NewCoordinator(addresses)
+		Loader:       loader,
+		LoadTTL:      *loadTTL,
+		LoadSoftTTL:  *loadSoftTTL,
+		WriteMode:    writeMode,
+		WriteRetries: *writeRetries,
+		WriteBatch:   *writeBatch,
+		WriteDelay:   *writeDelay,
+
+		Logger: logger,
+	})
+	if err != nil {
+		logging.Fatal(logger, "invalid configuration", "err", err)
+	}
+
+	(&api{cd: cd, maxBodyBytes: *maxBodyBytes}).register(http.DefaultServeMux)
 
 	http.HandleFunc("/get", func(w http.ResponseWriter, r *http.Request) {
 		key := r.URL.Query().Get("key")
-		val, err := cd.Get(r.Context(), key)
+		item, err := cd.Get(r.Context(), key)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeCacheError(w, err)
 			return
 		}
-		json.NewEncoder(w).Encode(map[string]string{"value": val})
+		// ttl is reported in whole seconds (rounded up) and omitted for keys
+		// without an expiry.
+		ttl := item.TTLSeconds()
+
+		// Binary clients get the raw bytes; the JSON form is only safe for
+		// UTF-8 values.
+		if strings.Contains(r.Header.Get("Accept"), octetStream) {
+			w.Header().Set("Content-Type", octetStream)
+			if ttl > 0 {
+				w.Header().Set("X-Cache-TTL", strconv.FormatInt(ttl, 10))
+			}
+			w.Write(item.Value)
+			return
+		}
+		json.NewEncoder(w).Encode(struct {
+			Value string `json:"value"`
+			TTL   int64  `json:"ttl,omitempty"`
+		}{
+			Value: string(item.Value),
+			TTL:   ttl,
+		})
 	})
 
 	http.HandleFunc("/set", func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, *maxBodyBytes)
+		var (
+			key   string
+			value []byte
+			ttl   int64 // seconds, 0 means no expiry
+		)
+		if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == octetStream {
+			// The body is the value; key and ttl come from the query string.
+			key = r.URL.Query().Get("key")
+			if v := r.URL.Query().Get("ttl"); v != "" {
+				n, err := strconv.ParseInt(v, 10, 64)
+				if err != nil {
+					http.Error(w, "invalid ttl", http.StatusBadRequest)
+					return
+				}
+				ttl = n
+			}
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeBodyError(w, err)
+				return
+			}
+			value = b
+		} else {
+			var body struct {
+				Key   string `json:"key"`
+				Value string `json:"value"`
+				TTL   int64  `json:"ttl"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				writeBodyError(w, err)
+				return
+			}
+			key, value, ttl = body.Key, []byte(body.Value), body.TTL
+		}
+		d, err := ttlSeconds("ttl", ttl)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := validateKey(key); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := cd.Set(r.Context(), key, value, d); err != nil {
+			writeCacheError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	http.HandleFunc("POST /add-node", func(w http.ResponseWriter, r *http.Request) {
 		var body struct {
-			Key   string `json:"key"`
-			Value string `json:"value"`
+			Address string `json:"address"`
+			Weight  int    `json:"weight"`
 		}
+
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if body.Weight == 0 {
+			body.Weight = 1
+		}
 
-		ok := cd.Set(r.Context(), body.Key, body.Value)
-		if !ok {
-			http.Error(w, "failed to set", http.StatusInternalServerError)
+		logger.Info("adding node", "node", body.Address, "weight", body.Weight)
+		switch err := cd.AddNode(body.Address, body.Weight); {
+		case errors.Is(err, coordinator.ErrInvalidNode):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, coordinator.ErrNodeExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Node addition process started"))
 	})
 
-	http.HandleFunc("/add-node", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("POST /remove-node", func(w http.ResponseWriter, r *http.Request) {
 		var body struct {
 			Address string `json:"address"`
+			Force   bool   `json:"force"` // skip the data handoff, for dead nodes
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
 
-		log.Printf("adding new node: %s", body.Address)
-		cd.AddNode(body.Address)
+		var err error
+		if body.Force {
+			logger.Info("removing node", "node", body.Address)
+			err = cd.RemoveNode(body.Address)
+		} else {
+			logger.Info("draining node", "node", body.Address)
+			err = cd.DrainNode(body.Address)
+		}
+		switch {
+		case errors.Is(err, coordinator.ErrUnknownNode):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, coordinator.ErrLastNode):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("Node addition process started"))
+		w.Write([]byte("Node removed"))
 	})
 
-	log.Printf("listening on :%s nodes=%v", *port, addresses)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%s", *port), nil))
+	http.Handle("/metrics", promhttp.Handler())
+
+	http.HandleFunc("/admin/nodes", func(w http.ResponseWriter, r *http.Request) {
+		type nodeInfo struct {
+			Address  string     `json:"address"`
+			Weight   int        `json:"weight"`
+			State    string     `json:"state"`
+			Failures int        `json:"failures"`
+			LastSeen *time.Time `json:"last_seen,omitempty"`
+		}
+		nodes := cd.Nodes()
+		out := make([]nodeInfo, 0, len(nodes))
+		for _, n := range nodes {
+			info := nodeInfo{
+				Address:  n.Addr,
+				Weight:   n.Weight,
+				State:    n.State.String(),
+				Failures: n.Failures,
+			}
+			if !n.LastSeen.IsZero() {
+				info.LastSeen = &n.LastSeen
+			}
+			out = append(out, info)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	})
+
+	http.HandleFunc("POST /admin/anti-entropy", func(w http.ResponseWriter, r *http.Request) {
+		repaired, err := cd.RunAntiEntropy(r.Context())
+		out := struct {
+			Repaired int    `json:"repaired"`
+			Error    string `json:"error,omitempty"`
+		}{Repaired: repaired}
+		if err != nil {
+			out.Error = err.Error()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	})
+
+	if *respAddr != "" {
+		go func() {
+			err := resp.NewServer(cd, *maxBodyBytes).ListenAndServe(*respAddr)
+			logging.Fatal(logger, "resp listener failed", "err", err)
+		}()
+	}
+
+	if *memcacheAddr != "" {
+		go func() {
+			err := memcache.NewServer(cd, *maxBodyBytes).ListenAndServe(*memcacheAddr)
+			logging.Fatal(logger, "memcache listener failed", "err", err)
+		}()
+	}
+
+	logger.Info("listening", "port", *port, "nodes", fmt.Sprint(members), "replicas", *replicas, "vnodes", *vnodes)
+	srv := &http.Server{Addr: fmt.Sprintf(":%s", *port), Handler: withConsistency(http.DefaultServeMux)}
+	go func() {
+		// Stop taking requests and flush queued backend writes before
+		// exiting.
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		logger.Info("shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}()
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		logging.Fatal(logger, "http listener failed", "err", err)
+	}
+	cd.Close()
+}
+
+// newLoader builds the backend named by spec: an http(s) origin URL or
+// driver:dsn for a SQL database.
+func newLoader(spec, table string, timeout time.Duration) (coordinator.Loader, error) {
+	if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+		return backend.NewHTTP(spec, timeout)
+	}
+	driver, dsn, err := backend.ParseDSN(spec)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return backend.NewSQL(ctx, driver, dsn, table)
+}
+
+// parseMembers parses "host:port[=weight],..." into ring members.
+func parseMembers(list string) ([]coordinator.Member, error) {
+	var members []coordinator.Member
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		m := coordinator.Member{Addr: item, Weight: 1}
+		if addr, w, ok := strings.Cut(item, "="); ok {
+			weight, err := strconv.Atoi(w)
+			if err != nil || weight < 1 || weight > coordinator.MaxWeight {
+				return nil, fmt.Errorf("bad weight in %q, want 1..%d", item, coordinator.MaxWeight)
+			}
+			m = coordinator.Member{Addr: addr, Weight: weight}
+		}
+		members = append(members, m)
+	}
+	return members, nil
 }
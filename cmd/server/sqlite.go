@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package main
+
+// Building with -tags sqlite links the SQLite driver, so that
+// --loader sqlite3:path.db can be used.
+import _ "github.com/mattn/go-sqlite3"
@@ -0,0 +1,101 @@
+// Package backend provides loaders the coordinator can read through to and
+// write through to: an HTTP origin and a database/sql table.
+package backend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+// maxResponseBytes bounds a value read from an HTTP origin.
+const maxResponseBytes = 64 << 20
+
+// HTTP loads values from an origin that serves each key at {base}/{key}.
+// Writes are sent as PUT and deletes as DELETE to the same URL; a 404 means
+// the key does not exist.
+type HTTP struct {
+	base   string
+	client *http.Client
+}
+
+// NewHTTP returns a loader for the origin at base.
+func NewHTTP(base string, timeout time.Duration) (*HTTP, error) {
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid origin url %q", base)
+	}
+	return &HTTP{
+		base:   strings.TrimSuffix(base, "/"),
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (h *HTTP) url(key string) string {
+	return h.base + "/" + url.PathEscape(key)
+}
+
+func (h *HTTP) Load(ctx context.Context, key string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return nil, coordinator.ErrNotFound
+	case res.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("origin returned %s", res.Status)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, maxResponseBytes))
+}
+
+// Write stores each entry with its own request; HTTP origins have no
+// common batch format.
+func (h *HTTP) Write(ctx context.Context, entries []coordinator.Entry) error {
+	for _, e := range entries {
+		if err := h.do(ctx, http.MethodPut, e.Key, e.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HTTP) Delete(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := h.do(ctx, http.MethodDelete, key, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *HTTP) do(ctx context.Context, method, key string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, method, h.url(key), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	res, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	// Deleting a key the origin does not have is not an error.
+	if res.StatusCode/100 != 2 && !(method == http.MethodDelete && res.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("origin returned %s for %s %s", res.Status, method, key)
+	}
+	return nil
+}
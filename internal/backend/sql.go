@@ -0,0 +1,113 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQL loads values from a table with a text primary key column named key
+// and a blob column named value. The driver must be linked into the binary.
+type SQL struct {
+	db                    *sql.DB
+	load, upsert, deleteq string
+}
+
+// NewSQL opens dsn with driver and prepares queries against table, creating
+// it if it does not exist.
+func NewSQL(ctx context.Context, driver, dsn, table string) (*SQL, error) {
+	if !identifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	blob := "BLOB"
+	p1, p2 := "?", "?"
+	if driver == "postgres" || driver == "pgx" {
+		blob, p1, p2 = "BYTEA", "$1", "$2"
+	}
+	create := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s ("key" TEXT PRIMARY KEY, "value" %s NOT NULL)`, table, blob)
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQL{
+		db:   db,
+		load: fmt.Sprintf(`SELECT "value" FROM %s WHERE "key" = %s`, table, p1),
+		upsert: fmt.Sprintf(`INSERT INTO %s ("key", "value") VALUES (%s, %s)
+			ON CONFLICT ("key") DO UPDATE SET "value" = excluded."value"`, table, p1, p2),
+		deleteq: fmt.Sprintf(`DELETE FROM %s WHERE "key" = %s`, table, p1),
+	}, nil
+}
+
+// ParseDSN splits "driver:dsn", e.g. "sqlite3:cache.db".
+func ParseDSN(s string) (driver, dsn string, err error) {
+	driver, dsn, ok := strings.Cut(s, ":")
+	if !ok || driver == "" {
+		return "", "", fmt.Errorf("expected driver:dsn, got %q", s)
+	}
+	return driver, dsn, nil
+}
+
+func (s *SQL) Load(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRowContext(ctx, s.load, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, coordinator.ErrNotFound
+	}
+	return value, err
+}
+
+// Write upserts entries in a single transaction.
+func (s *SQL) Write(ctx context.Context, entries []coordinator.Entry) error {
+	return s.each(ctx, s.upsert, len(entries), func(i int) []any {
+		return []any{entries[i].Key, entries[i].Value}
+	})
+}
+
+// Delete removes keys in a single transaction.
+func (s *SQL) Delete(ctx context.Context, keys []string) error {
+	return s.each(ctx, s.deleteq, len(keys), func(i int) []any {
+		return []any{keys[i]}
+	})
+}
+
+// each runs query n times in one transaction with the arguments args
+// returns for each index.
+func (s *SQL) each(ctx context.Context, query string, n int, args func(i int) []any) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i := 0; i < n; i++ {
+		if _, err := stmt.ExecContext(ctx, args(i)...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Close closes the database.
+func (s *SQL) Close() error {
+	return s.db.Close()
+}
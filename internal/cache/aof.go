@@ -0,0 +1,597 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FsyncPolicy says how often the append-only log is forced to disk, and so
+// how many acknowledged writes a power loss can take with it.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways syncs before every write is acknowledged.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncEverySec syncs once a second, losing at most the last second.
+	FsyncEverySec FsyncPolicy = "everysec"
+	// FsyncNever leaves flushing to the operating system.
+	FsyncNever FsyncPolicy = "never"
+)
+
+// FsyncPolicies lists every supported fsync policy.
+var FsyncPolicies = []FsyncPolicy{FsyncAlways, FsyncEverySec, FsyncNever}
+
+// ParseFsyncPolicy maps a case-insensitive name onto a policy.
+func ParseFsyncPolicy(name string) (FsyncPolicy, error) {
+	for _, p := range FsyncPolicies {
+		if strings.EqualFold(name, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown fsync policy %q", name)
+}
+
+// An append-only log file is
+//
+//	magic "CACHYAOF" | version uint16 | record*
+//
+// where each record is framed as
+//
+//	payload length uint32 | CRC-32C of payload uint32 | payload
+//
+// and the payload is an op byte followed by the key and, for sets, the
+// value, flags, expiry, stale time and version encoded as in a snapshot,
+// or for tombstones the version the key was deleted at.
+// Integers in the frame are little endian. A record that is cut short or
+// fails its checksum marks the end of the usable log; one that passes its
+// checksum but cannot be decoded means the log is corrupt.
+const (
+	aofMagic   = "CACHYAOF"
+	aofVersion = 1
+
+	opSet       = 0x01
+	opDelete    = 0x02
+	opTombstone = 0x03
+)
+
+// ErrLogFailed is returned for writes refused because the append-only log
+// can no longer be written and the policy is to sync every write.
+var ErrLogFailed = errors.New("append-only log failed")
+
+// appendLog is the append-only log of a cache. Shards append to it while
+// holding their own lock, so records for a key are in the order the writes
+// were applied.
+type appendLog struct {
+	path        string
+	log         *slog.Logger
+	fsync       FsyncPolicy
+	rewriteSize int64 // rewrite once the log is this big and has doubled; 0 never
+
+	mu     sync.Mutex
+	closed bool
+	f      *os.File
+	w      *bufio.Writer
+	size   int64 // bytes in the log, buffered or not
+	base   int64 // size just after the last rewrite
+	// err is the first write, flush or sync failure. The buffered writer
+	// keeps failing after one, so the log stays failed until a rewrite
+	// swaps in a new file.
+	err error
+
+	// While a rewrite is running, records are also kept here to be added to
+	// the new log once it has caught up with the live map.
+	rewriting bool
+	pending   bytes.Buffer
+	scratch   []byte
+}
+
+// OpenAppendLog replays the log at path into the cache, creating it if it
+// does not exist, and from then on records every set and delete in it. It
+// returns the number of records replayed. A damaged tail, as left by a
+// crash mid-write, is cut off with a warning; the records before it are
+// kept. It must be called before the cache is used.
+func (c *LruCache) OpenAppendLog(path string, fsync FsyncPolicy, rewriteSize int64) (int, error) {
+	replayed, validSize, err := c.replayLog(path)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if validSize == 0 {
+		// New (or unreadable) log: start it with a header.
+		if err := f.Truncate(0); err != nil {
+			f.Close()
+			return 0, err
+		}
+		hdr := aofHeader()
+		if _, err := f.WriteAt(hdr, 0); err != nil {
+			f.Close()
+			return 0, err
+		}
+		validSize = int64(len(hdr))
+	} else if info.Size() > validSize {
+		c.log.Warn("aof damaged, truncating", "path", path, "dropped_bytes", info.Size()-validSize)
+		if err := f.Truncate(validSize); err != nil {
+			f.Close()
+			return 0, err
+		}
+	}
+	if _, err := f.Seek(validSize, io.SeekStart); err != nil {
+		f.Close()
+		return 0, err
+	}
+
+	l := &appendLog{
+		path:        path,
+		log:         c.log,
+		fsync:       fsync,
+		rewriteSize: rewriteSize,
+		f:           f,
+		w:           bufio.NewWriterSize(f, 64<<10),
+		size:        validSize,
+		base:        validSize,
+	}
+	c.aof = l
+	for _, s := range c.shards {
+		s.aof = l
+	}
+	go c.runAppendLog()
+	return replayed, nil
+}
+
+// CloseAppendLog flushes and syncs the log. Writes made afterwards are not
+// recorded.
+func (c *LruCache) CloseAppendLog() error {
+	l := c.aof
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	if err := l.f.Sync(); err != nil {
+		return err
+	}
+	return l.f.Close()
+}
+
+func aofHeader() []byte {
+	hdr := []byte(aofMagic)
+	return binary.LittleEndian.AppendUint16(hdr, aofVersion)
+}
+
+// replayLog applies the records at path to the cache. validSize is the
+// length of the readable prefix of the file, 0 if it does not exist or is
+// not a log at all.
+func (c *LruCache) replayLog(path string) (replayed int, validSize int64, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	r := bufio.NewReaderSize(f, 64<<10)
+
+	hdr := make([]byte, len(aofMagic)+2)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, 0, nil // empty file
+		}
+		return 0, 0, fmt.Errorf("%s: %w", path, err)
+	}
+	if !bytes.Equal(hdr, aofHeader()) {
+		return 0, 0, fmt.Errorf("%s: not an append-only log of version %d", path, aofVersion)
+	}
+	validSize = int64(len(hdr))
+
+	now := time.Now()
+	var head [8]byte
+	for {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			break
+		}
+		// A corrupt length must not make the node allocate more than the
+		// file could hold.
+		n := binary.LittleEndian.Uint32(head[:4])
+		if int64(n) > info.Size()-validSize-int64(len(head)) {
+			break
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			break
+		}
+		if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(head[4:]) {
+			break
+		}
+		if err := c.applyRecord(payload, now); err != nil {
+			return replayed, validSize, fmt.Errorf("%s: corrupt record at offset %d: %w", path, validSize, err)
+		}
+		validSize += int64(len(head)) + int64(n)
+		replayed++
+	}
+	return replayed, validSize, nil
+}
+
+func (c *LruCache) applyRecord(payload []byte, now time.Time) error {
+	r := bytes.NewReader(payload)
+	op, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	key, err := readField(r)
+	if err != nil {
+		return err
+	}
+	s := c.shardFor(string(key))
+
+	switch op {
+	case opDelete:
+		s.mu.Lock()
+		s.deleteLocked(string(key), 0, false, now)
+		s.mu.Unlock()
+		return nil
+	case opTombstone:
+		version, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.deleteLocked(string(key), version, true, now)
+		s.mu.Unlock()
+		return nil
+	case opSet:
+		value, err := readField(r)
+		if err != nil {
+			return err
+		}
+		flags, err := binary.ReadUvarint(r)
+		if err != nil {
+			return err
+		}
+		exp, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		stale, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		version, err := binary.ReadVarint(r)
+		if err != nil {
+			return err
+		}
+		rec := record{
+			key:       string(key),
+			value:     value,
+			flags:     uint32(flags),
+			expiresAt: fromUnixNano(exp),
+			staleAt:   fromUnixNano(stale),
+			version:   version,
+		}
+		if !rec.expiresAt.IsZero() && !now.Before(rec.expiresAt) {
+			// Expired while the node was down; an earlier value must not
+			// survive either.
+			s.mu.Lock()
+			if node, ok := s.cache[string(key)]; ok {
+				s.removeNode(node)
+			}
+			s.mu.Unlock()
+			return nil
+		}
+		// An entry too large for the current limits is skipped, as it would
+		// have been rejected had it been written now.
+		s.store(&rec, nil)
+		return nil
+	}
+	return fmt.Errorf("unknown op %#x", op)
+}
+
+func readField(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(r, b)
+	return b, err
+}
+
+func encodeSet(buf []byte, rec record) []byte {
+	buf = append(buf, opSet)
+	buf = binary.AppendUvarint(buf, uint64(len(rec.key)))
+	buf = append(buf, rec.key...)
+	buf = binary.AppendUvarint(buf, uint64(len(rec.value)))
+	buf = append(buf, rec.value...)
+	buf = binary.AppendUvarint(buf, uint64(rec.flags))
+	buf = binary.AppendVarint(buf, unixNano(rec.expiresAt))
+	buf = binary.AppendVarint(buf, unixNano(rec.staleAt))
+	return binary.AppendVarint(buf, rec.version)
+}
+
+// encodeDelete encodes the delete of key at version, a plain delete
+// leaving no tombstone if version is 0.
+func encodeDelete(buf []byte, key string, version int64) []byte {
+	if version == 0 {
+		buf = append(buf, opDelete)
+	} else {
+		buf = append(buf, opTombstone)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	if version != 0 {
+		buf = binary.AppendVarint(buf, version)
+	}
+	return buf
+}
+
+// frame wraps payload in its length and checksum.
+func frame(payload []byte) []byte {
+	out := make([]byte, 8, 8+len(payload))
+	binary.LittleEndian.PutUint32(out[:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(out[4:], crc32.Checksum(payload, crcTable))
+	return append(out, payload...)
+}
+
+func (l *appendLog) appendSet(rec record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.scratch = encodeSet(l.scratch[:0], rec)
+	l.write(frame(l.scratch))
+}
+
+func (l *appendLog) appendDelete(key string, version int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.scratch = encodeDelete(l.scratch[:0], key, version)
+	l.write(frame(l.scratch))
+}
+
+// write adds a framed record to the log. It must be called with l.mu held.
+// A failure marks the log as failed rather than failing the write, which
+// has already been applied; check refuses the writes after it.
+func (l *appendLog) write(rec []byte) {
+	if l.closed {
+		return
+	}
+	if _, err := l.w.Write(rec); err != nil {
+		l.fail("write", err)
+	}
+	l.size += int64(len(rec))
+	if l.rewriting {
+		l.pending.Write(rec)
+	}
+}
+
+// fail records err as the reason the log failed. It must be called with
+// l.mu held.
+func (l *appendLog) fail(op string, err error) {
+	if l.err == nil {
+		l.log.Error("aof failed", "op", op, "path", l.path, "err", err, "fsync", l.fsync)
+	}
+	l.err = err
+}
+
+// check returns ErrLogFailed once the log has failed if the policy is to
+// sync every write, so that no write is acknowledged without being on
+// disk. Under the other policies, which already allow losing recent
+// writes, the cache keeps taking them. l may be nil.
+func (l *appendLog) check() error {
+	if l == nil || l.fsync != FsyncAlways {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err != nil {
+		return fmt.Errorf("%w: %v", ErrLogFailed, l.err)
+	}
+	return nil
+}
+
+// commit makes the records appended so far durable if the policy is to
+// sync on every write, and returns ErrLogFailed if they could not be.
+// Shards call it after releasing their lock, so concurrent writers share
+// one fsync.
+func (l *appendLog) commit() error {
+	if l.fsync != FsyncAlways {
+		return nil
+	}
+	if err := l.sync(true); err != nil {
+		return fmt.Errorf("%w: %v", ErrLogFailed, err)
+	}
+	return nil
+}
+
+func (l *appendLog) sync(fsync bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed || (l.w.Buffered() == 0 && !fsync) {
+		return l.err
+	}
+	if err := l.w.Flush(); err != nil {
+		l.fail("flush", err)
+		return err
+	}
+	if fsync {
+		if err := l.f.Sync(); err != nil {
+			l.fail("fsync", err)
+			return err
+		}
+	}
+	return l.err
+}
+
+// runAppendLog flushes the log once a second, syncing it under the
+// everysec policy, and starts a rewrite whenever the log has outgrown its
+// last compacted size or has failed, since a rewrite is what recovers it.
+func (c *LruCache) runAppendLog() {
+	l := c.aof
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		l.mu.Lock()
+		closed := l.closed
+		due := !l.rewriting && (l.err != nil || l.rewriteSize > 0 && l.size >= l.rewriteSize && l.size >= 2*l.base)
+		l.mu.Unlock()
+		if closed {
+			return
+		}
+
+		l.sync(l.fsync == FsyncEverySec) // failures are recorded in l.err
+		if due {
+			if err := c.RewriteAppendLog(); err != nil {
+				l.log.Error("aof rewrite failed", "path", l.path, "err", err)
+			}
+		}
+	}
+}
+
+// RewriteAppendLog compacts the log by writing the live contents of the
+// cache to a new file, then swapping it in. Writes continue while the new
+// file is built; they go to the old log as usual and are copied onto the
+// end of the new one before the swap.
+func (c *LruCache) RewriteAppendLog() error {
+	l := c.aof
+	if l == nil {
+		return errors.New("append-only log is not enabled")
+	}
+	l.mu.Lock()
+	if l.rewriting {
+		l.mu.Unlock()
+		return errors.New("rewrite already in progress")
+	}
+	l.rewriting = true
+	l.pending.Reset()
+	l.mu.Unlock()
+
+	finish := func() {
+		l.mu.Lock()
+		l.rewriting = false
+		l.pending.Reset()
+		l.mu.Unlock()
+	}
+
+	start := time.Now()
+	dir := filepath.Dir(l.path)
+	f, err := os.CreateTemp(dir, filepath.Base(l.path)+".rewrite-*")
+	if err != nil {
+		finish()
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op once renamed
+
+	w := bufio.NewWriterSize(f, 64<<10)
+	w.Write(aofHeader())
+	entries := 0
+	var buf []byte
+	now := time.Now()
+	for _, s := range c.shards {
+		s.mu.RLock()
+		s.policy.walk(func(n *dllNode) {
+			if n.expired(now) {
+				return
+			}
+			buf = encodeSet(buf[:0], n.record())
+			w.Write(frame(buf))
+			entries++
+		})
+		for key, v := range s.tombs {
+			if !s.tombExpired(v, now) {
+				buf = encodeDelete(buf[:0], key, v)
+				w.Write(frame(buf))
+			}
+		}
+		s.mu.RUnlock()
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		finish()
+		return err
+	}
+
+	// Catch up with everything written meanwhile and swap the files while
+	// holding the log lock, so no record is lost between the two.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	defer func() {
+		l.rewriting = false
+		l.pending.Reset()
+	}()
+	if l.closed {
+		f.Close()
+		return errors.New("append-only log closed during rewrite")
+	}
+	if _, err := f.Write(l.pending.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	// The new file already holds everything, so a flush that fails only
+	// marks the old log as failed. A failed writer keeps returning its
+	// error and is not flushed at all.
+	if l.err == nil {
+		if err := l.w.Flush(); err != nil {
+			l.fail("flush", err)
+		}
+	}
+	if err := os.Rename(f.Name(), l.path); err != nil {
+		f.Close()
+		return err
+	}
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	l.f.Close()
+	l.f = f
+	l.w = bufio.NewWriterSize(f, 64<<10)
+	l.size, l.base = info.Size(), info.Size()
+	if l.err != nil {
+		l.log.Info("aof recovered", "path", l.path)
+		l.err = nil
+	}
+	l.log.Info("aof rewritten", "path", l.path, "entries", entries, "bytes", info.Size(), "took", time.Since(start).Round(time.Millisecond))
+	return nil
+}
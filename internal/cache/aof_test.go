@@ -0,0 +1,278 @@
+package cache
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newLoggedCache(t *testing.T, path string) (*LruCache, int) {
+	t.Helper()
+	c := NewLruCache(Options{Capacity: 100, Shards: 4, TombstoneGrace: time.Hour})
+	n, err := c.OpenAppendLog(path, FsyncAlways, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.CloseAppendLog() })
+	return c, n
+}
+
+func TestAppendLogReplay(t *testing.T) {
+	v := time.Now().UnixNano()
+	tests := []struct {
+		name  string
+		write func(c *LruCache)
+		want  map[string]string // live keys and values after replay
+		tombs []string          // keys replayed as tombstones
+	}{
+		{
+			name:  "sets",
+			write: func(c *LruCache) { c.set("a", []byte("1"), 0, 0, 0, 0); c.set("b", []byte("2"), 0, 0, 0, 0) },
+			want:  map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:  "overwrite",
+			write: func(c *LruCache) { c.set("a", []byte("1"), 0, 0, 0, 0); c.set("a", []byte("2"), 0, 0, 0, 0) },
+			want:  map[string]string{"a": "2"},
+		},
+		{
+			name: "plain delete",
+			write: func(c *LruCache) {
+				c.set("a", []byte("1"), 0, 0, 0, 0)
+				c.set("b", []byte("2"), 0, 0, 0, 0)
+				c.delete("a", 0, true)
+			},
+			want: map[string]string{"b": "2"},
+		},
+		{
+			name: "tombstone",
+			write: func(c *LruCache) {
+				c.set("a", []byte("1"), 0, 0, 0, v)
+				c.delete("a", v+1, false)
+			},
+			want:  map[string]string{},
+			tombs: []string{"a"},
+		},
+		{
+			name: "write after tombstone",
+			write: func(c *LruCache) {
+				c.delete("a", v, false)
+				c.set("a", []byte("3"), 0, 0, 0, v+1)
+			},
+			want: map[string]string{"a": "3"},
+		},
+		{
+			name: "stale write below tombstone",
+			write: func(c *LruCache) {
+				c.delete("a", v+1, false)
+				c.set("a", []byte("old"), 0, 0, 0, v)
+			},
+			want:  map[string]string{},
+			tombs: []string{"a"},
+		},
+		{
+			name: "expired while down",
+			write: func(c *LruCache) {
+				c.set("a", []byte("1"), 0, 0, 0, 0)
+				c.set("a", []byte("2"), 0, time.Millisecond, 0, 0)
+				time.Sleep(2 * time.Millisecond)
+			},
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "aof")
+			c, _ := newLoggedCache(t, path)
+			tt.write(c)
+			if err := c.CloseAppendLog(); err != nil {
+				t.Fatal(err)
+			}
+
+			replayed, _ := newLoggedCache(t, path)
+			got := map[string]string{}
+			for _, k := range replayed.GetAllKeys() {
+				if it, err := replayed.get(k); err == nil {
+					got[k] = string(it.value)
+				}
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+			for _, k := range tt.tombs {
+				if _, ok := replayed.tombstone(k); !ok {
+					t.Errorf("no tombstone for %s after replay", k)
+				}
+			}
+		})
+	}
+}
+
+func TestAppendLogDamagedTail(t *testing.T) {
+	// A crash can leave any prefix of the last record behind. Replay keeps
+	// the records before it and cuts the rest off.
+	tests := []struct {
+		name string
+		tail func(rec []byte) []byte
+	}{
+		{"partial header", func(rec []byte) []byte { return rec[:5] }},
+		{"partial payload", func(rec []byte) []byte { return rec[:len(rec)-2] }},
+		{"bad checksum", func(rec []byte) []byte { rec[5] ^= 1; return rec }},
+		{"huge length", func(rec []byte) []byte { rec[3] = 0x7f; return rec }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "aof")
+			c, _ := newLoggedCache(t, path)
+			for _, k := range []string{"a", "b", "c"} {
+				c.set(k, []byte(k), 0, 0, 0, 0)
+			}
+			c.CloseAppendLog()
+			info, _ := os.Stat(path)
+			good := info.Size()
+
+			rec := frame(encodeSet(nil, record{key: "d", value: []byte("d"), version: 1}))
+			appendFile(t, path, tt.tail(rec))
+
+			replayed, n := newLoggedCache(t, path)
+			if n != 3 {
+				t.Errorf("replayed %d records, want 3", n)
+			}
+			if keys := replayed.GetAllKeys(); len(keys) != 3 || slices.Contains(keys, "d") {
+				t.Errorf("keys = %v, want a, b and c", keys)
+			}
+			if info, _ := os.Stat(path); info.Size() != good {
+				t.Errorf("log is %d bytes, want it cut back to %d", info.Size(), good)
+			}
+		})
+	}
+}
+
+func TestAppendLogCorruptRecord(t *testing.T) {
+	// A record that passes its checksum but cannot be decoded is not a torn
+	// write, and replay refuses to guess.
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{"unknown op", []byte{0x7f, 1, 'a'}},
+		{"set without version", encodeSet(nil, record{key: "a", value: []byte("1")})[:6]},
+		{"tombstone without version", []byte{opTombstone, 1, 'a'}},
+		{"key longer than record", []byte{opDelete, 9, 'a'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "aof")
+			c, _ := newLoggedCache(t, path)
+			c.set("a", []byte("1"), 0, 0, 0, 0)
+			c.CloseAppendLog()
+			appendFile(t, path, frame(tt.payload))
+
+			c = NewLruCache(Options{Capacity: 10})
+			_, err := c.OpenAppendLog(path, FsyncAlways, 0)
+			if err == nil || !strings.Contains(err.Error(), "corrupt record") {
+				t.Errorf("OpenAppendLog = %v, want a corrupt record error", err)
+			}
+		})
+	}
+}
+
+func TestAppendLogRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aof")
+	c, _ := newLoggedCache(t, path)
+	v := time.Now().UnixNano()
+	for range 50 {
+		c.set("a", []byte("many"), 0, 0, 0, 0)
+	}
+	c.set("b", []byte("2"), 0, 0, 0, 0)
+	c.delete("c", v, false)
+	before, _ := os.Stat(path)
+	if err := c.RewriteAppendLog(); err != nil {
+		t.Fatal(err)
+	}
+	c.set("d", []byte("4"), 0, 0, 0, 0) // lands in the rewritten log
+	c.CloseAppendLog()
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("log grew from %d to %d bytes on rewrite", before.Size(), after.Size())
+	}
+
+	replayed, n := newLoggedCache(t, path)
+	if n != 4 {
+		t.Errorf("replayed %d records, want 4", n)
+	}
+	if got := resident(replayed, []string{"a", "b", "c", "d"}); !slices.Equal(got, []string{"a", "b", "d"}) {
+		t.Errorf("resident = %v, want [a b d]", got)
+	}
+	if version, ok := replayed.tombstone("c"); !ok || version != v {
+		t.Errorf("tombstone for c = %d, %v, want %d", version, ok, v)
+	}
+}
+
+func appendFile(t *testing.T, path string, b []byte) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAppendLogFailureRefusesWrites(t *testing.T) {
+	// Once the log cannot be written, only the policy that promises every
+	// acknowledged write is on disk starts refusing writes.
+	tests := []struct {
+		fsync   FsyncPolicy
+		refused bool
+	}{
+		{FsyncAlways, true},
+		{FsyncEverySec, false},
+		{FsyncNever, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.fsync), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "aof")
+			c := NewLruCache(Options{Capacity: 10})
+			if _, err := c.OpenAppendLog(path, tt.fsync, 0); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { c.CloseAppendLog() })
+			c.aof.f.Close() // every later flush and sync fails
+			for _, k := range []string{"a", "b"} {
+				err := c.set(k, []byte(k), 0, 0, 0, 0)
+				if got := errors.Is(err, ErrLogFailed); got != tt.refused {
+					t.Errorf("set %s = %v, want refused %v", k, err, tt.refused)
+				}
+			}
+			// The first write was applied before its sync failed; the
+			// second was refused up front.
+			if tt.refused && c.exists("b") {
+				t.Error("b stored although the log had failed")
+			}
+
+			// A rewrite swaps in a working file, after which writes are
+			// taken and logged again.
+			if err := c.RewriteAppendLog(); err != nil {
+				t.Fatalf("rewrite after the failure = %v", err)
+			}
+			if err := c.set("c", []byte("c"), 0, 0, 0, 0); err != nil {
+				t.Fatalf("set c after the rewrite = %v", err)
+			}
+			if err := c.CloseAppendLog(); err != nil {
+				t.Fatal(err)
+			}
+			replayed, _ := newLoggedCache(t, path)
+			if !replayed.exists("c") {
+				t.Error("c not in the log after the rewrite")
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package cache
+
+// arcPolicy is the Adaptive Replacement Cache of Megiddo and Modha. T1 holds
+// entries seen once recently and T2 entries seen at least twice; B1 and B2
+// remember the keys recently evicted from each. A hit on a ghost shifts the
+// target size p of T1 towards the list that would have kept it, so a large
+// scan only ever churns T1 and leaves the frequently used T2 alone.
+//
+// The paper sizes everything against a fixed entry count c. Cache nodes may
+// be bounded by memory instead, so c is taken to be the number of resident
+// entries whenever it is needed.
+type arcPolicy struct {
+	t1, t2 DLL
+	b1, b2 DLL
+	ghosts map[string]*dllNode
+	p      int
+}
+
+func newARCPolicy() *arcPolicy {
+	return &arcPolicy{ghosts: make(map[string]*dllNode)}
+}
+
+func (p *arcPolicy) resident() int {
+	return p.t1.len + p.t2.len
+}
+
+func (p *arcPolicy) add(n *dllNode) {
+	g, ghost := p.ghosts[n.key]
+	switch {
+	case ghost && g.seg == segB1:
+		// Evicted from T1 too early: favour recency.
+		delta := 1
+		if p.b1.len > 0 && p.b2.len/p.b1.len > delta {
+			delta = p.b2.len / p.b1.len
+		}
+		p.p = min(p.p+delta, p.resident()+1)
+		p.forget(g)
+		n.seg = segT2
+		p.t2.pushFront(n)
+	case ghost && g.seg == segB2:
+		// Evicted from T2 too early: favour frequency.
+		delta := 1
+		if p.b2.len > 0 && p.b1.len/p.b2.len > delta {
+			delta = p.b1.len / p.b2.len
+		}
+		p.p = max(p.p-delta, 0)
+		p.forget(g)
+		n.seg = segT2
+		p.t2.pushFront(n)
+	default:
+		n.seg = segT1
+		p.t1.pushFront(n)
+	}
+}
+
+func (p *arcPolicy) access(n *dllNode) {
+	if n.seg == segT1 {
+		p.t1.remove(n)
+		n.seg = segT2
+		p.t2.pushFront(n)
+		return
+	}
+	p.t2.moveToFront(n)
+}
+
+func (p *arcPolicy) remove(n *dllNode) {
+	if n.seg == segT1 {
+		p.t1.remove(n)
+	} else {
+		p.t2.remove(n)
+	}
+	n.seg = segNone
+}
+
+// evict is ARC's REPLACE: take from T1 while it is above its target,
+// otherwise from T2, and remember the victim's key as a ghost.
+func (p *arcPolicy) evict() *dllNode {
+	var victim *dllNode
+	if p.t1.len > 0 && (p.t1.len > p.p || p.t2.len == 0) {
+		victim = p.t1.evictLRU()
+		p.remember(victim.key, segB1)
+	} else if p.t2.len > 0 {
+		victim = p.t2.evictLRU()
+		p.remember(victim.key, segB2)
+	}
+	if victim != nil {
+		victim.seg = segNone
+	}
+	return victim
+}
+
+// walk visits T1 and then T2. Ghosts are not entries and are skipped.
+func (p *arcPolicy) walk(fn func(n *dllNode)) {
+	p.t1.walk(fn)
+	p.t2.walk(fn)
+}
+
+func (p *arcPolicy) remember(key string, seg segment) {
+	g := &dllNode{key: key, seg: seg}
+	p.ghosts[key] = g
+	if seg == segB1 {
+		p.b1.pushFront(g)
+	} else {
+		p.b2.pushFront(g)
+	}
+
+	// Keep |T1|+|B1| <= c and all four lists within 2c.
+	c := max(p.resident(), 1)
+	for p.t1.len+p.b1.len > c && p.b1.len > 0 {
+		p.forget(p.b1.back)
+	}
+	for p.resident()+p.b1.len+p.b2.len > 2*c && p.b2.len > 0 {
+		p.forget(p.b2.back)
+	}
+}
+
+func (p *arcPolicy) forget(g *dllNode) {
+	if g.seg == segB1 {
+		p.b1.remove(g)
+	} else {
+		p.b2.remove(g)
+	}
+	delete(p.ghosts, g.key)
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+)
+
+const benchKeys = 1 << 14
+
+var benchShards = []int{1, 4, 16, 64}
+
+func benchCache(b *testing.B, shards int) (*LruCache, []string) {
+	c := NewLruCache(Options{Capacity: benchKeys, Shards: shards})
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+		if err := c.set(keys[i], []byte("value"), 0, 0, 0, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+	return c, keys
+}
+
+// benchParallel runs op from GOMAXPROCS goroutines against a full cache of
+// each shard count, every goroutine walking the keys from its own offset.
+func benchParallel(b *testing.B, op func(c *LruCache, i int, key string)) {
+	for _, shards := range benchShards {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			c, keys := benchCache(b, shards)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := rand.IntN(len(keys))
+				for pb.Next() {
+					op(c, i, keys[i%len(keys)])
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	benchParallel(b, func(c *LruCache, _ int, key string) {
+		c.get(key)
+	})
+}
+
+func BenchmarkSet(b *testing.B) {
+	value := []byte("value")
+	benchParallel(b, func(c *LruCache, _ int, key string) {
+		c.set(key, value, 0, 0, 0, 0)
+	})
+}
+
+// BenchmarkMixed is nine reads to every write.
+func BenchmarkMixed(b *testing.B) {
+	value := []byte("value")
+	benchParallel(b, func(c *LruCache, i int, key string) {
+		if i%10 == 0 {
+			c.set(key, value, 0, 0, 0, 0)
+			return
+		}
+		c.get(key)
+	})
+}
@@ -0,0 +1,155 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cachepb "github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+)
+
+func TestConditionalWrites(t *testing.T) {
+	// The version of the entry cases start from, and of the tombstone they
+	// start from instead, which is ahead of the clock so that a write after
+	// it is only accepted above it.
+	const condVersion = int64(100)
+	tombVersion := time.Now().Add(time.Hour).UnixNano()
+
+	ctx := context.Background()
+	newItem := &cachepb.SetRequest{Key: "k", Value: []byte("new")}
+
+	type result struct {
+		ok       bool
+		version  int64
+		previous *cachepb.GetResponse
+	}
+	set := func(res *cachepb.ConditionalSetResponse, err error) (result, error) {
+		if err != nil {
+			return result{}, err
+		}
+		return result{res.Success, res.Version, res.Previous}, nil
+	}
+	cas := func(expected int64) func(cn *CacheNode) (result, error) {
+		return func(cn *CacheNode) (result, error) {
+			return set(cn.CompareAndSet(ctx, &cachepb.CompareAndSetRequest{Item: newItem, ExpectedVersion: expected}))
+		}
+	}
+	nx := func(cn *CacheNode) (result, error) { return set(cn.SetIfAbsent(ctx, newItem)) }
+	xx := func(cn *CacheNode) (result, error) { return set(cn.SetIfPresent(ctx, newItem)) }
+	cad := func(expected int64) func(cn *CacheNode) (result, error) {
+		return func(cn *CacheNode) (result, error) {
+			res, err := cn.CompareAndDelete(ctx, &cachepb.CompareAndDeleteRequest{Key: "k", ExpectedVersion: expected})
+			if err != nil {
+				return result{}, err
+			}
+			return result{ok: res.Success, version: res.Version}, nil
+		}
+	}
+
+	tests := []struct {
+		name  string
+		state string // "absent", "present", "expired" or "deleted"
+		op    func(cn *CacheNode) (result, error)
+		ok    bool
+		value string // left stored afterwards, "" for none
+		// version is the version reported on failure; on success it must be
+		// above every version the key had before.
+		version int64
+	}{
+		{"cas match", "present", cas(condVersion), true, "new", 0},
+		{"cas stale", "present", cas(condVersion - 1), false, "old", condVersion},
+		{"cas absent", "absent", cas(condVersion), false, "", 0},
+		{"cas absent zero", "absent", cas(0), false, "", 0},
+		{"cas expired", "expired", cas(condVersion), false, "", 0},
+		{"nx absent", "absent", nx, true, "new", 0},
+		{"nx present", "present", nx, false, "old", condVersion},
+		{"nx expired", "expired", nx, true, "new", 0},
+		{"nx deleted", "deleted", nx, true, "new", 0},
+		{"xx present", "present", xx, true, "new", 0},
+		{"xx absent", "absent", xx, false, "", 0},
+		{"xx expired", "expired", xx, false, "", 0},
+		{"cad match", "present", cad(condVersion), true, "", 0},
+		{"cad stale", "present", cad(condVersion + 1), false, "old", condVersion},
+		{"cad absent", "absent", cad(condVersion), false, "", 0},
+		{"cad deleted", "deleted", cad(tombVersion), false, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cn := NewCacheNode(Options{Capacity: 10, TombstoneGrace: time.Hour})
+			t.Cleanup(func() { cn.Close() })
+			old := record{key: "k", value: []byte("old"), version: condVersion}
+			switch tt.state {
+			case "present":
+				cn.lru.shardFor("k").set(old)
+			case "expired":
+				old.expiresAt = time.Now().Add(-time.Second)
+				cn.lru.shardFor("k").set(old)
+			case "deleted":
+				cn.lru.delete("k", tombVersion, false)
+			}
+
+			got, err := tt.op(cn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ok != tt.ok {
+				t.Errorf("success = %v, want %v", got.ok, tt.ok)
+			}
+			if tt.ok {
+				floor := map[string]int64{"present": condVersion, "deleted": tombVersion}[tt.state]
+				if got.version <= floor {
+					t.Errorf("version = %d, want above %d", got.version, floor)
+				}
+				// A write that replaced an entry returns it, so that it can
+				// be undone.
+				replaced := tt.state == "present" && tt.value != ""
+				if replaced != (got.previous != nil) {
+					t.Errorf("previous = %v, want one %v", got.previous, replaced)
+				}
+				if got.previous != nil && (string(got.previous.Value) != "old" || got.previous.Version != condVersion) {
+					t.Errorf("previous = %q at %d, want old at %d", got.previous.Value, got.previous.Version, condVersion)
+				}
+			} else {
+				if got.version != tt.version {
+					t.Errorf("version = %d, want %d", got.version, tt.version)
+				}
+				if got.previous != nil {
+					t.Errorf("previous = %v on a failed write", got.previous)
+				}
+			}
+
+			it, err := cn.lru.get("k")
+			if stored := err == nil; stored != (tt.value != "") || stored && string(it.value) != tt.value {
+				t.Errorf("stored %q (found %v), want %q", it.value, stored, tt.value)
+			}
+		})
+	}
+}
+
+func TestCompareAndDrop(t *testing.T) {
+	ctx := context.Background()
+	cn := NewCacheNode(Options{Capacity: 10, TombstoneGrace: time.Hour})
+	t.Cleanup(func() { cn.Close() })
+	cn.lru.shardFor("k").set(record{key: "k", value: []byte("old"), version: 100})
+
+	// A drop at a stale version leaves the value alone.
+	res, err := cn.CompareAndDelete(ctx, &cachepb.CompareAndDeleteRequest{Key: "k", ExpectedVersion: 99, Drop: true})
+	if err != nil || res.Success || res.Version != 100 {
+		t.Fatalf("stale drop = %v at %d, %v, want a failure at 100", res.GetSuccess(), res.GetVersion(), err)
+	}
+	if _, err := cn.lru.get("k"); err != nil {
+		t.Errorf("value gone after a stale drop: %v", err)
+	}
+
+	// One at the stored version removes it without a tombstone.
+	res, err = cn.CompareAndDelete(ctx, &cachepb.CompareAndDeleteRequest{Key: "k", ExpectedVersion: 100, Drop: true})
+	if err != nil || !res.Success || res.Version != 0 {
+		t.Fatalf("drop = %v at %d, %v, want success at 0", res.GetSuccess(), res.GetVersion(), err)
+	}
+	if _, err := cn.lru.get("k"); err == nil {
+		t.Error("value still stored after the drop")
+	}
+	if v, ok := cn.lru.tombstone("k"); ok {
+		t.Errorf("drop left a tombstone at %d", v)
+	}
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	cachepb "github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+)
+
+var (
+	// ErrNotNumeric is returned by a counter operation on a value that is
+	// not a decimal number.
+	ErrNotNumeric = errors.New("value is not a number")
+	// ErrOverflow is returned by a counter operation whose result would not
+	// fit the counter.
+	ErrOverflow = errors.New("counter out of range")
+
+	errNoCounter = errors.New(ERRKEYNOTFOUND)
+)
+
+// counterRecord returns the entry a counter operation starts from: the live
+// one, or, if there is none and create is set, a new one with a nil value
+// that expires after ttl. A live entry keeps its flags and expiry.
+func counterRecord(cur *dllNode, create bool, ttl time.Duration) (record, error) {
+	if cur != nil {
+		return cur.record(), nil
+	}
+	if !create {
+		return record{}, errNoCounter
+	}
+	return newRecord("", nil, 0, ttl, 0, 0), nil
+}
+
+// incr adds delta to the integer stored under key, or subtracts it if decr
+// is set, and returns the entry it leaves. A missing key counts as initial
+// if create is set. With OVERFLOW_FAIL the value is a signed 64-bit integer
+// and a result outside that range is refused with ErrOverflow; with
+// OVERFLOW_UNSIGNED it is unsigned, wraps around on increment and stops at
+// zero on decrement, as memcached counters do. The entry it replaced is
+// returned too, nil if the key was created.
+func (c *LruCache) incr(key string, delta uint64, decr bool, overflow cachepb.Overflow, create bool, initial int64, ttl time.Duration) (record, *record, error) {
+	unsigned := overflow == cachepb.Overflow_OVERFLOW_UNSIGNED
+	return c.shardFor(key).apply(key, func(cur *dllNode) (record, error) {
+		rec, err := counterRecord(cur, create, ttl)
+		if err != nil {
+			return record{}, err
+		}
+		if cur == nil {
+			if unsigned && initial < 0 {
+				return record{}, ErrOverflow
+			}
+			rec.value = strconv.AppendInt(nil, initial, 10)
+		}
+		if unsigned {
+			rec.value, err = addUnsigned(rec.value, delta, decr)
+		} else {
+			rec.value, err = addSigned(rec.value, delta, decr)
+		}
+		return rec, err
+	})
+}
+
+func addSigned(value []byte, delta uint64, decr bool) ([]byte, error) {
+	n, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return nil, ErrNotNumeric
+	}
+	if delta > math.MaxInt64 {
+		return nil, ErrOverflow
+	}
+	d := int64(delta)
+	if decr {
+		if n < math.MinInt64+d {
+			return nil, ErrOverflow
+		}
+		n -= d
+	} else {
+		if n > math.MaxInt64-d {
+			return nil, ErrOverflow
+		}
+		n += d
+	}
+	return strconv.AppendInt(nil, n, 10), nil
+}
+
+func addUnsigned(value []byte, delta uint64, decr bool) ([]byte, error) {
+	n, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return nil, ErrNotNumeric
+	}
+	switch {
+	case !decr:
+		n += delta
+	case delta > n:
+		n = 0
+	default:
+		n -= delta
+	}
+	return strconv.AppendUint(nil, n, 10), nil
+}
+
+// incrFloat adds delta to the number stored under key and returns the entry
+// it leaves and the one it replaced, as incr does. A missing key counts as
+// initial if create is set. A result that is not finite is refused with
+// ErrOverflow.
+func (c *LruCache) incrFloat(key string, delta float64, create bool, initial float64, ttl time.Duration) (record, *record, error) {
+	return c.shardFor(key).apply(key, func(cur *dllNode) (record, error) {
+		rec, err := counterRecord(cur, create, ttl)
+		if err != nil {
+			return record{}, err
+		}
+		n := initial
+		if cur != nil {
+			n, err = strconv.ParseFloat(string(rec.value), 64)
+			if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+				return record{}, ErrNotNumeric
+			}
+		}
+		n += delta
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return record{}, ErrOverflow
+		}
+		rec.value = strconv.AppendFloat(nil, n, 'f', -1, 64)
+		return rec, nil
+	})
+}
+
+// item returns the entry as a reader at now would see it.
+func (r record) item(now time.Time) item {
+	it := item{value: r.value, flags: r.flags, version: r.version}
+	if !r.expiresAt.IsZero() {
+		it.ttl = r.expiresAt.Sub(now)
+	}
+	if !r.staleAt.IsZero() {
+		if now.Before(r.staleAt) {
+			it.softTTL = r.staleAt.Sub(now)
+		} else {
+			it.stale = true
+		}
+	}
+	return it
+}
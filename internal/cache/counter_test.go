@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+
+	cachepb "github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+)
+
+func TestIncr(t *testing.T) {
+	const (
+		signed   = cachepb.Overflow_OVERFLOW_FAIL
+		unsigned = cachepb.Overflow_OVERFLOW_UNSIGNED
+	)
+	maxInt := strconv.FormatInt(math.MaxInt64, 10)
+	minInt := strconv.FormatInt(math.MinInt64, 10)
+	maxUint := strconv.FormatUint(math.MaxUint64, 10)
+	tests := []struct {
+		name     string
+		stored   string // "" for a missing key
+		delta    uint64
+		decr     bool
+		overflow cachepb.Overflow
+		create   bool
+		initial  int64
+		want     string
+		err      error
+	}{
+		{"add", "5", 3, false, signed, false, 0, "8", nil},
+		{"subtract below zero", "5", 8, true, signed, false, 0, "-3", nil},
+		{"to max", "9223372036854775806", 1, false, signed, false, 0, maxInt, nil},
+		{"past max", maxInt, 1, false, signed, false, 0, "", ErrOverflow},
+		{"to min", "-9223372036854775807", 1, true, signed, false, 0, minInt, nil},
+		{"past min", minInt, 1, true, signed, false, 0, "", ErrOverflow},
+		{"delta past max", "0", math.MaxInt64 + 1, false, signed, false, 0, "", ErrOverflow},
+		{"unsigned past max wraps", maxUint, 2, false, unsigned, false, 0, "1", nil},
+		{"unsigned above max int", maxInt, 1, false, unsigned, false, 0, "9223372036854775808", nil},
+		{"unsigned below zero stops", "5", 8, true, unsigned, false, 0, "0", nil},
+		{"unsigned negative stored", "-1", 1, false, unsigned, false, 0, "", ErrNotNumeric},
+		{"not a number", "abc", 1, false, signed, false, 0, "", ErrNotNumeric},
+		{"float", "1.5", 1, false, signed, false, 0, "", ErrNotNumeric},
+		{"missing", "", 1, false, signed, false, 0, "", errNoCounter},
+		{"created", "", 2, false, signed, true, 10, "12", nil},
+		{"created decr", "", 2, true, signed, true, 10, "8", nil},
+		{"created past max", "", 1, false, signed, true, math.MaxInt64, "", ErrOverflow},
+		{"created unsigned negative", "", 1, false, unsigned, true, -1, "", ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(PolicyLRU, 10)
+			if tt.stored != "" {
+				c.set("n", []byte(tt.stored), 0, 0, 0, 0)
+			}
+			rec, prev, err := c.incr("n", tt.delta, tt.decr, tt.overflow, tt.create, tt.initial, 0)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("incr = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				// A refused operation leaves the counter as it was.
+				if it, gerr := c.get("n"); tt.stored != "" && (gerr != nil || string(it.value) != tt.stored) {
+					t.Errorf("counter = %q after a refused incr, want %q", it.value, tt.stored)
+				}
+				return
+			}
+			if string(rec.value) != tt.want {
+				t.Errorf("value = %s, want %s", rec.value, tt.want)
+			}
+			if created := prev == nil; created != (tt.stored == "") {
+				t.Errorf("previous = %v, want one only for an existing counter", prev)
+			}
+		})
+	}
+}
+
+func TestIncrFloat(t *testing.T) {
+	tests := []struct {
+		stored  string
+		delta   float64
+		create  bool
+		initial float64
+		want    string
+		err     error
+	}{
+		{"1.5", 0.25, false, 0, "1.75", nil},
+		{"10", -2.5, false, 0, "7.5", nil},
+		{"3", 0, false, 0, "3", nil},
+		{"", 1.5, true, 36.6, "38.1", nil},
+		{"", 0.5, true, 0, "0.5", nil},
+		{"", 1, false, 0, "", errNoCounter},
+		{"abc", 1, false, 0, "", ErrNotNumeric},
+		{"inf", 1, false, 0, "", ErrNotNumeric},
+		{"NaN", 1, false, 0, "", ErrNotNumeric},
+		{"1.7976931348623157e308", 1.7976931348623157e308, false, 0, "", ErrOverflow},
+		{"", math.MaxFloat64, true, math.MaxFloat64, "", ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s%+g", tt.stored, tt.delta), func(t *testing.T) {
+			c := newTestCache(PolicyLRU, 10)
+			if tt.stored != "" {
+				c.set("n", []byte(tt.stored), 0, 0, 0, 0)
+			}
+			rec, _, err := c.incrFloat("n", tt.delta, tt.create, tt.initial, 0)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("incrFloat = %v, want %v", err, tt.err)
+			}
+			if err == nil && string(rec.value) != tt.want {
+				t.Errorf("value = %s, want %s", rec.value, tt.want)
+			}
+		})
+	}
+}
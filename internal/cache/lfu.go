@@ -0,0 +1,84 @@
+package cache
+
+import "slices"
+
+// lfuPolicy is an O(1) LFU: entries are bucketed by access count, each
+// bucket being an LRU list so that ties go to the least recently used.
+type lfuPolicy struct {
+	buckets map[uint32]*DLL
+	minFreq uint32
+}
+
+func newLFUPolicy() *lfuPolicy {
+	return &lfuPolicy{buckets: make(map[uint32]*DLL)}
+}
+
+func (p *lfuPolicy) push(n *dllNode) {
+	b, ok := p.buckets[n.freq]
+	if !ok {
+		b = &DLL{}
+		p.buckets[n.freq] = b
+	}
+	b.pushFront(n)
+}
+
+// unlink takes n out of its bucket, dropping the bucket once empty.
+func (p *lfuPolicy) unlink(n *dllNode) {
+	b := p.buckets[n.freq]
+	b.remove(n)
+	if b.len == 0 {
+		delete(p.buckets, n.freq)
+	}
+}
+
+func (p *lfuPolicy) add(n *dllNode) {
+	n.freq = 1
+	p.push(n)
+	p.minFreq = 1
+}
+
+func (p *lfuPolicy) access(n *dllNode) {
+	p.unlink(n)
+	if p.minFreq == n.freq && p.buckets[n.freq] == nil {
+		p.minFreq++
+	}
+	n.freq++
+	p.push(n)
+}
+
+func (p *lfuPolicy) remove(n *dllNode) {
+	// minFreq may now point at a missing bucket; evict recomputes it.
+	p.unlink(n)
+}
+
+func (p *lfuPolicy) evict() *dllNode {
+	if len(p.buckets) == 0 {
+		return nil
+	}
+	b, ok := p.buckets[p.minFreq]
+	if !ok {
+		first := true
+		for f := range p.buckets {
+			if first || f < p.minFreq {
+				p.minFreq, first = f, false
+			}
+		}
+		b = p.buckets[p.minFreq]
+	}
+	victim := b.back
+	p.unlink(victim)
+	return victim
+}
+
+// walk visits buckets from the lowest access count up. Re-added entries
+// all start at a count of one, so only their order survives.
+func (p *lfuPolicy) walk(fn func(n *dllNode)) {
+	freqs := make([]uint32, 0, len(p.buckets))
+	for f := range p.buckets {
+		freqs = append(freqs, f)
+	}
+	slices.Sort(freqs)
+	for _, f := range freqs {
+		p.buckets[f].walk(fn)
+	}
+}
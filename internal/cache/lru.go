@@ -2,46 +2,150 @@ package cache
 
 import (
 	"errors"
-	"log"
+	"hash/maphash"
+	"log/slog"
 	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/sakshamg567/cachy/internal/logging"
 )
 
 type dllNode struct {
-	key   string
-	value string
-	next  *dllNode
-	prev  *dllNode
+	key       string
+	value     []byte
+	flags     uint32    // opaque to the cache, for memcached clients
+	expiresAt time.Time // zero means the entry never expires
+	staleAt   time.Time // zero means the entry never turns stale
+	version   int64     // writer's timestamp, or one chosen on write
+	// leaseUntil is when the refresh handed to a reader of the stale entry
+	// lapses and another reader may be asked instead.
+	leaseUntil time.Time
+	next       *dllNode
+	prev       *dllNode
+
+	// bookkeeping owned by the eviction policy
+	freq uint32
+	seg  segment
+}
+
+// entryOverhead approximates the memory an entry costs beyond its key and
+// value: the list node itself plus its slot in the map.
+const entryOverhead = int64(unsafe.Sizeof(dllNode{})) + 48
+
+func entrySize(key string, value []byte) int64 {
+	return entryOverhead + int64(len(key)) + int64(len(value))
+}
+
+func (n *dllNode) size() int64 {
+	return entrySize(n.key, n.value)
+}
+
+func (n *dllNode) expired(now time.Time) bool {
+	return !n.expiresAt.IsZero() && !now.Before(n.expiresAt)
+}
+
+// ttl returns the time left before the entry expires, or 0 if it has no expiry.
+func (n *dllNode) ttl(now time.Time) time.Duration {
+	if n.expiresAt.IsZero() {
+		return 0
+	}
+	return n.expiresAt.Sub(now)
+}
+
+// record returns the entry as written.
+func (n *dllNode) record() record {
+	return record{n.key, n.value, n.flags, n.expiresAt, n.staleAt, n.version}
+}
+
+func (n *dllNode) stale(now time.Time) bool {
+	return !n.staleAt.IsZero() && !now.Before(n.staleAt)
+}
+
+// refreshLease is how long the reader told to refresh a stale entry has to
+// write it back before another reader is told to instead.
+const refreshLease = 5 * time.Second
+
+// item is an entry as handed to readers.
+type item struct {
+	value   []byte
+	flags   uint32
+	ttl     time.Duration // remaining time to live, 0 if the entry never expires
+	softTTL time.Duration // remaining time before it turns stale, 0 if never or already stale
+	version int64
+
+	stale      bool // past its soft ttl
+	revalidate bool // this reader holds the refresh lease
+}
+
+// record is an entry as written, with absolute expiry times. It is what
+// snapshots and the append-only log store.
+type record struct {
+	key       string
+	value     []byte
+	flags     uint32
+	expiresAt time.Time
+	staleAt   time.Time
+	version   int64
+}
+
+// newRecord turns relative ttls into a record. softTTL only applies when it
+// ends before ttl does.
+func newRecord(key string, value []byte, flags uint32, ttl, softTTL time.Duration, version int64) record {
+	r := record{key: key, value: value, flags: flags, version: version}
+	now := time.Now()
+	if ttl > 0 {
+		r.expiresAt = now.Add(ttl)
+	}
+	if softTTL > 0 && (ttl <= 0 || softTTL < ttl) {
+		r.staleAt = now.Add(softTTL)
+	}
+	return r
 }
 
 type DLL struct {
 	front *dllNode
 	back  *dllNode
+	len   int
+}
+
+func (d *DLL) pushFront(node *dllNode) {
+	node.prev = nil
+	node.next = d.front
+	if d.front != nil {
+		d.front.prev = node
+	}
+	d.front = node
+	if d.back == nil {
+		d.back = node
+	}
+	d.len++
 }
 
 func (d *DLL) moveToFront(node *dllNode) {
 	if d.front == node {
 		return
 	}
+	d.remove(node)
+	d.pushFront(node)
+}
 
+func (d *DLL) remove(node *dllNode) {
 	if node.prev != nil {
 		node.prev.next = node.next
 	}
 	if node.next != nil {
 		node.next.prev = node.prev
 	}
+	if d.front == node {
+		d.front = node.next
+	}
 	if d.back == node {
 		d.back = node.prev
 	}
-
 	node.prev = nil
-	node.next = d.front
-	if d.front != nil {
-		d.front.prev = node
-	}
-	d.front = node
-	if d.back == nil {
-		d.back = d.front
-	}
+	node.next = nil
+	d.len--
 }
 
 func (d *DLL) evictLRU() *dllNode {
@@ -49,131 +153,664 @@ func (d *DLL) evictLRU() *dllNode {
 		return nil
 	}
 	node := d.back
-	if node.prev != nil {
-		node.prev.next = nil
-	}
-	d.back = node.prev
-	if d.back == nil {
-		d.front = nil
-	}
+	d.remove(node)
 	return node
 }
 
+// walk calls fn for each node from the back (least recently used) to the
+// front.
+func (d *DLL) walk(fn func(n *dllNode)) {
+	for n := d.back; n != nil; n = n.prev {
+		fn(n)
+	}
+}
+
+// Options bound the size of a cache. Zero values mean no limit.
+type Options struct {
+	Capacity     int   // maximum number of entries
+	MaxMemory    int64 // maximum bytes across keys, values and per-entry overhead
+	MaxEntrySize int64 // largest single entry accepted, in the same units
+
+	// Shards is how many independently locked partitions the cache is split
+	// into. Each gets an equal share of Capacity and MaxMemory, so an entry
+	// must fit in MaxMemory/Shards.
+	Shards int
+
+	Eviction      EvictionPolicy // which entry to drop when full, LRU by default
+	SweepInterval time.Duration  // how often expired keys are removed in the background
+
+	// TombstoneGrace is how long a delete is remembered after its version,
+	// so that replicas which missed it are repaired instead of bringing the
+	// key back; 0 keeps no tombstones. It should exceed the time replicas
+	// may take to converge.
+	TombstoneGrace time.Duration
+
+	// SnapshotPath is where the node saves its contents and restores them
+	// from on startup; empty disables snapshots. SnapshotInterval is how
+	// often it is rewritten, 0 meaning only on shutdown.
+	SnapshotPath     string
+	SnapshotInterval time.Duration
+
+	// AOFPath is the append-only log every write is recorded in and which
+	// is replayed on startup; empty disables it. When set, the log is the
+	// source of truth on startup and any snapshot is not loaded.
+	AOFPath  string
+	AOFFsync FsyncPolicy
+	// AOFRewriteSize is the size past which the log is compacted, once it
+	// has also doubled since the last compaction. 0 disables compaction.
+	AOFRewriteSize int64
+
+	Logger *slog.Logger // slog.Default() if nil
+}
+
+// LruCache is the store behind a cache node. Keys are spread over a number
+// of independent shards, each with its own map, eviction policy and lock, so
+// requests for different keys rarely contend. Despite the name, the order in
+// which entries are evicted is decided by the policy, LRU being the default.
 type LruCache struct {
-	capacity int
-	cache    map[string]*dllNode
-	dll      *DLL
-	mu       sync.RWMutex
+	shards []*shard
+	seed   maphash.Seed
+	aof    *appendLog
+	log    *slog.Logger
+}
+
+// shard is one independently locked partition of an LruCache. Its entry and
+// byte limits are its share of the cache-wide ones.
+type shard struct {
+	capacity     int
+	maxBytes     int64
+	maxEntrySize int64
+	usedBytes    int64
+	cache        map[string]*dllNode
+	tombs        map[string]int64 // key -> version it was deleted at
+	grace        time.Duration    // how long tombstones are kept
+	policy       policy
+	aof          *appendLog // nil unless the cache has an append-only log
+	log          *slog.Logger
+	stats        stats
+	mu           sync.RWMutex
+}
+
+func NewLruCache(opts Options) *LruCache {
+	n := max(opts.Shards, 1)
+	if opts.Capacity > 0 {
+		// every shard must be able to hold at least one entry
+		n = min(n, opts.Capacity)
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	c := &LruCache{
+		shards: make([]*shard, n),
+		seed:   maphash.MakeSeed(),
+		log:    logger,
+	}
+	hint := max(sizeHint(opts)/n, 1)
+	for i := range c.shards {
+		s := &shard{
+			maxEntrySize: opts.MaxEntrySize,
+			cache:        map[string]*dllNode{},
+			tombs:        map[string]int64{},
+			grace:        opts.TombstoneGrace,
+			policy:       newPolicy(opts.Eviction, hint),
+			log:          logger,
+		}
+		// Spread any remainder over the first shards so the totals add up.
+		if opts.Capacity > 0 {
+			s.capacity = opts.Capacity / n
+			if i < opts.Capacity%n {
+				s.capacity++
+			}
+		}
+		if opts.MaxMemory > 0 {
+			s.maxBytes = opts.MaxMemory / int64(n)
+			if int64(i) < opts.MaxMemory%int64(n) {
+				s.maxBytes++
+			}
+		}
+		c.shards[i] = s
+	}
+	return c
+}
+
+func (c *LruCache) shardFor(key string) *shard {
+	return c.shards[maphash.String(c.seed, key)%uint64(len(c.shards))]
+}
+
+func (c *LruCache) get(key string) (item, error) {
+	return c.shardFor(key).get(key)
+}
+
+func (c *LruCache) set(key string, value []byte, flags uint32, ttl, softTTL time.Duration, version int64) error {
+	return c.shardFor(key).set(newRecord(key, value, flags, ttl, softTTL, version))
+}
+
+// setIf stores the entry only if cond holds for the key's current one. It
+// reports whether it did, and the version stored under key afterwards.
+func (c *LruCache) setIf(key string, value []byte, flags uint32, ttl, softTTL time.Duration, version int64, cond condition) (bool, int64, error) {
+	return c.shardFor(key).setIf(newRecord(key, value, flags, ttl, softTTL, version), cond)
+}
+
+// delete removes key, leaving a tombstone at version unless drop is set.
+// See shard.delete.
+func (c *LruCache) delete(key string, version int64, drop bool) (bool, error) {
+	ok, _, err := c.shardFor(key).delete(key, version, !drop, nil)
+	return ok, err
+}
+
+// deleteIf deletes key, leaving a tombstone unless drop is set, only if it
+// is stored at version expected. It reports whether it did, and the
+// version of the tombstone, or else the version stored, 0 if none.
+func (c *LruCache) deleteIf(key string, expected int64, drop bool) (bool, int64, error) {
+	return c.shardFor(key).delete(key, 0, !drop, func(cur *dllNode) bool {
+		return cur != nil && cur.version == expected
+	})
+}
+
+// tombstone returns the version key was deleted at, if its tombstone is
+// still kept.
+func (c *LruCache) tombstone(key string) (int64, bool) {
+	s := c.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tombstone(key, time.Now())
+}
+
+func (c *LruCache) exists(key string) bool {
+	return c.shardFor(key).exists(key)
+}
+
+func (c *LruCache) GetAllKeys() []string {
+	var keys []string
+	for _, s := range c.shards {
+		keys = s.appendKeys(keys)
+	}
+	return keys
+}
+
+// Len returns the number of entries across all shards.
+func (c *LruCache) Len() int {
+	n := 0
+	for _, s := range c.shards {
+		s.mu.RLock()
+		n += len(s.cache)
+		s.mu.RUnlock()
+	}
+	return n
 }
 
-func NewLruCache(cap int) *LruCache {
-	return &LruCache{
-		capacity: cap,
-		cache:    map[string]*dllNode{},
-		dll:      &DLL{},
+// UsedBytes returns the memory accounted to entries across all shards.
+func (c *LruCache) UsedBytes() int64 {
+	var n int64
+	for _, s := range c.shards {
+		s.mu.RLock()
+		n += s.usedBytes
+		s.mu.RUnlock()
 	}
+	return n
 }
 
 const (
 	ERRKEYNOTFOUND = "key not found"
 )
 
-func (c *LruCache) get(key string) (string, error) {
-	c.mu.Lock()
+var (
+	ErrEntryTooLarge = errors.New("entry exceeds the maximum entry size")
+	ErrEmptyKey      = errors.New("key must not be empty")
+)
+
+const (
+	// sweepSampleSize is how many entries a single sweep pass inspects.
+	sweepSampleSize = 20
+)
+
+// get returns the entry for key. Expired entries are removed lazily here.
+// The first reader of a stale entry, and the next one each time the refresh
+// lease lapses, is told to revalidate it; the others are served the stale
+// value.
+func (s *shard) get(key string) (item, error) {
+	now := time.Now()
+
+	s.mu.Lock()
 	var (
-		val string
-		ok  bool
+		it      item
+		ok      bool
+		expired bool
 	)
-	if node, exists := c.cache[key]; exists {
-		c.dll.moveToFront(node)
-		val = node.value
-		ok = true
+	if node, exists := s.cache[key]; exists {
+		if node.expired(now) {
+			s.removeNode(node)
+			expired = true
+		} else {
+			s.policy.access(node)
+			it = item{value: node.value, flags: node.flags, ttl: node.ttl(now), version: node.version}
+			if node.stale(now) {
+				it.stale = true
+				if !now.Before(node.leaseUntil) {
+					node.leaseUntil = now.Add(refreshLease)
+					it.revalidate = true
+				}
+			} else if !node.staleAt.IsZero() {
+				it.softTTL = node.staleAt.Sub(now)
+			}
+			ok = true
+		}
 	}
-	c.mu.Unlock()
+	s.mu.Unlock()
 
 	if ok {
-		log.Printf("CACHE GET key=%q hit value=%q", key, val)
-		return val, nil
+		s.stats.hits.Add(1)
+		result := "hit"
+		if it.stale {
+			s.stats.staleHits.Add(1)
+			result = "stale"
+		}
+		s.log.Debug("cache get", "key", key, "result", result, logging.Value(it.value), "ttl", it.ttl, "revalidate", it.revalidate)
+		return it, nil
 	}
-	log.Printf("CACHE GET key=%q miss", key)
-	return "", errors.New(ERRKEYNOTFOUND)
+	s.stats.misses.Add(1)
+	if expired {
+		s.stats.expired.Add(1)
+		s.log.Debug("cache get", "key", key, "result", "expired")
+	} else {
+		s.log.Debug("cache get", "key", key, "result", "miss")
+	}
+	return item{}, errors.New(ERRKEYNOTFOUND)
 }
 
-func (c *LruCache) set(key string, value string) bool {
-	var (
-		action     string // "update" or "insert"
-		evictedKey string
-	)
+// set stores rec. An entry with an expiry time lives until then, or else
+// until evicted or deleted. Entries chosen by the eviction policy are
+// dropped until the new one fits, and entries that could never fit are
+// rejected with ErrEntryTooLarge. A versioned write older than the stored
+// entry is dropped without error, so that the last write wins whatever
+// order replicas receive them in.
+func (s *shard) set(rec record) error {
+	_, _, err := s.setIf(rec, nil)
+	return err
+}
+
+// setIf is set for a write that only goes ahead if cond, when not nil,
+// holds. It reports whether the entry was stored, and the version stored
+// under its key afterwards.
+func (s *shard) setIf(rec record, cond condition) (bool, int64, error) {
+	action, evicted, err := s.store(&rec, cond)
+	if err != nil {
+		s.log.Debug("cache set", "key", rec.key, "result", "rejected", "size", entrySize(rec.key, rec.value))
+		return false, 0, err
+	}
+	if action == actionSuperseded || action == actionFailed {
+		s.log.Debug("cache set", "key", rec.key, "result", action, "version", rec.version)
+		return false, rec.version, nil
+	}
+	if s.aof != nil {
+		if err := s.aof.commit(); err != nil {
+			return false, 0, err
+		}
+	}
+	s.stats.sets.Add(1)
+
+	s.log.Debug("cache set", "key", rec.key, "result", action, logging.Value(rec.value), "expires_at", rec.expiresAt, "stale_at", rec.staleAt, "version", rec.version, "evicted", evicted)
+	return true, rec.version, nil
+}
+
+// Results of a store.
+const (
+	actionInsert     = "insert"
+	actionUpdate     = "update"
+	actionSuperseded = "superseded"          // a newer version is already stored
+	actionFailed     = "precondition_failed" // a conditional write's condition did not hold
+)
 
-	c.mu.Lock()
-	if node, ok := c.cache[key]; ok {
-		node.value = value
-		c.dll.moveToFront(node)
-		action = "update"
+// condition decides, under the shard lock, whether a conditional write goes
+// ahead. cur is the live entry for the key, or nil if there is none.
+type condition func(cur *dllNode) bool
+
+// store inserts or updates an entry, records it in the append-only log and
+// evicts until the shard is back within its limits. It fails without
+// writing if the log has failed under the always policy. A write without a
+// version is given the current time, and a conditional one that passes
+// cond is stored above the version it replaces, so a key's version
+// increases with every write. Afterwards rec.version is the version stored
+// under the key, 0 if there is none.
+func (s *shard) store(rec *record, cond condition) (action string, evicted int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.storeLocked(rec, cond)
+}
+
+// storeLocked is store for a caller that holds s.mu.
+func (s *shard) storeLocked(rec *record, cond condition) (action string, evicted int, err error) {
+	key := rec.key
+	if key == "" {
+		return "", 0, ErrEmptyKey
+	}
+	size := entrySize(key, rec.value)
+	if (s.maxEntrySize > 0 && size > s.maxEntrySize) || (s.maxBytes > 0 && size > s.maxBytes) {
+		return "", 0, ErrEntryTooLarge
+	}
+	if err := s.aof.check(); err != nil {
+		return "", 0, err
+	}
+
+	now := time.Now()
+	node, ok := s.cache[key]
+	var cur *dllNode
+	if ok && !node.expired(now) {
+		cur = node
+	}
+	tomb, deleted := s.tombstone(key, now)
+	switch {
+	case cond != nil && !cond(cur):
+		rec.version = 0
+		if cur != nil {
+			rec.version = cur.version
+		}
+		return actionFailed, 0, nil
+	case cond == nil && cur != nil && rec.version != 0 && rec.version < cur.version:
+		rec.version = cur.version
+		return actionSuperseded, 0, nil
+	case cond == nil && deleted && rec.version != 0 && rec.version <= tomb:
+		// written before a delete this shard has already seen
+		rec.version = 0
+		return actionSuperseded, 0, nil
+	}
+	unversioned := rec.version == 0
+	if unversioned {
+		rec.version = now.UnixNano()
+	}
+	if cur != nil && rec.version <= cur.version && (cond != nil || unversioned) {
+		rec.version = cur.version + 1
+	}
+	if deleted && rec.version <= tomb {
+		rec.version = tomb + 1
+	}
+	delete(s.tombs, key)
+	if ok {
+		s.usedBytes += size - node.size()
+		node.value = rec.value
+		node.flags = rec.flags
+		node.expiresAt = rec.expiresAt
+		node.staleAt = rec.staleAt
+		node.version = rec.version
+		node.leaseUntil = time.Time{}
+		s.policy.access(node)
+		action = actionUpdate
 	} else {
-		if len(c.cache) >= c.capacity {
-			evicted := c.dll.evictLRU()
-			if evicted != nil {
-				evictedKey = evicted.key
-				delete(c.cache, evicted.key)
-			}
+		node = &dllNode{
+			key:       key,
+			value:     rec.value,
+			flags:     rec.flags,
+			expiresAt: rec.expiresAt,
+			staleAt:   rec.staleAt,
+			version:   rec.version,
+		}
+		s.cache[key] = node
+		s.policy.add(node)
+		s.usedBytes += size
+		action = actionInsert
+	}
+	// A policy may pick the entry just written as its victim (LFU does for
+	// a fresh key). It is kept and handed back to the policy once enough
+	// other entries are gone; the size check above guarantees it fits alone.
+	spared := false
+	for s.overBudget() {
+		victim := s.policy.evict()
+		if victim == nil {
+			break
 		}
-		newNode := &dllNode{
-			key:   key,
-			value: value,
+		if victim == node {
+			spared = true
+			continue
 		}
-		c.dll.moveToFront(newNode)
-		c.cache[key] = newNode
-		action = "insert"
+		delete(s.cache, victim.key)
+		s.usedBytes -= victim.size()
+		evicted++
+	}
+	s.stats.evictions.Add(uint64(evicted))
+	if spared {
+		s.policy.add(node)
+	}
+	if s.aof != nil {
+		s.aof.appendSet(*rec)
 	}
-	c.mu.Unlock()
+	return action, evicted, nil
+}
 
-	if evictedKey != "" {
-		log.Printf("CACHE SET key=%q %s value=%q evicted=%q", key, action, value, evictedKey)
-	} else {
-		log.Printf("CACHE SET key=%q %s value=%q", key, action, value)
+// apply replaces the entry for key with the one fn derives from it, reading
+// and writing under one lock. fn is given the live entry, or nil if there
+// is none, and may fail to leave it as it is. The new entry is versioned
+// above the one it replaces and logged like any other write. apply returns
+// it together with the entry it replaced, nil if there was none.
+func (s *shard) apply(key string, fn func(cur *dllNode) (record, error)) (record, *record, error) {
+	s.mu.Lock()
+	var cur *dllNode
+	if node, ok := s.cache[key]; ok && !node.expired(time.Now()) {
+		cur = node
+	}
+	rec, err := fn(cur)
+	if err != nil {
+		s.mu.Unlock()
+		return record{}, nil, err
 	}
-	return true
+	var prev *record
+	if cur != nil {
+		r := cur.record()
+		prev = &r
+	}
+	rec.key, rec.version = key, 0
+	action, evicted, err := s.storeLocked(&rec, nil)
+	s.mu.Unlock()
+	if err != nil {
+		return record{}, nil, err
+	}
+	if s.aof != nil {
+		if err := s.aof.commit(); err != nil {
+			return record{}, nil, err
+		}
+	}
+	s.stats.sets.Add(1)
+
+	s.log.Debug("cache set", "key", key, "result", action, logging.Value(rec.value), "expires_at", rec.expiresAt, "stale_at", rec.staleAt, "version", rec.version, "evicted", evicted)
+	return rec, prev, nil
 }
 
-func (c *LruCache) GetAllKeys() []string {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// overBudget reports whether the cache holds more entries or bytes than it
+// is allowed to. It must be called with s.mu held.
+func (s *shard) overBudget() bool {
+	return (s.capacity > 0 && len(s.cache) > s.capacity) ||
+		(s.maxBytes > 0 && s.usedBytes > s.maxBytes)
+}
+
+// removeNode unlinks node and releases its memory. It must be called with
+// s.mu held.
+func (s *shard) removeNode(node *dllNode) {
+	s.policy.remove(node)
+	delete(s.cache, node.key)
+	s.usedBytes -= node.size()
+}
+
+func (s *shard) appendKeys(keys []string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	keys := make([]string, 0, len(c.cache))
-	for k := range c.cache {
+	for k := range s.cache {
 		keys = append(keys, k)
 	}
 	return keys
 }
 
-func (c *LruCache) delete(key string) bool {
-	var removed bool
+// delete removes the entry for key and reports whether there was one. With
+// tomb, it also leaves a tombstone at version, so that replicas which
+// missed the delete are repaired rather than bringing the key back, and an
+// entry written after version is kept instead. Version 0 is chosen above
+// the stored one. A delete with cond only goes ahead if cond holds for the
+// live entry. Like a write, a delete is refused if the append-only log can
+// no longer record it. delete returns the version it was made at, or for
+// one cond refused the version stored, 0 if none.
+func (s *shard) delete(key string, version int64, tomb bool, cond condition) (bool, int64, error) {
+	now := time.Now()
+	s.mu.Lock()
+	if err := s.aof.check(); err != nil {
+		s.mu.Unlock()
+		return false, 0, err
+	}
+	if cond != nil {
+		var cur *dllNode
+		if node, ok := s.cache[key]; ok && !node.expired(now) {
+			cur = node
+		}
+		if !cond(cur) {
+			s.mu.Unlock()
+			version = 0
+			if cur != nil {
+				version = cur.version
+			}
+			s.log.Debug("cache delete", "key", key, "result", actionFailed, "version", version)
+			return false, version, nil
+		}
+	}
+	removed, changed, at := s.deleteLocked(key, version, tomb, now)
+	if changed && s.aof != nil {
+		s.aof.appendDelete(key, at)
+	}
+	s.mu.Unlock()
 
-	c.mu.Lock()
-	node, ok := c.cache[key]
+	if changed && s.aof != nil {
+		if err := s.aof.commit(); err != nil {
+			return removed, 0, err
+		}
+	}
+
+	if removed {
+		s.stats.deletes.Add(1)
+		s.log.Debug("cache delete", "key", key, "result", "deleted")
+	} else {
+		s.log.Debug("cache delete", "key", key, "result", "not_found")
+	}
+	return removed, at, nil
+}
+
+// deleteLocked is delete for a caller that holds s.mu. It reports whether
+// an entry was removed, whether the delete changed anything that needs
+// logging, and the version the delete is at, 0 for one without a tombstone.
+func (s *shard) deleteLocked(key string, version int64, tomb bool, now time.Time) (removed, changed bool, at int64) {
+	node, ok := s.cache[key]
+	if !tomb {
+		if ok {
+			s.removeNode(node)
+		}
+		return ok, ok, 0
+	}
+	var cur *dllNode
+	if ok && !node.expired(now) {
+		cur = node
+	}
+	if version == 0 {
+		version = now.UnixNano()
+		if cur != nil && version <= cur.version {
+			version = cur.version + 1
+		}
+	}
+	if cur != nil && cur.version > version {
+		return false, false, 0 // written after the delete
+	}
 	if ok {
-		if node.prev != nil {
-			node.prev.next = node.next
+		s.removeNode(node)
+	}
+	changed = ok
+	if s.grace > 0 && !s.tombExpired(version, now) && version > s.tombs[key] {
+		s.tombs[key] = version
+		changed = true
+	}
+	return ok, changed, version
+}
+
+// tombstone returns the version key was deleted at if the shard still
+// keeps its tombstone. It must be called with s.mu held.
+func (s *shard) tombstone(key string, now time.Time) (int64, bool) {
+	v, ok := s.tombs[key]
+	if !ok || s.tombExpired(v, now) {
+		return 0, false
+	}
+	return v, true
+}
+
+// tombExpired reports whether a tombstone at version is past its grace
+// period. Versions are write timestamps, so replicas drop the tombstone of
+// a delete at about the same time however late they saw it.
+func (s *shard) tombExpired(version int64, now time.Time) bool {
+	return !now.Before(time.Unix(0, version).Add(s.grace))
+}
+
+// exists reports whether key holds an unexpired entry without counting as an
+// access for the eviction policy.
+func (s *shard) exists(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	node, ok := s.cache[key]
+	return ok && !node.expired(time.Now())
+}
+
+// sweep removes expired entries from a random sample of the shard and
+// returns how many entries it inspected and how many it expired. Tombstones
+// past their grace period are dropped from a sample of the same size.
+func (s *shard) sweep() (checked, expired int) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, node := range s.cache {
+		if checked == sweepSampleSize {
+			break
 		}
-		if node.next != nil {
-			node.next.prev = node.prev
+		checked++
+		if node.expired(now) {
+			s.removeNode(node)
+			expired++
 		}
-		if c.dll.front == node {
-			c.dll.front = node.next
+	}
+	n := 0
+	for key, v := range s.tombs {
+		if n == sweepSampleSize {
+			break
 		}
-		if c.dll.back == node {
-			c.dll.back = node.prev
+		n++
+		if s.tombExpired(v, now) {
+			delete(s.tombs, key)
 		}
-		delete(c.cache, key)
-		removed = true
 	}
-	c.mu.Unlock()
+	return checked, expired
+}
 
-	if removed {
-		log.Printf("CACHE DEL key=%q success", key)
-	} else {
-		log.Printf("CACHE DEL key=%q not_found", key)
+// startSweeper expires entries in the background every interval so that keys
+// disappear on time even if nobody reads them. Like Redis' active expiry, a
+// pass keeps sampling while more than a quarter of the sample was expired.
+func (c *LruCache) startSweeper(interval time.Duration) {
+	if interval <= 0 {
+		return
 	}
-	return removed
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			total := 0
+			for _, s := range c.shards {
+				for {
+					checked, expired := s.sweep()
+					s.stats.expired.Add(uint64(expired))
+					total += expired
+					if checked == 0 || expired*4 <= checked {
+						break
+					}
+				}
+			}
+			if total > 0 {
+				c.log.Debug("cache sweep", "expired", total)
+			}
+		}
+	}()
 }
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"time"
+
+	cachepb "github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+	"github.com/sakshamg567/cachy/util"
+)
+
+// maxTreeDepth bounds the Merkle trees a node builds: 2^16 leaves.
+const maxTreeDepth = 16
+
+// versionEntry is a key and the version of its value, or of the delete
+// that removed it.
+type versionEntry struct {
+	key     string
+	hash    uint32 // ring position of key
+	version int64
+	deleted bool
+}
+
+// versions returns every unexpired key and kept tombstone whose ring
+// position falls in ranges, with its version.
+func (c *LruCache) versions(ranges []*cachepb.KeyRange) []versionEntry {
+	now := time.Now()
+	var out []versionEntry
+	for _, s := range c.shards {
+		s.mu.RLock()
+		for key, n := range s.cache {
+			if n.expired(now) {
+				continue
+			}
+			h := util.Hash(key)
+			if !inRanges(h, ranges) {
+				continue
+			}
+			out = append(out, versionEntry{key: key, hash: h, version: n.version})
+		}
+		for key, v := range s.tombs {
+			if s.tombExpired(v, now) {
+				continue
+			}
+			h := util.Hash(key)
+			if !inRanges(h, ranges) {
+				continue
+			}
+			out = append(out, versionEntry{key: key, hash: h, version: v, deleted: true})
+		}
+		s.mu.RUnlock()
+	}
+	return out
+}
+
+func inRanges(h uint32, ranges []*cachepb.KeyRange) bool {
+	for _, r := range ranges {
+		if util.InRange(h, r.Lo, r.Hi) {
+			return true
+		}
+	}
+	return false
+}
+
+// leafOf returns the leaf a key hash falls in for a tree of the given depth.
+func leafOf(h uint32, depth uint32) uint32 {
+	if depth == 0 {
+		return 0
+	}
+	return h >> (32 - depth)
+}
+
+// merkleTree hashes entries into a tree of the given depth, laid out in
+// heap order. Leaves combine their entries with XOR so that the order keys
+// are visited in does not matter. A tombstone hashes differently from a
+// value at the same version.
+func merkleTree(entries []versionEntry, depth uint32) []uint64 {
+	leaves := 1 << depth
+	tree := make([]uint64, 2*leaves-1)
+	first := leaves - 1
+
+	var buf [8]byte
+	for _, e := range entries {
+		h := fnv.New64a()
+		h.Write([]byte(e.key))
+		binary.LittleEndian.PutUint64(buf[:], uint64(e.version))
+		h.Write(buf[:])
+		if e.deleted {
+			h.Write([]byte{1})
+		}
+		tree[first+int(leafOf(e.hash, depth))] ^= h.Sum64()
+	}
+
+	var pair [16]byte
+	for i := first - 1; i >= 0; i-- {
+		binary.LittleEndian.PutUint64(pair[:8], tree[2*i+1])
+		binary.LittleEndian.PutUint64(pair[8:], tree[2*i+2])
+		h := fnv.New64a()
+		h.Write(pair[:])
+		tree[i] = h.Sum64()
+	}
+	return tree
+}
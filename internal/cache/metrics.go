@@ -0,0 +1,148 @@
+package cache
+
+import (
+	"context"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var rpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "cachy_node_rpc_duration_seconds",
+	Help:    "Time taken to serve gRPC requests, by method and status code.",
+	Buckets: prometheus.ExponentialBuckets(0.00005, 2, 16), // 50µs to ~1.6s
+}, []string{"method", "code"})
+
+// stats are the counters of one shard. Keeping them per shard means readers
+// of different shards never write to the same cache line; they are summed
+// over every shard of every exported cache at scrape time.
+type stats struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	staleHits atomic.Uint64
+	sets      atomic.Uint64
+	deletes   atomic.Uint64
+	evictions atomic.Uint64
+	expired   atomic.Uint64
+}
+
+var (
+	hitsDesc      = prometheus.NewDesc("cachy_cache_hits_total", "Reads that found an unexpired entry.", nil, nil)
+	missesDesc    = prometheus.NewDesc("cachy_cache_misses_total", "Reads that found no entry or an expired one.", nil, nil)
+	staleHitsDesc = prometheus.NewDesc("cachy_cache_stale_hits_total", "Reads served a value past its soft ttl, also counted as hits.", nil, nil)
+	setsDesc      = prometheus.NewDesc("cachy_cache_sets_total", "Entries written, whether inserted or updated.", nil, nil)
+	deletesDesc   = prometheus.NewDesc("cachy_cache_deletes_total", "Entries removed by a delete.", nil, nil)
+	evictionsDesc = prometheus.NewDesc("cachy_cache_evictions_total", "Entries dropped by the eviction policy to make room.", nil, nil)
+	expiredDesc   = prometheus.NewDesc("cachy_cache_expired_total", "Entries removed because their ttl ran out.", nil, nil)
+	entriesDesc   = prometheus.NewDesc("cachy_cache_entries", "Entries currently held, including expired ones not yet removed.", nil, nil)
+	bytesDesc     = prometheus.NewDesc("cachy_cache_bytes", "Memory accounted to keys, values and per-entry overhead.", nil, nil)
+)
+
+// counts are the counters of stats added up over shards and caches.
+type counts struct {
+	hits, misses, staleHits, sets, deletes, evictions, expired uint64
+}
+
+// addCache adds the counters of every shard of c.
+func (t *counts) addCache(c *LruCache) {
+	for _, s := range c.shards {
+		t.hits += s.stats.hits.Load()
+		t.misses += s.stats.misses.Load()
+		t.staleHits += s.stats.staleHits.Load()
+		t.sets += s.stats.sets.Load()
+		t.deletes += s.stats.deletes.Load()
+		t.evictions += s.stats.evictions.Load()
+		t.expired += s.stats.expired.Load()
+	}
+}
+
+// exported holds the caches the metrics are reported for: the node's own,
+// normally, but every cache registered in the process is added up. retired
+// keeps the counts of caches since unregistered, so that the counters
+// never go backwards.
+var exported = struct {
+	sync.Mutex
+	once    sync.Once
+	caches  map[*LruCache]struct{}
+	retired counts
+}{caches: map[*LruCache]struct{}{}}
+
+// registerMetrics adds c to the exported metrics until unregisterMetrics.
+func (c *LruCache) registerMetrics() {
+	exported.once.Do(func() { prometheus.MustRegister(collector{}) })
+	exported.Lock()
+	exported.caches[c] = struct{}{}
+	exported.Unlock()
+}
+
+func (c *LruCache) unregisterMetrics() {
+	exported.Lock()
+	defer exported.Unlock()
+	if _, ok := exported.caches[c]; ok {
+		exported.retired.addCache(c)
+		delete(exported.caches, c)
+	}
+}
+
+// exportedTotals returns the counters of every cache ever exported, and the
+// entries and bytes of those still exported.
+func exportedTotals() (t counts, entries, bytes int64) {
+	exported.Lock()
+	defer exported.Unlock()
+	t = exported.retired
+	for c := range exported.caches {
+		t.addCache(c)
+		entries += int64(c.Len())
+		bytes += c.UsedBytes()
+	}
+	return t, entries, bytes
+}
+
+// collector reports the cache metrics, summed over the exported caches.
+type collector struct{}
+
+func (collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{hitsDesc, missesDesc, staleHitsDesc, setsDesc, deletesDesc, evictionsDesc, expiredDesc, entriesDesc, bytesDesc} {
+		ch <- d
+	}
+}
+
+func (collector) Collect(ch chan<- prometheus.Metric) {
+	t, entries, bytes := exportedTotals()
+	counter := func(d *prometheus.Desc, v uint64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, float64(v))
+	}
+	counter(hitsDesc, t.hits)
+	counter(missesDesc, t.misses)
+	counter(staleHitsDesc, t.staleHits)
+	counter(setsDesc, t.sets)
+	counter(deletesDesc, t.deletes)
+	counter(evictionsDesc, t.evictions)
+	counter(expiredDesc, t.expired)
+	ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.GaugeValue, float64(entries))
+	ch <- prometheus.MustNewConstMetric(bytesDesc, prometheus.GaugeValue, float64(bytes))
+}
+
+// UnaryServerInterceptor records the latency of every unary RPC the node
+// serves in cachy_node_rpc_duration_seconds.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	rpcDuration.WithLabelValues(path.Base(info.FullMethod), status.Code(err).String()).Observe(time.Since(start).Seconds())
+	return res, err
+}
+
+// StreamServerInterceptor is UnaryServerInterceptor for streaming RPCs,
+// timed from the start of the stream until the handler returns.
+func StreamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	rpcDuration.WithLabelValues(path.Base(info.FullMethod), status.Code(err).String()).Observe(time.Since(start).Seconds())
+	return err
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	cachepb "github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+)
+
+func TestMetricsSurviveClose(t *testing.T) {
+	ctx := context.Background()
+	before, _, _ := exportedTotals()
+
+	cn := NewCacheNode(Options{Capacity: 100})
+	for _, k := range []string{"a", "b", "c"} {
+		if _, err := cn.Set(ctx, &cachepb.SetRequest{Key: k, Value: []byte(k)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cn.Get(ctx, &cachepb.GetRequest{Key: "a"})
+	cn.Get(ctx, &cachepb.GetRequest{Key: "missing"})
+
+	open, entries, _ := exportedTotals()
+	if open.sets-before.sets != 3 || open.hits-before.hits != 1 || open.misses-before.misses != 1 {
+		t.Errorf("while open: %d sets, %d hits, %d misses, want 3, 1, 1", open.sets-before.sets, open.hits-before.hits, open.misses-before.misses)
+	}
+	if entries < 3 {
+		t.Errorf("%d entries exported, want at least 3", entries)
+	}
+
+	// Closing the node stops reporting its entries, but its counts are
+	// kept, and closing it twice does not count them again.
+	cn.Close()
+	cn.Close()
+	closed, _, _ := exportedTotals()
+	if closed != open {
+		t.Errorf("after Close counts = %+v, want %+v", closed, open)
+	}
+}
@@ -2,47 +2,335 @@ package cache
 
 import (
 	"context"
-	"log"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"time"
 
+	"github.com/sakshamg567/cachy/internal/logging"
 	cachepb "github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type CacheNode struct {
 	cachepb.UnimplementedCacheServer
-	lru *LruCache
+	lru          *LruCache
+	snapshotPath string
+	log          *slog.Logger
 }
 
-func NewCacheNode(cap int) cachepb.CacheServer {
-	lru := NewLruCache(cap)
+// NewCacheNode builds a node's cache, restoring it from the append-only log
+// at opts.AOFPath or, without one, from the snapshot at opts.SnapshotPath.
+// A missing snapshot is not an error; an unreadable or corrupt one is
+// logged and the node starts empty.
+func NewCacheNode(opts Options) *CacheNode {
+	lru := NewLruCache(opts)
+	logger := lru.log
+	switch {
+	case opts.AOFPath != "":
+		start := time.Now()
+		n, err := lru.OpenAppendLog(opts.AOFPath, opts.AOFFsync, opts.AOFRewriteSize)
+		if err != nil {
+			// Running on without the log would silently drop durability.
+			logging.Fatal(logger, "aof open failed", "path", opts.AOFPath, "err", err)
+		}
+		logger.Info("aof replayed", "path", opts.AOFPath, "records", n, "entries", lru.Len(), "took", time.Since(start).Round(time.Millisecond))
+	case opts.SnapshotPath != "":
+		start := time.Now()
+		n, err := lru.LoadSnapshot(opts.SnapshotPath)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			logger.Info("no snapshot, starting empty", "path", opts.SnapshotPath)
+		case err != nil:
+			logger.Error("snapshot load failed, starting empty", "path", opts.SnapshotPath, "err", err)
+		default:
+			logger.Info("snapshot loaded", "path", opts.SnapshotPath, "entries", n, "took", time.Since(start).Round(time.Millisecond))
+		}
+	}
+	lru.registerMetrics()
+	lru.startSweeper(opts.SweepInterval)
+	lru.startSnapshots(opts.SnapshotPath, opts.SnapshotInterval)
 
 	return &CacheNode{
-		lru: lru,
+		lru:          lru,
+		snapshotPath: opts.SnapshotPath,
+		log:          logger,
 	}
 }
 
+// Close flushes and syncs the append-only log, if any, and stops reporting
+// the cache's metrics.
+func (cn *CacheNode) Close() error {
+	cn.lru.unregisterMetrics()
+	return cn.lru.CloseAppendLog()
+}
+
+// Snapshot saves the cache to the configured snapshot path, if any. It is
+// meant to be called on shutdown once the node has stopped taking writes.
+func (cn *CacheNode) Snapshot() error {
+	if cn.snapshotPath == "" {
+		return nil
+	}
+	start := time.Now()
+	n, err := cn.lru.WriteSnapshot(cn.snapshotPath)
+	if err != nil {
+		return err
+	}
+	cn.log.Info("snapshot written", "path", cn.snapshotPath, "entries", n, "took", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
 func (cn *CacheNode) Get(ctx context.Context, req *cachepb.GetRequest) (*cachepb.GetResponse, error) {
-	log.Printf("RPC Get key=%q", req.Key)
-	val, err := cn.lru.get(req.Key)
+	cn.log.Debug("rpc", "method", "Get", "key", req.Key)
+	it, err := cn.lru.get(req.Key)
 	if err != nil {
-		return &cachepb.GetResponse{Found: false}, nil
+		return cn.missResponse(req.Key), nil
 	}
-	return &cachepb.GetResponse{Value: val, Found: true}, nil
+	return getResponse(it), nil
+}
+
+// missResponse answers a read of a key that is not stored, saying so if it
+// was deleted so that the coordinator can repair replicas that still hold
+// it.
+func (cn *CacheNode) missResponse(key string) *cachepb.GetResponse {
+	version, deleted := cn.lru.tombstone(key)
+	return &cachepb.GetResponse{Found: false, Deleted: deleted, Version: version}
 }
 
 func (cn *CacheNode) Set(ctx context.Context, req *cachepb.SetRequest) (*cachepb.SetResponse, error) {
-	log.Printf("RPC Set key=%q value=%q", req.Key, req.Value)
-	_ = cn.lru.set(req.Key, req.Value)
+	cn.log.Debug("rpc", "method", "Set", "key", req.Key, logging.Value(req.Value), "ttl_ms", req.TtlMs, "version", req.Version)
+	if err := cn.lru.set(req.Key, req.Value, req.Flags, msToDuration(req.TtlMs), msToDuration(req.SoftTtlMs), req.Version); err != nil {
+		return nil, writeStatus(err)
+	}
 	return &cachepb.SetResponse{Success: true}, nil
 }
 
 func (cn *CacheNode) GetAllKeys(ctx context.Context, req *cachepb.GetAllKeysRequest) (*cachepb.GetAllKeysResponse, error) {
 	keys := cn.lru.GetAllKeys()
-	log.Printf("RPC GetAllKeys count=%d", len(keys))
+	cn.log.Debug("rpc", "method", "GetAllKeys", "count", len(keys))
 	return &cachepb.GetAllKeysResponse{Keys: keys}, nil
 }
 
 func (cn *CacheNode) Delete(ctx context.Context, req *cachepb.DeleteRequest) (*cachepb.DeleteResponse, error) {
-	log.Printf("RPC Delete key=%q", req.Key)
-	success := cn.lru.delete(req.Key)
+	cn.log.Debug("rpc", "method", "Delete", "key", req.Key)
+	success, err := cn.lru.delete(req.Key, req.Version, req.Drop)
+	if err != nil {
+		return nil, writeStatus(err)
+	}
 	return &cachepb.DeleteResponse{Success: success}, nil
 }
+
+func (cn *CacheNode) Exists(ctx context.Context, req *cachepb.ExistsRequest) (*cachepb.ExistsResponse, error) {
+	cn.log.Debug("rpc", "method", "Exists", "key", req.Key)
+	return &cachepb.ExistsResponse{Found: cn.lru.exists(req.Key)}, nil
+}
+
+func (cn *CacheNode) MGet(ctx context.Context, req *cachepb.MGetRequest) (*cachepb.MGetResponse, error) {
+	cn.log.Debug("rpc", "method", "MGet", "keys", len(req.Keys))
+	items := make([]*cachepb.GetResponse, len(req.Keys))
+	for i, key := range req.Keys {
+		it, err := cn.lru.get(key)
+		if err != nil {
+			items[i] = cn.missResponse(key)
+			continue
+		}
+		items[i] = getResponse(it)
+	}
+	return &cachepb.MGetResponse{Items: items}, nil
+}
+
+// MSet applies each write independently; one rejected item does not fail the
+// others.
+func (cn *CacheNode) MSet(ctx context.Context, req *cachepb.MSetRequest) (*cachepb.MSetResponse, error) {
+	cn.log.Debug("rpc", "method", "MSet", "items", len(req.Items))
+	items := make([]*cachepb.SetResponse, len(req.Items))
+	for i, item := range req.Items {
+		if err := cn.lru.set(item.Key, item.Value, item.Flags, msToDuration(item.TtlMs), msToDuration(item.SoftTtlMs), item.Version); err != nil {
+			items[i] = &cachepb.SetResponse{Success: false, Error: err.Error(), Code: int32(status.Code(writeStatus(err)))}
+			continue
+		}
+		items[i] = &cachepb.SetResponse{Success: true}
+	}
+	return &cachepb.MSetResponse{Items: items}, nil
+}
+
+func (cn *CacheNode) MDelete(ctx context.Context, req *cachepb.MDeleteRequest) (*cachepb.MDeleteResponse, error) {
+	cn.log.Debug("rpc", "method", "MDelete", "keys", len(req.Keys))
+	deleted := make([]bool, len(req.Keys))
+	for i, key := range req.Keys {
+		ok, err := cn.lru.delete(key, req.Version, false)
+		if err != nil {
+			return nil, writeStatus(err)
+		}
+		deleted[i] = ok
+	}
+	return &cachepb.MDeleteResponse{Deleted: deleted}, nil
+}
+
+// MerkleTree hashes the keys in the requested ranges so that replicas can
+// find the keys they disagree on without exchanging them all.
+func (cn *CacheNode) MerkleTree(ctx context.Context, req *cachepb.MerkleTreeRequest) (*cachepb.MerkleTreeResponse, error) {
+	cn.log.Debug("rpc", "method", "MerkleTree", "ranges", len(req.Ranges), "depth", req.Depth)
+	if req.Depth > maxTreeDepth {
+		return nil, status.Errorf(codes.InvalidArgument, "depth %d exceeds %d", req.Depth, maxTreeDepth)
+	}
+	return &cachepb.MerkleTreeResponse{Nodes: merkleTree(cn.lru.versions(req.Ranges), req.Depth)}, nil
+}
+
+// Scan streams the key and version of every entry and tombstone in the
+// requested ranges and leaves.
+func (cn *CacheNode) Scan(req *cachepb.ScanRequest, stream cachepb.Cache_ScanServer) error {
+	cn.log.Debug("rpc", "method", "Scan", "ranges", len(req.Ranges), "leaves", len(req.Leaves))
+	if req.Depth > maxTreeDepth {
+		return status.Errorf(codes.InvalidArgument, "depth %d exceeds %d", req.Depth, maxTreeDepth)
+	}
+	leaves := make(map[uint32]bool, len(req.Leaves))
+	for _, l := range req.Leaves {
+		leaves[l] = true
+	}
+	for _, e := range cn.lru.versions(req.Ranges) {
+		if len(leaves) > 0 && !leaves[leafOf(e.hash, req.Depth)] {
+			continue
+		}
+		if err := stream.Send(&cachepb.ScanEntry{Key: e.key, Version: e.version, Deleted: e.deleted}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompareAndSet writes the item only if the key is stored at the expected
+// version, checked and written under one lock.
+func (cn *CacheNode) CompareAndSet(ctx context.Context, req *cachepb.CompareAndSetRequest) (*cachepb.ConditionalSetResponse, error) {
+	cn.log.Debug("rpc", "method", "CompareAndSet", "key", req.Item.GetKey(), "expected_version", req.ExpectedVersion)
+	return cn.setIf(req.Item, func(cur *dllNode) bool {
+		return cur != nil && cur.version == req.ExpectedVersion
+	})
+}
+
+// SetIfAbsent writes the item only if the key is not stored.
+func (cn *CacheNode) SetIfAbsent(ctx context.Context, req *cachepb.SetRequest) (*cachepb.ConditionalSetResponse, error) {
+	cn.log.Debug("rpc", "method", "SetIfAbsent", "key", req.Key)
+	return cn.setIf(req, func(cur *dllNode) bool { return cur == nil })
+}
+
+// SetIfPresent writes the item only if the key is stored.
+func (cn *CacheNode) SetIfPresent(ctx context.Context, req *cachepb.SetRequest) (*cachepb.ConditionalSetResponse, error) {
+	cn.log.Debug("rpc", "method", "SetIfPresent", "key", req.Key)
+	return cn.setIf(req, func(cur *dllNode) bool { return cur != nil })
+}
+
+// setIf runs a conditional write and answers with the entry it replaced,
+// read under the same lock as the condition.
+func (cn *CacheNode) setIf(req *cachepb.SetRequest, cond condition) (*cachepb.ConditionalSetResponse, error) {
+	if req == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing item")
+	}
+	var prev *record
+	ok, version, err := cn.lru.setIf(req.Key, req.Value, req.Flags, msToDuration(req.TtlMs), msToDuration(req.SoftTtlMs), req.Version, func(cur *dllNode) bool {
+		if !cond(cur) {
+			return false
+		}
+		if cur != nil {
+			r := cur.record()
+			prev = &r
+		}
+		return true
+	})
+	if err != nil {
+		return nil, writeStatus(err)
+	}
+	return &cachepb.ConditionalSetResponse{Success: ok, Version: version, Previous: previousResponse(prev)}, nil
+}
+
+// CompareAndDelete deletes the key only if it is stored at the expected
+// version, checked and deleted under one lock.
+func (cn *CacheNode) CompareAndDelete(ctx context.Context, req *cachepb.CompareAndDeleteRequest) (*cachepb.CompareAndDeleteResponse, error) {
+	cn.log.Debug("rpc", "method", "CompareAndDelete", "key", req.Key, "expected_version", req.ExpectedVersion)
+	ok, version, err := cn.lru.deleteIf(req.Key, req.ExpectedVersion, req.Drop)
+	if err != nil {
+		return nil, writeStatus(err)
+	}
+	return &cachepb.CompareAndDeleteResponse{Success: ok, Version: version}, nil
+}
+
+func (cn *CacheNode) Incr(ctx context.Context, req *cachepb.IncrRequest) (*cachepb.CounterResponse, error) {
+	cn.log.Debug("rpc", "method", "Incr", "key", req.Key, "delta", req.Delta)
+	return counterResponse(cn.lru.incr(req.Key, req.Delta, false, req.Overflow, req.Create, req.Initial, msToDuration(req.TtlMs)))
+}
+
+func (cn *CacheNode) Decr(ctx context.Context, req *cachepb.IncrRequest) (*cachepb.CounterResponse, error) {
+	cn.log.Debug("rpc", "method", "Decr", "key", req.Key, "delta", req.Delta)
+	return counterResponse(cn.lru.incr(req.Key, req.Delta, true, req.Overflow, req.Create, req.Initial, msToDuration(req.TtlMs)))
+}
+
+func (cn *CacheNode) IncrByFloat(ctx context.Context, req *cachepb.IncrByFloatRequest) (*cachepb.CounterResponse, error) {
+	cn.log.Debug("rpc", "method", "IncrByFloat", "key", req.Key, "delta", req.Delta)
+	return counterResponse(cn.lru.incrFloat(req.Key, req.Delta, req.Create, req.Initial, msToDuration(req.TtlMs)))
+}
+
+// counterResponse returns the entry a counter operation left and the one it
+// replaced, or reports the key as not found if it was missing and not
+// created.
+func counterResponse(rec record, prev *record, err error) (*cachepb.CounterResponse, error) {
+	switch {
+	case errors.Is(err, errNoCounter):
+		return &cachepb.CounterResponse{Item: &cachepb.GetResponse{Found: false}}, nil
+	case errors.Is(err, ErrNotNumeric):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, ErrOverflow):
+		return nil, status.Error(codes.OutOfRange, err.Error())
+	case err != nil:
+		return nil, writeStatus(err)
+	}
+	return &cachepb.CounterResponse{Item: getResponse(rec.item(time.Now())), Previous: previousResponse(prev)}, nil
+}
+
+// previousResponse describes the entry a write replaced, nil if there was
+// none.
+func previousResponse(prev *record) *cachepb.GetResponse {
+	if prev == nil {
+		return nil
+	}
+	return getResponse(prev.item(time.Now()))
+}
+
+// writeStatus maps a refused write onto a gRPC status: an entry there is no
+// room for exhausts a resource, a node that cannot log writes is
+// unavailable, and anything else is a bad request.
+func writeStatus(err error) error {
+	switch {
+	case errors.Is(err, ErrEntryTooLarge):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ErrLogFailed):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+func getResponse(it item) *cachepb.GetResponse {
+	return &cachepb.GetResponse{
+		Value:      it.value,
+		Found:      true,
+		Flags:      it.flags,
+		TtlMs:      durationToMs(it.ttl),
+		Stale:      it.stale,
+		Revalidate: it.revalidate,
+		SoftTtlMs:  durationToMs(it.softTTL),
+		Version:    it.version,
+	}
+}
+
+func msToDuration(ms int64) time.Duration {
+	return time.Duration(ms) * time.Millisecond
+}
+
+// durationToMs rounds d up to whole milliseconds so that an entry with less
+// than a millisecond left is not reported as having no expiry.
+func durationToMs(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
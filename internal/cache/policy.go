@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EvictionPolicy names the strategy a cache uses to pick which entry to drop
+// when it is full.
+type EvictionPolicy string
+
+const (
+	// PolicyLRU evicts the least recently used entry.
+	PolicyLRU EvictionPolicy = "lru"
+	// PolicyLFU evicts the least frequently used entry, breaking ties by
+	// recency.
+	PolicyLFU EvictionPolicy = "lfu"
+	// PolicyARC balances recency and frequency adaptively, keeping one-off
+	// scans from flushing frequently used entries.
+	PolicyARC EvictionPolicy = "arc"
+	// PolicyTinyLFU is W-TinyLFU: a small LRU window in front of a segmented
+	// LRU whose admissions are filtered by an approximate frequency sketch.
+	PolicyTinyLFU EvictionPolicy = "tinylfu"
+)
+
+// EvictionPolicies lists every supported policy.
+var EvictionPolicies = []EvictionPolicy{PolicyLRU, PolicyLFU, PolicyARC, PolicyTinyLFU}
+
+// ParseEvictionPolicy maps a case-insensitive name onto a policy.
+func ParseEvictionPolicy(name string) (EvictionPolicy, error) {
+	for _, p := range EvictionPolicies {
+		if strings.EqualFold(name, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown eviction policy %q", name)
+}
+
+// segment records which of a policy's lists an entry is on.
+type segment uint8
+
+const (
+	segNone      segment = iota
+	segT1                // ARC: seen once recently
+	segT2                // ARC: seen at least twice recently
+	segB1                // ARC: ghost of an entry evicted from T1
+	segB2                // ARC: ghost of an entry evicted from T2
+	segWindow            // W-TinyLFU admission window
+	segProbation         // W-TinyLFU main cache, not yet proven
+	segProtected         // W-TinyLFU main cache, hit while on probation
+)
+
+// policy tracks the entries of a cache and picks eviction victims. Every
+// method is called with the cache lock held.
+type policy interface {
+	// add records an entry that was just inserted.
+	add(n *dllNode)
+	// access records a hit on, or an overwrite of, an existing entry.
+	access(n *dllNode)
+	// remove forgets an entry that was deleted or expired.
+	remove(n *dllNode)
+	// evict picks a victim, forgets it and returns it. It returns nil when
+	// the policy holds no entries.
+	evict() *dllNode
+	// walk calls fn for every entry, starting with those closest to
+	// eviction, so that re-adding them in walk order rebuilds roughly the
+	// same state.
+	walk(fn func(n *dllNode))
+}
+
+func newPolicy(p EvictionPolicy, sizeHint int) policy {
+	switch p {
+	case PolicyLFU:
+		return newLFUPolicy()
+	case PolicyARC:
+		return newARCPolicy()
+	case PolicyTinyLFU:
+		return newTinyLFUPolicy(sizeHint)
+	default:
+		return &lruPolicy{}
+	}
+}
+
+// sizeHint estimates how many entries a cache with opts will hold, for
+// policies that size internal structures up front.
+func sizeHint(opts Options) int {
+	const assumedEntrySize = 1 << 10
+	if opts.Capacity > 0 {
+		return opts.Capacity
+	}
+	if opts.MaxMemory > 0 {
+		return int(opts.MaxMemory / assumedEntrySize)
+	}
+	return 1 << 16
+}
+
+type lruPolicy struct {
+	dll DLL
+}
+
+func (p *lruPolicy) add(n *dllNode)    { p.dll.pushFront(n) }
+func (p *lruPolicy) access(n *dllNode) { p.dll.moveToFront(n) }
+func (p *lruPolicy) remove(n *dllNode) { p.dll.remove(n) }
+func (p *lruPolicy) evict() *dllNode   { return p.dll.evictLRU() }
+
+func (p *lruPolicy) walk(fn func(n *dllNode)) { p.dll.walk(fn) }
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func newTestCache(policy EvictionPolicy, capacity int) *LruCache {
+	return NewLruCache(Options{Capacity: capacity, Shards: 1, Eviction: policy})
+}
+
+// resident returns which of keys c still holds.
+func resident(c *LruCache, keys []string) []string {
+	var out []string
+	for _, k := range keys {
+		if c.exists(k) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func TestEvictionOrder(t *testing.T) {
+	// Every case fills a cache of three with a, b and c, reads the keys in
+	// reads, then writes d and expects evicted to be the one dropped.
+	tests := []struct {
+		policy  EvictionPolicy
+		reads   []string
+		evicted string
+	}{
+		{PolicyLRU, []string{"a"}, "b"},
+		{PolicyLRU, []string{"a", "b"}, "c"},
+		{PolicyLFU, []string{"a", "a", "c"}, "b"},
+		{PolicyLFU, []string{"b", "c"}, "a"},
+		{PolicyARC, []string{"a"}, "b"},
+		{PolicyARC, []string{"a", "b"}, "c"},
+		// The window holds one entry, so d pushes c onto probation, where
+		// it competes with the oldest entry there and loses a tie.
+		{PolicyTinyLFU, []string{"a"}, "c"},
+		{PolicyTinyLFU, []string{"c", "c"}, "a"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%v", tt.policy, tt.reads), func(t *testing.T) {
+			c := newTestCache(tt.policy, 3)
+			for _, k := range []string{"a", "b", "c"} {
+				if err := c.set(k, []byte(k), 0, 0, 0, 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, k := range tt.reads {
+				if _, err := c.get(k); err != nil {
+					t.Fatalf("get %s: %v", k, err)
+				}
+			}
+			if err := c.set("d", []byte("d"), 0, 0, 0, 0); err != nil {
+				t.Fatal(err)
+			}
+
+			all := []string{"a", "b", "c", "d"}
+			want := slices.DeleteFunc(slices.Clone(all), func(k string) bool { return k == tt.evicted })
+			if got := resident(c, all); !slices.Equal(got, want) {
+				t.Errorf("resident = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestEvictionScanResistance(t *testing.T) {
+	// Four keys read often, then a scan of thirty keys read once through a
+	// cache of ten. Only LRU lets the scan flush the hot keys.
+	hot := []string{"h0", "h1", "h2", "h3"}
+	tests := []struct {
+		policy   EvictionPolicy
+		survived int
+	}{
+		{PolicyLRU, 0},
+		{PolicyLFU, 4},
+		{PolicyARC, 4},
+		{PolicyTinyLFU, 4},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			c := newTestCache(tt.policy, 10)
+			for _, k := range hot {
+				c.set(k, []byte(k), 0, 0, 0, 0)
+			}
+			for range 3 {
+				for _, k := range hot {
+					c.get(k)
+				}
+			}
+			for i := range 30 {
+				k := fmt.Sprintf("s%d", i)
+				c.set(k, []byte(k), 0, 0, 0, 0)
+			}
+			if got := resident(c, hot); len(got) != tt.survived {
+				t.Errorf("hot keys left = %v, want %d of them", got, tt.survived)
+			}
+			if n := len(c.GetAllKeys()); n != 10 {
+				t.Errorf("cache holds %d keys, want 10", n)
+			}
+		})
+	}
+}
+
+func TestTinyLFUWindowSizedFromCapacity(t *testing.T) {
+	tests := []struct {
+		capacity, added         int
+		window, probation, prot int
+	}{
+		{capacity: 200, added: 3, window: 2, probation: 1},
+		{capacity: 1000, added: 10, window: 10},
+		{capacity: 1000, added: 25, window: 10, probation: 15},
+		{capacity: 10, added: 5, window: 1, probation: 4},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tt.capacity, tt.added), func(t *testing.T) {
+			p := newTinyLFUPolicy(tt.capacity)
+			for i := range tt.added {
+				p.add(&dllNode{key: fmt.Sprint(i)})
+			}
+			if p.window.len != tt.window || p.probation.len != tt.probation || p.protected.len != tt.prot {
+				t.Errorf("window, probation, protected = %d, %d, %d, want %d, %d, %d",
+					p.window.len, p.probation.len, p.protected.len, tt.window, tt.probation, tt.prot)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// A snapshot file is
+//
+//	magic "CACHYSNP" | version uint16
+//	record*          | 0x00 | count uvarint | crc32 uint32
+//
+// where each record is
+//
+//	0x01 | key length uvarint | key | value length uvarint | value |
+//	flags uvarint | expiry varint | stale time varint | version varint
+//
+// Times are unix nanoseconds, 0 for none. The checksum is CRC-32C over
+// every byte before it. Integers in the header and trailer are little
+// endian.
+const (
+	snapshotMagic   = "CACHYSNP"
+	snapshotVersion = 1
+
+	recordEntry = 0x01
+	recordEnd   = 0x00
+)
+
+var ErrBadSnapshot = errors.New("invalid snapshot")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// WriteSnapshot saves every unexpired entry to path, coldest first, and
+// returns how many were written. The file is written under a temporary name
+// and renamed into place, so a crash never leaves a partial snapshot behind.
+func (c *LruCache) WriteSnapshot(path string) (int, error) {
+	entries := c.snapshotEntries()
+
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name()) // no-op once renamed
+
+	if err := writeSnapshot(f, entries); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return 0, err
+	}
+	// Persist the rename itself.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return len(entries), nil
+}
+
+// snapshotEntries collects the unexpired entries of every shard in eviction
+// order. Shards are interleaved by relative position, so that entries from
+// the cold end of each shard come before the hot end of any other.
+func (c *LruCache) snapshotEntries() []record {
+	type ranked struct {
+		record
+		rank float64
+	}
+	var all []ranked
+	now := time.Now()
+	for _, s := range c.shards {
+		s.mu.RLock()
+		var shardEntries []record
+		s.policy.walk(func(n *dllNode) {
+			if n.expired(now) {
+				return
+			}
+			// Values are replaced, never modified in place, so they can be
+			// shared with the snapshot without copying.
+			shardEntries = append(shardEntries, n.record())
+		})
+		s.mu.RUnlock()
+
+		for i, e := range shardEntries {
+			all = append(all, ranked{e, float64(i) / float64(len(shardEntries))})
+		}
+	}
+	slices.SortStableFunc(all, func(a, b ranked) int {
+		switch {
+		case a.rank < b.rank:
+			return -1
+		case a.rank > b.rank:
+			return 1
+		}
+		return 0
+	})
+
+	out := make([]record, len(all))
+	for i, r := range all {
+		out[i] = r.record
+	}
+	return out
+}
+
+func writeSnapshot(w io.Writer, entries []record) error {
+	crc := crc32.New(crcTable)
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+
+	bw.WriteString(snapshotMagic)
+	binary.Write(bw, binary.LittleEndian, uint16(snapshotVersion))
+	for _, e := range entries {
+		bw.WriteByte(recordEntry)
+		bw.Write(binary.AppendUvarint(nil, uint64(len(e.key))))
+		bw.WriteString(e.key)
+		bw.Write(binary.AppendUvarint(nil, uint64(len(e.value))))
+		bw.Write(e.value)
+		bw.Write(binary.AppendUvarint(nil, uint64(e.flags)))
+		bw.Write(binary.AppendVarint(nil, unixNano(e.expiresAt)))
+		bw.Write(binary.AppendVarint(nil, unixNano(e.staleAt)))
+		bw.Write(binary.AppendVarint(nil, e.version))
+	}
+	bw.WriteByte(recordEnd)
+	bw.Write(binary.AppendUvarint(nil, uint64(len(entries))))
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, crc.Sum32())
+}
+
+// LoadSnapshot fills the cache from a file written by WriteSnapshot and
+// returns how many entries were loaded. Entries that expired in the
+// meantime are skipped. The whole file is verified before anything is
+// loaded, so a damaged snapshot is rejected with ErrBadSnapshot and leaves
+// the cache untouched.
+func (c *LruCache) LoadSnapshot(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := readSnapshot(bufio.NewReader(f), info.Size())
+	if err != nil {
+		return 0, err
+	}
+
+	loaded := 0
+	now := time.Now()
+	for _, e := range entries {
+		if !e.expiresAt.IsZero() && !now.Before(e.expiresAt) {
+			continue
+		}
+		if _, _, err := c.shardFor(e.key).store(&e, nil); err != nil {
+			continue
+		}
+		loaded++
+	}
+	return loaded, nil
+}
+
+// snapshotReader reads through a running checksum, keeping count of the
+// bytes left in the file.
+type snapshotReader struct {
+	r    *bufio.Reader
+	crc  hash.Hash32
+	left int64
+}
+
+func (r *snapshotReader) ReadByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err == nil {
+		r.crc.Write([]byte{b})
+		r.left--
+	}
+	return b, err
+}
+
+func (r *snapshotReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.crc.Write(p[:n])
+	r.left -= int64(n)
+	return n, err
+}
+
+// bytes reads a length-prefixed field. A corrupt length must not make the
+// node allocate more than the rest of the file could hold, since the
+// checksum is only verified at the end.
+func (r *snapshotReader) bytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(max(r.left, 0)) {
+		return nil, fmt.Errorf("%w: field of %d bytes", ErrBadSnapshot, n)
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	return buf, err
+}
+
+func readSnapshot(br *bufio.Reader, size int64) ([]record, error) {
+	r := &snapshotReader{r: br, crc: crc32.New(crcTable), left: size}
+	corrupt := func(err error) error {
+		if errors.Is(err, ErrBadSnapshot) {
+			return err
+		}
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("%w: %v", ErrBadSnapshot, err)
+	}
+
+	header := make([]byte, len(snapshotMagic)+2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, corrupt(err)
+	}
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, fmt.Errorf("%w: not a snapshot file", ErrBadSnapshot)
+	}
+	version := binary.LittleEndian.Uint16(header[len(snapshotMagic):])
+	if version != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrBadSnapshot, version)
+	}
+
+	var entries []record
+	for {
+		tag, err := r.ReadByte()
+		if err != nil {
+			return nil, corrupt(err)
+		}
+		if tag == recordEnd {
+			break
+		}
+		if tag != recordEntry {
+			return nil, fmt.Errorf("%w: unknown record type %#x", ErrBadSnapshot, tag)
+		}
+
+		key, err := r.bytes()
+		if err != nil {
+			return nil, corrupt(err)
+		}
+		value, err := r.bytes()
+		if err != nil {
+			return nil, corrupt(err)
+		}
+		flags, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, corrupt(err)
+		}
+		exp, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, corrupt(err)
+		}
+		stale, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, corrupt(err)
+		}
+		ver, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, corrupt(err)
+		}
+		entries = append(entries, record{
+			key:       string(key),
+			value:     value,
+			flags:     uint32(flags),
+			expiresAt: fromUnixNano(exp),
+			staleAt:   fromUnixNano(stale),
+			version:   ver,
+		})
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, corrupt(err)
+	}
+	want := r.crc.Sum32()
+	var got uint32
+	if err := binary.Read(br, binary.LittleEndian, &got); err != nil {
+		return nil, corrupt(err)
+	}
+	if got != want {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrBadSnapshot)
+	}
+	if count != uint64(len(entries)) {
+		return nil, fmt.Errorf("%w: expected %d entries, found %d", ErrBadSnapshot, count, len(entries))
+	}
+	if _, err := br.ReadByte(); err != io.EOF {
+		return nil, fmt.Errorf("%w: trailing data", ErrBadSnapshot)
+	}
+	return entries, nil
+}
+
+// unixNano encodes t for a snapshot or log record, the zero time as 0.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// startSnapshots writes a snapshot to path every interval until the
+// process exits.
+func (c *LruCache) startSnapshots(path string, interval time.Duration) {
+	if path == "" || interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			start := time.Now()
+			n, err := c.WriteSnapshot(path)
+			if err != nil {
+				c.log.Error("snapshot write failed", "path", path, "err", err)
+				continue
+			}
+			c.log.Info("snapshot written", "path", path, "entries", n, "took", time.Since(start).Round(time.Millisecond))
+		}
+	}()
+}
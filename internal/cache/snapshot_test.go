@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+// sameRecord reports whether two records agree on everything a snapshot
+// or log keeps, times compared at nanosecond precision.
+func sameRecord(a, b record) bool {
+	return a.key == b.key && bytes.Equal(a.value, b.value) && a.flags == b.flags &&
+		unixNano(a.expiresAt) == unixNano(b.expiresAt) &&
+		unixNano(a.staleAt) == unixNano(b.staleAt) && a.version == b.version
+}
+
+// stored returns the record held for key, if any.
+func stored(c *LruCache, key string) (record, bool) {
+	s := c.shardFor(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n, ok := s.cache[key]
+	if !ok {
+		return record{}, false
+	}
+	return n.record(), true
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		rec  record
+		kept bool
+	}{
+		{"plain", record{key: "a", value: []byte("1"), version: 10}, true},
+		{"flags", record{key: "b", value: []byte("2"), flags: 0xdeadbeef, version: 11}, true},
+		{"empty value", record{key: "c", value: []byte{}, version: 12}, true},
+		{"binary", record{key: "d", value: []byte{0, 0xff, '\r', '\n', 0}, version: 13}, true},
+		{"ttl", record{key: "e", value: []byte("5"), expiresAt: now.Add(time.Hour), version: 14}, true},
+		{"soft ttl", record{key: "f", value: []byte("6"), expiresAt: now.Add(time.Hour), staleAt: now.Add(time.Minute), version: 15}, true},
+		{"expired since", record{key: "g", value: []byte("7"), expiresAt: now.Add(-time.Second), version: 16}, false},
+	}
+	var entries []record
+	for _, tt := range tests {
+		entries = append(entries, tt.rec)
+	}
+	path := filepath.Join(t.TempDir(), "snap")
+	// Written directly, since WriteSnapshot would leave out the expired one.
+	var buf bytes.Buffer
+	if err := writeSnapshot(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestCache(PolicyLRU, 100)
+	n, err := c.LoadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(entries)-1 {
+		t.Errorf("loaded %d entries, want %d", n, len(entries)-1)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := stored(c, tt.rec.key)
+			if ok != tt.kept {
+				t.Fatalf("present = %v, want %v", ok, tt.kept)
+			}
+			if ok && !sameRecord(got, tt.rec) {
+				t.Errorf("got %+v, want %+v", got, tt.rec)
+			}
+		})
+	}
+
+	// Written back out and read again, the cache is unchanged.
+	if _, err := c.WriteSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	again := newTestCache(PolicyLRU, 100)
+	if _, err := again.LoadSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		want, _ := stored(c, tt.rec.key)
+		got, ok := stored(again, tt.rec.key)
+		if ok != tt.kept || ok && !sameRecord(got, want) {
+			t.Errorf("%s after rewrite: %+v, %v, want %+v", tt.rec.key, got, ok, want)
+		}
+	}
+}
+
+func TestSnapshotKeepsEvictionOrder(t *testing.T) {
+	c := newTestCache(PolicyLRU, 3)
+	for _, k := range []string{"a", "b", "c"} {
+		c.set(k, []byte(k), 0, 0, 0, 0)
+	}
+	c.get("a") // b is now the coldest
+	path := filepath.Join(t.TempDir(), "snap")
+	if _, err := c.WriteSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	restored := newTestCache(PolicyLRU, 3)
+	if _, err := restored.LoadSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	restored.set("d", []byte("d"), 0, 0, 0, 0)
+	if restored.exists("b") || !restored.exists("a") || !restored.exists("c") {
+		t.Errorf("resident = %v, want b evicted first", resident(restored, []string{"a", "b", "c", "d"}))
+	}
+}
+
+func TestSnapshotRejectsDamage(t *testing.T) {
+	var good bytes.Buffer
+	entries := []record{
+		{key: "a", value: []byte("1"), version: 1},
+		{key: "b", value: []byte("2"), version: 2},
+	}
+	if err := writeSnapshot(&good, entries); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		damage func(b []byte) []byte
+	}{
+		{"empty", func(b []byte) []byte { return nil }},
+		{"truncated", func(b []byte) []byte { return b[:len(b)-5] }},
+		{"header only", func(b []byte) []byte { return b[:len(snapshotMagic)+2] }},
+		{"bad magic", func(b []byte) []byte { b[0] = 'X'; return b }},
+		{"newer version", func(b []byte) []byte { b[len(snapshotMagic)] = 9; return b }},
+		// the value of a: header, tag, key length, key, value length, value
+		{"flipped value byte", func(b []byte) []byte { b[len(snapshotMagic)+2+4] ^= 0x20; return b }},
+		// the key length of a, far beyond the end of the file
+		{"huge length", func(b []byte) []byte {
+			at := len(snapshotMagic) + 2 + 1
+			return slices.Concat(b[:at], binary.AppendUvarint(nil, 1<<30), b[at+1:])
+		}},
+		{"bad checksum", func(b []byte) []byte { b[len(b)-1] ^= 1; return b }},
+		{"trailing data", func(b []byte) []byte { return append(b, 0) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snap")
+			if err := os.WriteFile(path, tt.damage(bytes.Clone(good.Bytes())), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			c := newTestCache(PolicyLRU, 10)
+			c.set("x", []byte("x"), 0, 0, 0, 0)
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			if _, err := c.LoadSnapshot(path); !errors.Is(err, ErrBadSnapshot) {
+				t.Errorf("LoadSnapshot = %v, want ErrBadSnapshot", err)
+			}
+			// Nothing is allocated for a length the file cannot hold.
+			runtime.ReadMemStats(&after)
+			if n := after.TotalAlloc - before.TotalAlloc; n > 1<<20 {
+				t.Errorf("LoadSnapshot allocated %d bytes", n)
+			}
+			if keys := c.GetAllKeys(); len(keys) != 1 || keys[0] != "x" {
+				t.Errorf("cache holds %v after a rejected snapshot, want [x]", keys)
+			}
+		})
+	}
+}
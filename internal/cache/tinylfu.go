@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"hash/maphash"
+	"math/bits"
+)
+
+// tinyLFUPolicy is W-TinyLFU (Einziger, Friedman and Manes). New entries
+// land in a small LRU window; entries pushed out of the window join the
+// probation segment of a segmented LRU, and a hit there promotes them to the
+// protected segment. Under pressure the newest arrival on probation competes
+// with the oldest entry on probation and the one with the lower estimated
+// frequency is evicted, so one-hit wonders from a scan never displace the
+// hot set.
+type tinyLFUPolicy struct {
+	window, probation, protected DLL
+	sketch                       *countMinSketch
+	windowMax, protectedMax      int
+}
+
+const (
+	windowPercent    = 1  // share of the capacity kept in the window
+	protectedPercent = 80 // share of the main cache kept protected
+)
+
+// newTinyLFUPolicy sizes the segments and the sketch for a cache expected
+// to hold sizeHint entries. Sizing them from the capacity rather than from
+// the entries held keeps a filling cache from pushing nearly every new
+// entry straight out of a window of one.
+func newTinyLFUPolicy(sizeHint int) *tinyLFUPolicy {
+	windowMax := max(sizeHint*windowPercent/100, 1)
+	return &tinyLFUPolicy{
+		sketch:       newCountMinSketch(sizeHint),
+		windowMax:    windowMax,
+		protectedMax: max((sizeHint-windowMax)*protectedPercent/100, 1),
+	}
+}
+
+func (p *tinyLFUPolicy) add(n *dllNode) {
+	p.sketch.increment(n.key)
+	n.seg = segWindow
+	p.window.pushFront(n)
+
+	// Overflow from the window moves to probation; whether it stays there is
+	// decided in evict once the cache is actually full.
+	for p.window.len > p.windowMax {
+		moved := p.window.evictLRU()
+		moved.seg = segProbation
+		p.probation.pushFront(moved)
+	}
+}
+
+func (p *tinyLFUPolicy) access(n *dllNode) {
+	p.sketch.increment(n.key)
+	switch n.seg {
+	case segWindow:
+		p.window.moveToFront(n)
+	case segProbation:
+		p.probation.remove(n)
+		n.seg = segProtected
+		p.protected.pushFront(n)
+		for p.protected.len > p.protectedMax {
+			demoted := p.protected.evictLRU()
+			demoted.seg = segProbation
+			p.probation.pushFront(demoted)
+		}
+	case segProtected:
+		p.protected.moveToFront(n)
+	}
+}
+
+func (p *tinyLFUPolicy) remove(n *dllNode) {
+	switch n.seg {
+	case segWindow:
+		p.window.remove(n)
+	case segProbation:
+		p.probation.remove(n)
+	case segProtected:
+		p.protected.remove(n)
+	}
+	n.seg = segNone
+}
+
+func (p *tinyLFUPolicy) evict() *dllNode {
+	var victim *dllNode
+	switch {
+	case p.probation.len >= 2:
+		candidate, oldest := p.probation.front, p.probation.back
+		victim = oldest
+		if p.sketch.estimate(candidate.key) <= p.sketch.estimate(oldest.key) {
+			victim = candidate
+		}
+		p.probation.remove(victim)
+	case p.probation.len == 1 && p.protected.len > 0:
+		candidate, oldest := p.probation.front, p.protected.back
+		if p.sketch.estimate(candidate.key) <= p.sketch.estimate(oldest.key) {
+			victim = candidate
+			p.probation.remove(victim)
+		} else {
+			victim = oldest
+			p.protected.remove(victim)
+		}
+	case p.probation.len == 1:
+		victim = p.probation.evictLRU()
+	case p.protected.len > 0:
+		victim = p.protected.evictLRU()
+	default:
+		victim = p.window.evictLRU()
+	}
+	if victim != nil {
+		victim.seg = segNone
+	}
+	return victim
+}
+
+func (p *tinyLFUPolicy) walk(fn func(n *dllNode)) {
+	p.probation.walk(fn)
+	p.protected.walk(fn)
+	p.window.walk(fn)
+}
+
+// countMinSketch estimates how often keys were seen using four rows of
+// counters that saturate at 15, as 4-bit counters would. All counters are
+// halved once the number of increments reaches ten times the width, so old
+// popularity fades.
+type countMinSketch struct {
+	rows      [4][]uint8
+	mask      uint64
+	seed      maphash.Seed
+	additions int
+	resetAt   int
+}
+
+func newCountMinSketch(width int) *countMinSketch {
+	if width < 64 {
+		width = 64
+	}
+	width = 1 << bits.Len(uint(width-1)) // next power of two
+	s := &countMinSketch{
+		mask:    uint64(width - 1),
+		seed:    maphash.MakeSeed(),
+		resetAt: 10 * width,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// indexes derives one counter position per row from a single 64-bit hash.
+func (s *countMinSketch) indexes(key string) [4]uint64 {
+	h := maphash.String(s.seed, key)
+	lo, hi := h, h>>32|h<<32
+	var idx [4]uint64
+	for i := range idx {
+		idx[i] = (lo + uint64(i)*hi) & s.mask
+	}
+	return idx
+}
+
+func (s *countMinSketch) increment(key string) {
+	for i, j := range s.indexes(key) {
+		if s.rows[i][j] < 15 {
+			s.rows[i][j]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		for i := range s.rows {
+			for j := range s.rows[i] {
+				s.rows[i][j] >>= 1
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *countMinSketch) estimate(key string) uint8 {
+	est := uint8(15)
+	for i, j := range s.indexes(key) {
+		est = min(est, s.rows[i][j])
+	}
+	return est
+}
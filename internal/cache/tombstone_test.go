@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTombstones(t *testing.T) {
+	now := time.Now()
+	at := now.UnixNano()
+	old := now.Add(-2 * time.Hour).UnixNano() // past a grace of an hour
+	tests := []struct {
+		name    string
+		grace   time.Duration
+		deleted int64 // version of the delete
+		write   int64 // version of the write after it, 0 for unversioned
+		stored  bool  // whether the write is kept
+		tomb    bool  // whether the tombstone outlives a sweep
+	}{
+		{"older write dropped", time.Hour, at, at - 1, false, true},
+		{"same version dropped", time.Hour, at, at, false, true},
+		{"newer write kept", time.Hour, at, at + 1, true, false},
+		{"unversioned write kept", time.Hour, at + int64(time.Minute), 0, true, false},
+		{"no grace keeps no tombstone", 0, at, at - 1, true, false},
+		{"expired tombstone swept", time.Hour, old, 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLruCache(Options{Capacity: 10, Shards: 1, TombstoneGrace: tt.grace})
+			c.set("k", []byte("v"), 0, 0, 0, tt.deleted-1)
+			c.delete("k", tt.deleted, false)
+			if tt.write != 0 || tt.stored {
+				if err := c.set("k", []byte("w"), 0, 0, 0, tt.write); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := c.exists("k"); got != tt.stored {
+				t.Errorf("stored = %v, want %v", got, tt.stored)
+			}
+			if it, err := c.get("k"); err == nil && tt.write == 0 && it.version <= tt.deleted {
+				t.Errorf("unversioned write at %d, want above the delete at %d", it.version, tt.deleted)
+			}
+			c.shards[0].sweep()
+			if _, ok := c.tombstone("k"); ok != tt.tomb {
+				t.Errorf("tombstone kept = %v, want %v", ok, tt.tomb)
+			}
+		})
+	}
+}
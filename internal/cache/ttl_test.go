@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// timed returns a record for key expiring and going stale the given time
+// from now, in the past if negative; 0 leaves either unset.
+func timed(key string, expiresIn, staleIn time.Duration) record {
+	r := record{key: key, value: []byte(key)}
+	now := time.Now()
+	if expiresIn != 0 {
+		r.expiresAt = now.Add(expiresIn)
+	}
+	if staleIn != 0 {
+		r.staleAt = now.Add(staleIn)
+	}
+	return r
+}
+
+func TestLazyExpiry(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresIn time.Duration
+		live      bool
+	}{
+		{"no ttl", 0, true},
+		{"future", time.Hour, true},
+		{"past", -time.Second, false},
+		{"now", -time.Nanosecond, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(PolicyLRU, 10)
+			s := c.shards[0]
+			if err := s.set(timed("k", tt.expiresIn, 0)); err != nil {
+				t.Fatal(err)
+			}
+			if got := c.exists("k"); got != tt.live {
+				t.Errorf("exists = %v, want %v", got, tt.live)
+			}
+			it, err := c.get("k")
+			if (err == nil) != tt.live {
+				t.Fatalf("get error = %v, want live %v", err, tt.live)
+			}
+			if tt.live {
+				if tt.expiresIn == 0 && it.ttl != 0 {
+					t.Errorf("ttl = %v, want 0 for no expiry", it.ttl)
+				}
+				if tt.expiresIn > 0 && (it.ttl <= 0 || it.ttl > tt.expiresIn) {
+					t.Errorf("ttl = %v, want within (0, %v]", it.ttl, tt.expiresIn)
+				}
+				return
+			}
+			// the read removed the expired entry and counted it
+			if _, ok := s.cache["k"]; ok {
+				t.Error("expired entry still in the shard after get")
+			}
+			if n := s.stats.expired.Load(); n != 1 {
+				t.Errorf("expired count = %d, want 1", n)
+			}
+		})
+	}
+}
+
+func TestSweepExpiry(t *testing.T) {
+	// Each pass is repeated the way startSweeper does, until a sample comes
+	// back less than a quarter expired.
+	tests := []struct {
+		name          string
+		expired, live int
+		wantLeft      int
+	}{
+		{"empty", 0, 0, 0},
+		{"all live", 0, 30, 30},
+		{"all expired", 50, 0, 0},
+		{"one sample", 10, 5, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(PolicyLRU, 100)
+			s := c.shards[0]
+			for i := range tt.expired {
+				s.set(timed(fmt.Sprintf("e%d", i), -time.Second, 0))
+			}
+			for i := range tt.live {
+				s.set(timed(fmt.Sprintf("l%d", i), time.Hour, 0))
+			}
+			total := 0
+			for {
+				checked, expired := s.sweep()
+				total += expired
+				if checked == 0 || expired*4 <= checked {
+					break
+				}
+			}
+			if total != tt.expired {
+				t.Errorf("swept %d, want %d", total, tt.expired)
+			}
+			if n := len(s.cache); n != tt.wantLeft {
+				t.Errorf("%d entries left, want %d", n, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestSoftExpiry(t *testing.T) {
+	// Only the first reader of a stale entry is told to revalidate it while
+	// its refresh lease runs.
+	tests := []struct {
+		name       string
+		staleIn    time.Duration
+		stale      []bool
+		revalidate []bool
+	}{
+		{"fresh", time.Hour, []bool{false, false}, []bool{false, false}},
+		{"stale", -time.Second, []bool{true, true, true}, []bool{true, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCache(PolicyLRU, 10)
+			c.shards[0].set(timed("k", time.Hour, tt.staleIn))
+			for i := range tt.stale {
+				it, err := c.get("k")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if it.stale != tt.stale[i] || it.revalidate != tt.revalidate[i] {
+					t.Errorf("read %d: stale, revalidate = %v, %v, want %v, %v",
+						i, it.stale, it.revalidate, tt.stale[i], tt.revalidate[i])
+				}
+			}
+		})
+	}
+}
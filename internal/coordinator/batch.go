@@ -0,0 +1,311 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Entry is a key/value pair to write.
+type Entry struct {
+	Key   string
+	Value []byte
+	Flags uint32
+	TTL   time.Duration // 0 means no expiry
+	// SoftTTL is how long the value is fresh. After it, and until TTL, it
+	// is still served but marked stale. 0 means it never turns stale.
+	SoftTTL time.Duration
+	// Version orders conflicting writes: replicas keep the highest. It is
+	// the write time in unix nanoseconds unless set by the caller.
+	Version int64
+}
+
+func (e Entry) request() *cacheNodepb.SetRequest {
+	return &cacheNodepb.SetRequest{Key: e.Key, Value: e.Value, Flags: e.Flags, TtlMs: e.TTL.Milliseconds(), SoftTtlMs: e.SoftTTL.Milliseconds(), Version: e.Version}
+}
+
+// stamp versions e with now unless it already has a version.
+func (e *Entry) stamp(now time.Time) {
+	if e.Version == 0 {
+		e.Version = now.UnixNano()
+	}
+}
+
+// GetResult is the outcome of one key in an MGet. Err is ErrNotFound on a
+// miss.
+type GetResult struct {
+	Item
+	Err error
+}
+
+// DeleteResult is the outcome of one key in an MDelete.
+type DeleteResult struct {
+	Deleted bool // the key was stored on at least one replica
+	Err     error
+}
+
+// batch is the slice of a multi-key request bound for one node. idx maps
+// each position in the batch back to the caller's request.
+type batch struct {
+	n   node
+	idx []int
+}
+
+// MGet reads keys with one RPC per owning node, all issued in parallel.
+// Results are returned in the order of keys. Keys whose owner could not be
+// reached are retried one at a time against their replicas. With a loader,
+// misses are read through it in parallel. Above consistency level One every
+// key is read on its own, as by Get.
+func (c *Coordinator) MGet(ctx context.Context, keys []string) []GetResult {
+	results := make([]GetResult, len(keys))
+	if c.readLevel(ctx) != One {
+		var wg sync.WaitGroup
+		for i, key := range keys {
+			wg.Add(1)
+			go func(i int, key string) {
+				defer wg.Done()
+				results[i].Item, results[i].Err = c.Get(ctx, key)
+			}(i, key)
+		}
+		wg.Wait()
+		return results
+	}
+
+	groups := make(map[string]*batch)
+	for i, key := range keys {
+		n, ok := c.ring.getNode(key)
+		if !ok {
+			results[i].Err = ErrNoNodes
+			continue
+		}
+		addBatch(groups, n, i)
+	}
+
+	var retry []int
+	var mu sync.Mutex
+	c.fanOutBatches(groups, func(b *batch) {
+		req := &cacheNodepb.MGetRequest{Keys: make([]string, len(b.idx))}
+		for j, i := range b.idx {
+			req.Keys[j] = keys[i]
+		}
+		res, err := b.n.client.MGet(ctx, req)
+		if err != nil {
+			c.log.Warn("mget failed", "node", b.n.addr, "keys", len(b.idx), "err", err)
+			mu.Lock()
+			retry = append(retry, b.idx...)
+			mu.Unlock()
+			return
+		}
+		for j, i := range b.idx {
+			item := res.Items[j]
+			if !item.Found {
+				results[i].Err = ErrNotFound
+				continue
+			}
+			results[i].Item = itemFrom(item)
+		}
+	})
+
+	for _, i := range retry {
+		results[i].Item, results[i].Err = c.Get(ctx, keys[i])
+	}
+
+	if c.loader != nil {
+		var wg sync.WaitGroup
+		for i := range results {
+			if !errors.Is(results[i].Err, ErrNotFound) {
+				continue
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i].Item, results[i].Err = c.Get(ctx, keys[i])
+			}(i)
+		}
+		wg.Wait()
+	}
+	return results
+}
+
+// MSet writes entries to their owners and replicas with one RPC per node,
+// all issued in parallel. Each entry succeeds if as many of its nodes as
+// the write consistency level requires accepted it; errors are returned in
+// the order of entries. Depending on the write mode, the entries are also
+// written to the backend in one batch and all fail if it does.
+func (c *Coordinator) MSet(ctx context.Context, entries []Entry) []error {
+	now := time.Now()
+	entries = slices.Clone(entries)
+	for i := range entries {
+		entries[i].stamp(now)
+	}
+	if err := c.writeBackend(ctx, entries); err != nil {
+		return repeatErr(err, len(entries))
+	}
+	keys := make([]string, len(entries))
+	for i, e := range entries {
+		keys[i] = e.Key
+	}
+	return c.writeBatches(ctx, keys, hint{}, func(b *batch) ([]error, error) {
+		req := &cacheNodepb.MSetRequest{Items: make([]*cacheNodepb.SetRequest, len(b.idx))}
+		for j, i := range b.idx {
+			req.Items[j] = entries[i].request()
+		}
+		res, err := b.n.client.MSet(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		errs := make([]error, len(b.idx))
+		for j, item := range res.Items {
+			if !item.Success {
+				code := codes.Code(item.Code)
+				if code == codes.OK {
+					code = codes.Unknown
+				}
+				errs[j] = nodeError(b.n.addr, status.Error(code, item.Error))
+			}
+		}
+		return errs, nil
+	})
+}
+
+// MDelete removes keys from their owners and replicas with one RPC per node,
+// all issued in parallel, and from the backend as MSet writes to it.
+// Results are returned in the order of keys.
+func (c *Coordinator) MDelete(ctx context.Context, keys []string) []DeleteResult {
+	if err := c.deleteBackend(ctx, keys); err != nil {
+		results := make([]DeleteResult, len(keys))
+		for i := range results {
+			results[i].Err = err
+		}
+		return results
+	}
+	deleted := make([]bool, len(keys))
+	var mu sync.Mutex
+	version := time.Now().UnixNano()
+	errs := c.writeBatches(ctx, keys, hint{delete: true, version: version}, func(b *batch) ([]error, error) {
+		req := &cacheNodepb.MDeleteRequest{Keys: make([]string, len(b.idx)), Version: version}
+		for j, i := range b.idx {
+			req.Keys[j] = keys[i]
+		}
+		res, err := b.n.client.MDelete(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		for j, i := range b.idx {
+			deleted[i] = deleted[i] || res.Deleted[j]
+		}
+		mu.Unlock()
+		return make([]error, len(b.idx)), nil
+	})
+
+	results := make([]DeleteResult, len(keys))
+	for i := range keys {
+		results[i] = DeleteResult{Deleted: deleted[i], Err: errs[i]}
+	}
+	return results
+}
+
+// writeBatches groups keys by every node that should hold them and calls
+// send once per node in parallel. send returns a per-item error for each
+// key in the batch, or an error if the whole RPC failed. A key's result is
+// nil if as many of its nodes as the write consistency level requires
+// accepted it, otherwise the first failure. Keys written to a node standing
+// in for a replica that is down are hinted for that replica with h; unlike
+// single writes, a batch whose RPC fails is not handed off.
+func (c *Coordinator) writeBatches(ctx context.Context, keys []string, h hint, send func(b *batch) ([]error, error)) []error {
+	need := c.writeLevel(ctx).required(c.ring.replicas)
+	groups := make(map[string]*batch)
+	errs := make([]error, len(keys))
+	owners := make(map[string]map[int]string) // holder -> key index -> owner
+	for i, key := range keys {
+		targets, _ := c.ring.writeTargets(key)
+		if len(targets) < need {
+			errs[i] = tooFewReplicas(len(targets), need)
+			continue
+		}
+		for _, t := range targets {
+			addBatch(groups, t.node, i)
+			if t.owner != "" {
+				if owners[t.addr] == nil {
+					owners[t.addr] = make(map[int]string)
+				}
+				owners[t.addr][i] = t.owner
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	acks := make([]int, len(keys))
+	c.fanOutBatches(groups, func(b *batch) {
+		itemErrs, err := send(b)
+		if err != nil {
+			c.log.Warn("batch write failed", "node", b.n.addr, "keys", len(b.idx), "err", err)
+			err = nodeError(b.n.addr, err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for j, i := range b.idx {
+			e := err
+			if e == nil {
+				e = itemErrs[j]
+			}
+			if e == nil {
+				acks[i]++
+				if owner, ok := owners[b.n.addr][i]; ok {
+					h := h
+					h.holder = b.n.addr
+					c.hint(owner, keys[i], h)
+				}
+			} else if errs[i] == nil {
+				errs[i] = e
+			}
+		}
+	})
+
+	for i := range keys {
+		switch {
+		case acks[i] >= need:
+			errs[i] = nil
+		case errs[i] == nil:
+			errs[i] = ErrNoNodes // no node was found for the key
+		}
+	}
+	return errs
+}
+
+func repeatErr(err error, n int) []error {
+	errs := make([]error, n)
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+func addBatch(groups map[string]*batch, n node, i int) {
+	b, ok := groups[n.addr]
+	if !ok {
+		b = &batch{n: n}
+		groups[n.addr] = b
+	}
+	b.idx = append(b.idx, i)
+}
+
+func (c *Coordinator) fanOutBatches(groups map[string]*batch, fn func(b *batch)) {
+	var wg sync.WaitGroup
+	for _, b := range groups {
+		wg.Add(1)
+		go func(b *batch) {
+			defer wg.Done()
+			fn(b)
+		}(b)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,104 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// entries returns an entry for each key, with the key as its value.
+func entries(keys []string) []Entry {
+	out := make([]Entry, len(keys))
+	for i, k := range keys {
+		out[i] = Entry{Key: k, Value: []byte(k)}
+	}
+	return out
+}
+
+func TestBatchRoundTrip(t *testing.T) {
+	nodes := startTestNodes(t, 3)
+	c := newTestCoordinator(t, nodes, Config{Replicas: 2})
+	ctx := context.Background()
+	keys := testKeys(100)
+
+	for i, err := range c.MSet(ctx, entries(keys)) {
+		if err != nil {
+			t.Fatalf("MSet %s: %v", keys[i], err)
+		}
+	}
+	// One RPC per node still puts every key on each of its replicas.
+	if k := misplaced(c, nodes, keys); k != "" {
+		t.Fatalf("after MSet: %s", k)
+	}
+
+	// Results come back in request order, misses included.
+	read := append([]string{"missing"}, keys...)
+	for i, res := range c.MGet(ctx, read) {
+		switch {
+		case i == 0 && !errors.Is(res.Err, ErrNotFound):
+			t.Errorf("MGet missing = %q, %v, want %v", res.Value, res.Err, ErrNotFound)
+		case i > 0 && (res.Err != nil || string(res.Value) != read[i]):
+			t.Errorf("MGet %s = %q, %v", read[i], res.Value, res.Err)
+		}
+	}
+
+	for i, res := range c.MDelete(ctx, read) {
+		if res.Err != nil || res.Deleted != (i > 0) {
+			t.Errorf("MDelete %s = %+v, want deleted %v", read[i], res, i > 0)
+		}
+	}
+	for i, res := range c.MGet(ctx, keys) {
+		if !errors.Is(res.Err, ErrNotFound) {
+			t.Errorf("MGet %s after MDelete = %q, %v", keys[i], res.Value, res.Err)
+		}
+	}
+}
+
+func TestBatchWithNodeDown(t *testing.T) {
+	nodes := startTestNodes(t, 3)
+	c := newTestCoordinator(t, nodes, Config{Replicas: 2})
+	ctx := context.Background()
+	keys := testKeys(100)
+	for i, err := range c.MSet(ctx, entries(keys)) {
+		if err != nil {
+			t.Fatalf("MSet %s: %v", keys[i], err)
+		}
+	}
+
+	// The node is stopped but not yet known to be down, so batches are
+	// still sent to it and fail.
+	down := nodes[0]
+	down.stop()
+
+	// Reads of keys it owns fall back to their other replica.
+	for i, res := range c.MGet(ctx, keys) {
+		if res.Err != nil || string(res.Value) != keys[i] {
+			t.Errorf("MGet %s = %q, %v", keys[i], res.Value, res.Err)
+		}
+	}
+
+	// One replica is enough at One, but not at All for keys the stopped
+	// node is a replica of.
+	for i, err := range c.MSet(ctx, entries(keys)) {
+		if err != nil {
+			t.Errorf("MSet %s at one: %v", keys[i], err)
+		}
+	}
+	all := WithConsistency(ctx, All)
+	for i, err := range c.MSet(all, entries(keys)) {
+		if want := c.ring.isReplica(down.addr, keys[i]); want != errors.Is(err, ErrNodeUnavailable) {
+			t.Errorf("MSet %s at all = %v, want unavailable %v", keys[i], err, want)
+		}
+	}
+
+	for i, res := range c.MDelete(ctx, keys) {
+		if res.Err != nil || !res.Deleted {
+			t.Errorf("MDelete %s = %+v", keys[i], res)
+		}
+	}
+	for i, res := range c.MGet(ctx, keys) {
+		if !errors.Is(res.Err, ErrNotFound) {
+			t.Errorf("MGet %s after MDelete = %q, %v", keys[i], res.Value, res.Err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package coordinator
+
+import (
+	"context"
+	"time"
+
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+)
+
+// CompareAndSet stores e only if its key is still at version expected, the
+// Version of an earlier Get, and returns the version it was stored at.
+// Otherwise it returns ErrNotFound if the key is not stored, or ErrConflict
+// together with the version that is.
+func (c *Coordinator) CompareAndSet(ctx context.Context, e Entry, expected int64) (int64, error) {
+	ok, version, err := c.setIf(ctx, e, func(ctx context.Context, n node, req *cacheNodepb.SetRequest) (*cacheNodepb.ConditionalSetResponse, error) {
+		return n.client.CompareAndSet(ctx, &cacheNodepb.CompareAndSetRequest{Item: req, ExpectedVersion: expected})
+	})
+	switch {
+	case err != nil:
+		return 0, err
+	case ok:
+		return version, nil
+	case version == 0:
+		return 0, ErrNotFound
+	}
+	return version, ErrConflict
+}
+
+// SetIfAbsent stores e only if its key is not stored, and returns the
+// version it was stored at. Otherwise it returns ErrConflict together with
+// the version that is.
+func (c *Coordinator) SetIfAbsent(ctx context.Context, e Entry) (int64, error) {
+	ok, version, err := c.setIf(ctx, e, func(ctx context.Context, n node, req *cacheNodepb.SetRequest) (*cacheNodepb.ConditionalSetResponse, error) {
+		return n.client.SetIfAbsent(ctx, req)
+	})
+	switch {
+	case err != nil:
+		return 0, err
+	case !ok:
+		return version, ErrConflict
+	}
+	return version, nil
+}
+
+// SetIfPresent stores e only if its key is stored, and returns the version
+// it was stored at, or ErrNotFound.
+func (c *Coordinator) SetIfPresent(ctx context.Context, e Entry) (int64, error) {
+	ok, version, err := c.setIf(ctx, e, func(ctx context.Context, n node, req *cacheNodepb.SetRequest) (*cacheNodepb.ConditionalSetResponse, error) {
+		return n.client.SetIfPresent(ctx, req)
+	})
+	switch {
+	case err != nil:
+		return 0, err
+	case !ok:
+		return 0, ErrNotFound
+	}
+	return version, nil
+}
+
+// setIf runs a conditional write. The condition is decided by the first
+// node key is written to, which is also the one reads at level One go to;
+// if the write goes ahead there, it is propagated from there at the
+// version that node stored it at, which the other replicas keep as any
+// newer write. It is written to the backend, depending on the write mode,
+// only once the condition has passed.
+func (c *Coordinator) setIf(ctx context.Context, e Entry, call func(ctx context.Context, n node, req *cacheNodepb.SetRequest) (*cacheNodepb.ConditionalSetResponse, error)) (bool, int64, error) {
+	key := e.Key
+	targets, spares := c.ring.writeTargets(key)
+	if len(targets) == 0 {
+		return false, 0, ErrNoNodes
+	}
+	need := c.writeLevel(ctx).required(c.ring.replicas)
+	if len(targets) < need {
+		return false, 0, tooFewReplicas(len(targets), need)
+	}
+	e.stamp(time.Now())
+
+	first := targets[0]
+	res, err := call(ctx, first.node, e.request())
+	if err != nil {
+		c.log.Warn("conditional set failed", "key", key, "node", first.addr, "err", err)
+		return false, 0, nodeError(first.addr, err)
+	}
+	if !res.Success {
+		c.log.Debug("conditional set refused", "key", key, "node", first.addr, "version", res.Version)
+		return false, res.Version, nil
+	}
+	e.Version = res.Version
+	if err := c.propagate(ctx, e, res.Previous, targets, spares, need); err != nil {
+		return false, 0, err
+	}
+	return true, e.Version, nil
+}
+
+// CompareAndDelete deletes key only if it is still at version expected, the
+// Version of an earlier Get, and returns the version of the delete.
+// Otherwise it returns ErrNotFound if the key is not stored, or ErrConflict
+// together with the version that is. As with a conditional write, the first
+// node key is written to decides; the delete is then made on the backend,
+// depending on the write mode, and repeated on the other replicas at the
+// same version. If the backend refuses it, the key stays deleted from that
+// node, which only costs a reload.
+func (c *Coordinator) CompareAndDelete(ctx context.Context, key string, expected int64) (int64, error) {
+	targets, spares := c.ring.writeTargets(key)
+	if len(targets) == 0 {
+		return 0, ErrNoNodes
+	}
+	need := c.writeLevel(ctx).required(c.ring.replicas)
+	if len(targets) < need {
+		return 0, tooFewReplicas(len(targets), need)
+	}
+
+	first := targets[0]
+	res, err := first.client.CompareAndDelete(ctx, &cacheNodepb.CompareAndDeleteRequest{Key: key, ExpectedVersion: expected})
+	if err != nil {
+		c.log.Warn("conditional delete failed", "key", key, "node", first.addr, "err", err)
+		return 0, nodeError(first.addr, err)
+	}
+	switch {
+	case !res.Success && res.Version == 0:
+		return 0, ErrNotFound
+	case !res.Success:
+		c.log.Debug("conditional delete refused", "key", key, "node", first.addr, "version", res.Version)
+		return res.Version, ErrConflict
+	}
+	h := hint{holder: first.addr, delete: true, version: res.Version}
+	if first.owner != "" {
+		c.hint(first.owner, key, h)
+	}
+	if err := c.deleteBackend(ctx, []string{key}); err != nil {
+		return 0, err
+	}
+
+	req := &cacheNodepb.DeleteRequest{Key: key, Version: res.Version}
+	err = c.replicate(ctx, key, targets[1:], spares, need, h, func(ctx context.Context, n node) error {
+		_, err := n.client.Delete(ctx, req)
+		if err != nil {
+			c.log.Warn("delete failed", "key", key, "node", n.addr, "err", err)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.Version, nil
+}
+
+// propagate finishes a write that the first of targets has already taken
+// at e.Version, replacing previous: it writes e to the backend, or undoes
+// it on that node if the backend refuses it, and then copies it to the
+// other replicas.
+func (c *Coordinator) propagate(ctx context.Context, e Entry, previous *cacheNodepb.GetResponse, targets []writeTarget, spares []node, need int) error {
+	key := e.Key
+	first := targets[0]
+	if err := c.writeBackend(ctx, []Entry{e}); err != nil {
+		c.rollback(ctx, first.node, e, previous)
+		return err
+	}
+	if first.owner != "" {
+		c.hint(first.owner, key, hint{holder: first.addr})
+	}
+
+	req := e.request()
+	return c.replicate(ctx, key, targets[1:], spares, need, hint{}, func(ctx context.Context, n node) error {
+		_, err := n.client.Set(ctx, req)
+		if err != nil {
+			c.log.Warn("set failed", "key", key, "node", n.addr, "err", err)
+		}
+		return err
+	})
+}
+
+// rollback undoes a write n took at e.Version by putting previous back, or
+// deleting the key if there was none. Both are conditional on the key
+// still being at e.Version, so a write made since is kept.
+func (c *Coordinator) rollback(ctx context.Context, n node, e Entry, previous *cacheNodepb.GetResponse) {
+	var err error
+	if previous == nil {
+		_, err = n.client.CompareAndDelete(ctx, &cacheNodepb.CompareAndDeleteRequest{Key: e.Key, ExpectedVersion: e.Version})
+	} else {
+		req := copyRequest(e.Key, previous)
+		req.Version = 0 // stored above e.Version
+		_, err = n.client.CompareAndSet(ctx, &cacheNodepb.CompareAndSetRequest{Item: req, ExpectedVersion: e.Version})
+	}
+	if err != nil {
+		c.log.Warn("rollback failed", "key", e.Key, "node", n.addr, "err", err)
+	}
+}
+
+// replicate copies a write the first of a key's targets has taken to rest,
+// waiting for as many of them as need, which counts the first, calls for.
+// If the first already meets it, they are written in the background.
+func (c *Coordinator) replicate(ctx context.Context, key string, rest []writeTarget, spares []node, need int, h hint, write func(ctx context.Context, n node) error) error {
+	if len(rest) == 0 {
+		return nil
+	}
+	if need <= 1 {
+		go func() {
+			bctx, cancel := detach(ctx)
+			defer cancel()
+			c.writeReplicas(bctx, key, rest, spares, 1, h, write)
+		}()
+		return nil
+	}
+	return c.writeReplicas(ctx, key, rest, spares, need-1, h, write)
+}
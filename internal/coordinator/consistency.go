@@ -0,0 +1,89 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Consistency is how many of a key's replicas must answer a read or
+// acknowledge a write before it completes.
+type Consistency int
+
+const (
+	// One completes on the first replica. Reads go to the owner and only
+	// fall back to the other replicas if it cannot be reached.
+	One Consistency = iota
+	// Quorum completes on a majority of the replicas. A quorum read after a
+	// quorum write sees that write.
+	Quorum
+	// All completes only once every replica has answered.
+	All
+)
+
+func (c Consistency) String() string {
+	switch c {
+	case One:
+		return "one"
+	case Quorum:
+		return "quorum"
+	case All:
+		return "all"
+	}
+	return "unknown"
+}
+
+// ParseConsistency maps a case-insensitive name onto a level.
+func ParseConsistency(name string) (Consistency, error) {
+	for _, c := range []Consistency{One, Quorum, All} {
+		if strings.EqualFold(name, c.String()) {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown consistency level %q", name)
+}
+
+// required returns how many of replicas must answer.
+func (c Consistency) required(replicas int) int {
+	switch c {
+	case Quorum:
+		return replicas/2 + 1
+	case All:
+		return replicas
+	}
+	return 1
+}
+
+type consistencyKey struct{}
+
+// WithConsistency returns a context under which reads and writes use level
+// instead of the coordinator's default.
+func WithConsistency(ctx context.Context, level Consistency) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, level)
+}
+
+// ConsistencyFrom returns the level set with WithConsistency, if any.
+func ConsistencyFrom(ctx context.Context) (Consistency, bool) {
+	level, ok := ctx.Value(consistencyKey{}).(Consistency)
+	return level, ok
+}
+
+func (c *Coordinator) readLevel(ctx context.Context) Consistency {
+	if level, ok := ConsistencyFrom(ctx); ok {
+		return level
+	}
+	return c.readConsistency
+}
+
+func (c *Coordinator) writeLevel(ctx context.Context) Consistency {
+	if level, ok := ConsistencyFrom(ctx); ok {
+		return level
+	}
+	return c.writeConsistency
+}
+
+// tooFewReplicas is returned when fewer replicas than a level needs are
+// reachable.
+func tooFewReplicas(have, need int) error {
+	return fmt.Errorf("%w: %d of %d required replicas reachable", ErrNodeUnavailable, have, need)
+}
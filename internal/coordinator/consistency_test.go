@@ -0,0 +1,298 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/cache"
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// testNode is a cache node served in-process. stop and start take it off
+// and back onto its address, keeping its contents. health answers the
+// coordinator's heartbeats, SERVING to begin with.
+type testNode struct {
+	addr   string
+	node   *cache.CacheNode
+	health *health.Server
+	srv    *grpc.Server
+}
+
+func startTestNodes(t *testing.T, n int) []*testNode {
+	t.Helper()
+	nodes := make([]*testNode, n)
+	for i := range nodes {
+		nodes[i] = &testNode{addr: "127.0.0.1:0", node: cache.NewCacheNode(cache.Options{Capacity: 1000}), health: health.NewServer()}
+		nodes[i].health.SetServingStatus(cacheNodepb.Cache_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+		nodes[i].start(t)
+	}
+	return nodes
+}
+
+func (n *testNode) start(t *testing.T) {
+	t.Helper()
+	lis, err := net.Listen("tcp", n.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n.addr = lis.Addr().String()
+	n.srv = grpc.NewServer()
+	cacheNodepb.RegisterCacheServer(n.srv, n.node)
+	grpc_health_v1.RegisterHealthServer(n.srv, n.health)
+	go n.srv.Serve(lis)
+	t.Cleanup(n.srv.Stop)
+}
+
+func (n *testNode) stop() { n.srv.Stop() }
+
+func newTestCoordinator(t *testing.T, nodes []*testNode, cfg Config) *Coordinator {
+	t.Helper()
+	for _, n := range nodes {
+		cfg.Nodes = append(cfg.Nodes, Member{Addr: n.addr, Weight: 1})
+	}
+	cfg.Vnodes = 16
+	c, err := NewCoordinator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+// valueOn returns the value the node at addr holds for key, asking it
+// directly, and whether it holds one.
+func valueOn(c *Coordinator, addr, key string) (string, bool) {
+	n, ok := c.ring.member(addr)
+	if !ok {
+		return "", false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := n.client.Get(ctx, &cacheNodepb.GetRequest{Key: key})
+	if err != nil || !res.Found {
+		return "", false
+	}
+	return string(res.Value), true
+}
+
+func TestParseConsistency(t *testing.T) {
+	tests := []struct {
+		name string
+		want Consistency
+		err  bool
+	}{
+		{"one", One, false},
+		{"QUORUM", Quorum, false},
+		{"All", All, false},
+		{"two", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseConsistency(tt.name)
+			if (err != nil) != tt.err || got != tt.want {
+				t.Errorf("ParseConsistency(%q) = %v, %v, want %v, error %v", tt.name, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestConsistencyRequired(t *testing.T) {
+	tests := []struct {
+		replicas         int
+		one, quorum, all int
+	}{
+		{1, 1, 1, 1},
+		{2, 1, 2, 2},
+		{3, 1, 2, 3},
+		{4, 1, 3, 4},
+		{5, 1, 3, 5},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.replicas), func(t *testing.T) {
+			for level, want := range map[Consistency]int{One: tt.one, Quorum: tt.quorum, All: tt.all} {
+				if got := level.required(tt.replicas); got != want {
+					t.Errorf("%v of %d = %d, want %d", level, tt.replicas, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestConsistencyWithReplicaDown(t *testing.T) {
+	// Four nodes, three replicas per key, and the key's owner stopped. With
+	// hinted handoff the fourth node takes the owner's write, so even
+	// writes at all succeed; reads are never handed off.
+	tests := []struct {
+		level    Consistency
+		hints    bool
+		writeErr bool
+		readErr  bool
+	}{
+		{One, false, false, false},
+		{Quorum, false, false, false},
+		{All, false, true, true},
+		{Quorum, true, false, false},
+		{All, true, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v/hints=%v", tt.level, tt.hints), func(t *testing.T) {
+			nodes := startTestNodes(t, 4)
+			cfg := Config{Replicas: 3}
+			if tt.hints {
+				cfg.MaxHints = 100
+				cfg.HintReplayInterval = time.Hour // replayed by hand below
+			}
+			c := newTestCoordinator(t, nodes, cfg)
+			ctx := WithConsistency(context.Background(), tt.level)
+
+			const key = "k"
+			owner := c.ring.getNodes(key, 3)[0].addr
+			for _, n := range nodes {
+				if n.addr == owner {
+					n.stop()
+				}
+			}
+
+			err := c.Set(ctx, key, []byte("v"), 0)
+			if (err != nil) != tt.writeErr {
+				t.Fatalf("Set = %v, want error %v", err, tt.writeErr)
+			}
+			if err != nil && !errors.Is(err, ErrNodeUnavailable) {
+				t.Errorf("Set = %v, want ErrNodeUnavailable", err)
+			}
+			if tt.writeErr {
+				return
+			}
+
+			it, err := c.Get(ctx, key)
+			if (err != nil) != tt.readErr {
+				t.Fatalf("Get = %v, want error %v", err, tt.readErr)
+			}
+			if err == nil && string(it.Value) != "v" {
+				t.Errorf("Get = %q, want v", it.Value)
+			}
+		})
+	}
+}
+
+func TestHintedHandoffReplay(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(c *Coordinator, ctx context.Context, key string) error
+		want  string // the owner's value once hints are replayed, "" for none
+	}{
+		{"set", func(c *Coordinator, ctx context.Context, key string) error {
+			return c.Set(ctx, key, []byte("new"), 0)
+		}, "new"},
+		{"delete", func(c *Coordinator, ctx context.Context, key string) error {
+			_, err := c.Delete(ctx, key)
+			return err
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := startTestNodes(t, 4)
+			c := newTestCoordinator(t, nodes, Config{Replicas: 3, MaxHints: 100, HintReplayInterval: time.Hour})
+			ctx := WithConsistency(context.Background(), All)
+
+			const key = "k"
+			if err := c.Set(ctx, key, []byte("old"), 0); err != nil {
+				t.Fatal(err)
+			}
+			var down *testNode
+			owner := c.ring.getNodes(key, 3)[0].addr
+			for _, n := range nodes {
+				if n.addr == owner {
+					down = n
+				}
+			}
+			down.stop()
+
+			if err := tt.write(c, ctx, key); err != nil {
+				t.Fatal(err)
+			}
+			if got := c.hints.take(owner); len(got) != 1 {
+				t.Fatalf("hints for the owner = %v, want one for %s", got, key)
+			} else {
+				c.hints.restore(owner, key, got[key])
+			}
+
+			down.start(t)
+			// The client backs off before redialling a node it lost.
+			deadline := time.Now().Add(10 * time.Second)
+			for len(c.hints.owners()) > 0 && time.Now().Before(deadline) {
+				c.replayHints(owner)
+				time.Sleep(50 * time.Millisecond)
+			}
+			if owners := c.hints.owners(); len(owners) > 0 {
+				t.Fatalf("hints still pending for %v", owners)
+			}
+			if got, _ := valueOn(c, owner, key); got != tt.want {
+				t.Errorf("owner holds %q, want %q", got, tt.want)
+			}
+			// The stand-in no longer keeps a copy it was never a replica of.
+			for _, n := range nodes {
+				if _, ok := valueOn(c, n.addr, key); ok && !c.ring.isReplica(n.addr, key) {
+					t.Errorf("%s still holds %s after replay", n.addr, key)
+				}
+			}
+		})
+	}
+}
+
+// racingClient is a node client that has the node take another write of a
+// key right after each Get of it.
+type racingClient struct {
+	cacheNodepb.CacheClient
+	value string
+}
+
+func (c racingClient) Get(ctx context.Context, req *cacheNodepb.GetRequest, opts ...grpc.CallOption) (*cacheNodepb.GetResponse, error) {
+	res, err := c.CacheClient.Get(ctx, req, opts...)
+	if err == nil {
+		_, err = c.CacheClient.Set(ctx, &cacheNodepb.SetRequest{Key: req.Key, Value: []byte(c.value)})
+	}
+	return res, err
+}
+
+func TestHintReplayKeepsNewerWrite(t *testing.T) {
+	nodes := startTestNodes(t, 2)
+	c := newTestCoordinator(t, nodes, Config{Replicas: 1, MaxHints: 100, HintReplayInterval: time.Hour})
+	ctx := context.Background()
+
+	const key = "k"
+	owner := c.ring.getNodes(key, 1)[0].addr
+	holder := nodes[0]
+	if holder.addr == owner {
+		holder = nodes[1]
+	}
+	if _, err := holder.node.Set(ctx, &cacheNodepb.SetRequest{Key: key, Value: []byte("old")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The stand-in takes a newer write of the key while the hint is being
+	// replayed: the owner gets the value that was read, and the stand-in
+	// keeps the newer one rather than losing it.
+	from, _ := c.ring.member(holder.addr)
+	c.ring.mu.Lock()
+	c.ring.members[holder.addr] = node{addr: from.addr, conn: from.conn, client: racingClient{from.client, "newer"}}
+	c.ring.mu.Unlock()
+	to, _ := c.ring.member(owner)
+	if err := c.replayHint(ctx, to, key, hint{holder: holder.addr}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := valueOn(c, owner, key); got != "old" {
+		t.Errorf("owner holds %q, want old", got)
+	}
+	if res, err := holder.node.Get(ctx, &cacheNodepb.GetRequest{Key: key}); err != nil || string(res.Value) != "newer" {
+		t.Errorf("stand-in holds %q, %v, want newer", res.GetValue(), err)
+	}
+}
@@ -2,60 +2,722 @@ package coordinator
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
-	"github.com/sakshamg567/cachy/util"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// Member is a cache node address together with its relative share of the key
+// space. A node of weight 2 owns twice as many virtual nodes as one of
+// weight 1.
+type Member struct {
+	Addr   string
+	Weight int
+}
+
+// MaxWeight bounds a member's weight, and with it the virtual nodes placed
+// for it while the ring is locked.
+const MaxWeight = 100
+
+type Config struct {
+	Nodes    []Member
+	Replicas int // distinct nodes each key is stored on
+	Vnodes   int // virtual nodes per unit of weight
+
+	// ReadConsistency and WriteConsistency are the levels used by requests
+	// whose context does not carry one (see WithConsistency).
+	ReadConsistency  Consistency
+	WriteConsistency Consistency
+
+	// HealthInterval is how often every node is sent a gRPC health check;
+	// 0 disables checking. A node that misses DownAfter checks in a row is
+	// taken out of routing until it answers again.
+	HealthInterval time.Duration
+	HealthTimeout  time.Duration
+	DownAfter      int
+
+	// MaxHints bounds the writes handed off to another node while a replica
+	// is unavailable, to be replayed to it once it recovers; 0 disables
+	// hinted handoff. Besides on recovery, hints are replayed every
+	// HintReplayInterval (10s if 0) to replicas not known to be down.
+	MaxHints           int
+	HintReplayInterval time.Duration
+
+	// AntiEntropyInterval is how often replicas are compared and brought
+	// in line with each other; 0 disables it.
+	AntiEntropyInterval time.Duration
+
+	// Loader, if set, is read on a miss and the value it returns is cached
+	// with LoadTTL and LoadSoftTTL. Stale keys are then refreshed from it
+	// in the background instead of by a caller.
+	Loader      Loader
+	LoadTTL     time.Duration
+	LoadSoftTTL time.Duration
+
+	// WriteMode says whether writes also go to the Loader, which must then
+	// be a Writer. Backend writes are attempted WriteRetries times. Under
+	// write-behind, up to WriteBatch of them are sent together, after at
+	// most WriteDelay.
+	WriteMode    WriteMode
+	WriteRetries int
+	WriteBatch   int
+	WriteDelay   time.Duration
+
+	Logger *slog.Logger // slog.Default() if nil
+}
+
+var (
+	ErrUnknownNode = errors.New("node is not part of the ring")
+	ErrNodeExists  = errors.New("node is already part of the ring")
+	ErrInvalidNode = errors.New("invalid node")
+	ErrLastNode    = errors.New("cannot remove the last node")
+	ErrNoNodes     = errors.New("no cache nodes available")
+
+	ErrNotFound        = errors.New("key not found")
+	ErrNodeUnavailable = errors.New("cache node unavailable")
+	ErrEntryTooLarge   = errors.New("entry too large")
+	// ErrInvalidRequest is returned for a request a node refused as
+	// malformed, such as a write with an empty key.
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrConflict is returned by a conditional write whose key has moved
+	// on from the expected version, or is already stored.
+	ErrConflict = errors.New("version conflict")
+	// ErrNotNumeric is returned by a counter operation on a value that is
+	// not a decimal number, and ErrOverflow by one whose result would not
+	// fit the counter.
+	ErrNotNumeric = errors.New("value is not a number")
+	ErrOverflow   = errors.New("counter out of range")
+)
+
+// nodeError classifies an RPC failure against addr so callers can tell an
+// unreachable node from a request the node refused.
+func nodeError(addr string, err error) error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return fmt.Errorf("%w: %s: %v", ErrNodeUnavailable, addr, status.Convert(err).Message())
+	case codes.FailedPrecondition:
+		return ErrNotNumeric
+	case codes.OutOfRange:
+		return ErrOverflow
+	case codes.ResourceExhausted:
+		return fmt.Errorf("%w: %s", ErrEntryTooLarge, status.Convert(err).Message())
+	case codes.InvalidArgument:
+		return fmt.Errorf("%w: %s", ErrInvalidRequest, status.Convert(err).Message())
+	}
+	return fmt.Errorf("%s: %w", addr, err)
+}
+
+// Item is a value read from the cache.
+type Item struct {
+	Value []byte
+	Flags uint32        // opaque client flags stored with the value
+	TTL   time.Duration // remaining time to live, 0 if the key never expires
+
+	// Stale is set once the value is past its soft ttl. Revalidate is set
+	// for the one caller expected to refresh it; everyone else keeps being
+	// served the stale value meanwhile.
+	Stale      bool
+	Revalidate bool
+
+	// Version is the write timestamp of the value in unix nanoseconds.
+	Version int64
+}
+
+// TTLSeconds is TTL rounded up to whole seconds, the unit the HTTP API and
+// the memcached front-end report it in; 0 if the key never expires.
+func (it Item) TTLSeconds() int64 {
+	return int64((it.TTL + time.Second - 1) / time.Second)
+}
+
 type Coordinator struct {
 	ring *HashRing
+	log  *slog.Logger
+
+	readConsistency  Consistency
+	writeConsistency Consistency
+
+	reads singleflight.Group // concurrent Gets of a key share one lookup
+
+	loader       Loader
+	loadTTL      time.Duration
+	loadSoftTTL  time.Duration
+	writer       Writer
+	writeMode    WriteMode
+	writeRetries int
+	behind       *writeBehind // set under write-behind
+
+	hints *hintStore // nil without hinted handoff
 }
 
-func NewCoordinator(addresses []string) *Coordinator {
+// NewCoordinator builds a coordinator over the nodes in cfg and starts its
+// background work. It fails if a write mode is set without a loader that
+// accepts writes.
+func NewCoordinator(cfg Config) (*Coordinator, error) {
+	var writer Writer
+	if cfg.WriteMode != "" && cfg.WriteMode != WriteNone {
+		w, ok := cfg.Loader.(Writer)
+		if !ok {
+			return nil, fmt.Errorf("write mode %q needs a loader that accepts writes", cfg.WriteMode)
+		}
+		writer = w
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
 
-	ring := NewHashRing(addresses)
+	ring := NewHashRing(cfg.Nodes, cfg.Replicas, cfg.Vnodes)
+	ring.log = logger
 
-	return &Coordinator{
-		ring: ring,
+	c := &Coordinator{
+		ring:             ring,
+		log:              logger,
+		readConsistency:  cfg.ReadConsistency,
+		writeConsistency: cfg.WriteConsistency,
+		loader:           cfg.Loader,
+		loadTTL:          cfg.LoadTTL,
+		loadSoftTTL:      cfg.LoadSoftTTL,
+		writeMode:        WriteNone,
+		writeRetries:     cfg.WriteRetries,
+	}
+	if writer != nil {
+		c.writer, c.writeMode = writer, cfg.WriteMode
+		if c.writeMode == WriteBehind {
+			c.behind = newWriteBehind(writer, logger, cfg.WriteBatch, cfg.WriteDelay, cfg.WriteRetries)
+		}
+	}
+	if cfg.MaxHints > 0 {
+		c.hints = newHintStore(cfg.MaxHints)
+		ring.recovered = c.replayHints
+		c.startHintReplay(cfg.HintReplayInterval)
+	}
+	ring.startHealthChecks(cfg.HealthInterval, cfg.HealthTimeout, cfg.DownAfter)
+	c.startAntiEntropy(cfg.AntiEntropyInterval)
+	return c, nil
+}
+
+// Close flushes writes still queued for the backend, then stops health
+// checks and closes the connections to the nodes. The coordinator must not
+// be used afterwards.
+func (c *Coordinator) Close() {
+	if c.behind != nil {
+		c.behind.close()
+	}
+	c.ring.close()
+}
+
+// sharedRead is the result of a lookup shared by concurrent Gets. Only one
+// of them may take on a revalidation.
+type sharedRead struct {
+	item    Item
+	claimed atomic.Bool
+}
+
+// Get returns the item stored under key, or ErrNotFound. How many replicas
+// are asked depends on the read consistency level. Concurrent Gets of the
+// same key and level are coalesced into a single lookup, so a hot key that
+// has just expired does not send every caller to the nodes at once. With a
+// loader, a miss is read through it. A caller whose ctx is done before the
+// lookup finishes gets ctx's error; the lookup carries on for the others.
+func (c *Coordinator) Get(ctx context.Context, key string) (Item, error) {
+	level := c.readLevel(ctx)
+	flight := key
+	if level != One {
+		flight = level.String() + "\x00" + key
+	}
+	ch := c.reads.DoChan(flight, func() (any, error) {
+		// The lookup is shared, so it must outlive the caller that happened
+		// to start it, but not its deadline.
+		lctx, cancel := detach(ctx)
+		defer cancel()
+		var (
+			it  Item
+			err error
+		)
+		if level == One {
+			it, err = c.get(lctx, key)
+		} else {
+			it, err = c.getQuorum(lctx, key, level)
+		}
+		if errors.Is(err, ErrNotFound) && c.loader != nil {
+			it, err = c.load(lctx, key)
+		}
+		return &sharedRead{item: it}, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return Item{}, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return Item{}, res.Err
+		}
+		r := res.Val.(*sharedRead)
+		it := r.item
+		it.Revalidate = it.Revalidate && r.claimed.CompareAndSwap(false, true)
+		if it.Revalidate && c.loader != nil {
+			it.Revalidate = false
+			go c.refresh(key)
+		}
+		return it, nil
 	}
 }
 
-func (c *Coordinator) Get(ctx context.Context, key string) (string, error) {
+// get looks key up on the owner first; replicas are only consulted when the
+// nodes before them are unreachable.
+func (c *Coordinator) get(ctx context.Context, key string) (Item, error) {
+	nodes := c.ring.getNodes(key, c.ring.replicas)
+	if len(nodes) == 0 {
+		return Item{}, ErrNoNodes
+	}
+
+	var lastErr error
+	for _, n := range nodes {
+		val, err := n.client.Get(ctx, &cacheNodepb.GetRequest{Key: key})
+		if err != nil {
+			c.log.Warn("get failed", "key", key, "node", n.addr, "err", err)
+			lastErr = nodeError(n.addr, err)
+			continue
+		}
+		if !val.Found {
+			return Item{}, ErrNotFound
+		}
+		return itemFrom(val), nil
+	}
+	return Item{}, lastErr
+}
 
-	n := c.ring.getNode(key)
+// getQuorum asks every replica of key in parallel and, once as many as level
+// requires have answered, returns the newest value among their answers.
+// Once every replica has answered, those behind the newest are repaired.
+func (c *Coordinator) getQuorum(ctx context.Context, key string, level Consistency) (Item, error) {
+	nodes := c.ring.getNodes(key, c.ring.replicas)
+	need := level.required(c.ring.replicas)
+	if len(nodes) < need {
+		return Item{}, tooFewReplicas(len(nodes), need)
+	}
 
-	val, err := n.client.Get(ctx, &cacheNodepb.GetRequest{Key: key})
+	var (
+		mu      sync.Mutex
+		newest  *cacheNodepb.GetResponse
+		answers []replicaAnswer
+		wg      sync.WaitGroup
+	)
+	wg.Add(len(nodes))
+	err := c.quorum(ctx, nodes, need, func(ctx context.Context, n node) error {
+		defer wg.Done()
+		res, err := n.client.Get(ctx, &cacheNodepb.GetRequest{Key: key})
+		if err != nil {
+			c.log.Warn("get failed", "key", key, "node", n.addr, "err", err)
+			return err
+		}
+		mu.Lock()
+		answers = append(answers, replicaAnswer{n, res})
+		if newer(res, newest) {
+			newest = res
+		}
+		mu.Unlock()
+		return nil
+	})
+	go func() {
+		wg.Wait()
+		c.readRepair(key, answers)
+	}()
 	if err != nil {
-		return "", err
+		return Item{}, err
 	}
 
-	return val.Value, nil
+	mu.Lock()
+	defer mu.Unlock()
+	if newest == nil || !newest.Found {
+		return Item{}, ErrNotFound
+	}
+	return itemFrom(newest), nil
+}
+
+func itemFrom(res *cacheNodepb.GetResponse) Item {
+	return Item{
+		Value:      res.Value,
+		Flags:      res.Flags,
+		TTL:        time.Duration(res.TtlMs) * time.Millisecond,
+		Stale:      res.Stale,
+		Revalidate: res.Revalidate,
+		Version:    res.Version,
+	}
 }
 
-func (c *Coordinator) Set(ctx context.Context, key, value string) bool {
-	n := c.ring.getNode(key)
+// Exists reports whether key is stored, without fetching its value or
+// counting as a use of it for eviction.
+func (c *Coordinator) Exists(ctx context.Context, key string) (bool, error) {
+	nodes := c.ring.getNodes(key, c.ring.replicas)
+	if len(nodes) == 0 {
+		return false, ErrNoNodes
+	}
+
+	var lastErr error
+	for _, n := range nodes {
+		res, err := n.client.Exists(ctx, &cacheNodepb.ExistsRequest{Key: key})
+		if err != nil {
+			c.log.Warn("exists failed", "key", key, "node", n.addr, "err", err)
+			lastErr = nodeError(n.addr, err)
+			continue
+		}
+		return res.Found, nil
+	}
+	return false, lastErr
+}
 
-	_, err := n.client.Set(ctx, &cacheNodepb.SetRequest{Key: key, Value: value})
-	return err == nil
+// Set stores value under key on the owner and its replicas in parallel. A ttl
+// of 0 means the key never expires. Set returns once as many nodes as the
+// write consistency level requires have accepted the write, or with the
+// first failure once that can no longer happen. The remaining replicas are
+// still written in the background. With hinted handoff, writes for a
+// replica that is unavailable go to the next healthy node on the ring and
+// are replayed to it once it recovers.
+func (c *Coordinator) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.SetEntry(ctx, Entry{Key: key, Value: value, TTL: ttl})
 }
 
-func (c *Coordinator) AddNode(addr string) {
-	c.ring.addNode(addr)
+// SetEntry is Set for an entry that also carries client flags. Depending on
+// the write mode the value is also written to the backend. An entry without
+// a version is stamped with the current time.
+func (c *Coordinator) SetEntry(ctx context.Context, e Entry) error {
+	e.stamp(time.Now())
+	if err := c.writeBackend(ctx, []Entry{e}); err != nil {
+		return err
+	}
+	return c.setCache(ctx, e)
+}
+
+// setCache writes e to the ring only.
+func (c *Coordinator) setCache(ctx context.Context, e Entry) error {
+	key := e.Key
+	targets, spares := c.ring.writeTargets(key)
+	if len(targets) == 0 {
+		return ErrNoNodes
+	}
+	need := c.writeLevel(ctx).required(c.ring.replicas)
+	if len(targets) < need {
+		return tooFewReplicas(len(targets), need)
+	}
+	e.stamp(time.Now())
+	req := e.request()
+
+	return c.writeReplicas(ctx, key, targets, spares, need, hint{}, func(ctx context.Context, n node) error {
+		_, err := n.client.Set(ctx, req)
+		if err != nil {
+			c.log.Warn("set failed", "key", key, "node", n.addr, "err", err)
+		}
+		return err
+	})
+}
 
+// Delete removes key from the owner and its replicas, and from the backend
+// depending on the write mode. It reports whether any node held the key.
+func (c *Coordinator) Delete(ctx context.Context, key string) (bool, error) {
+	if err := c.deleteBackend(ctx, []string{key}); err != nil {
+		return false, err
+	}
+	return c.deleteCache(ctx, key)
+}
+
+// deleteCache removes key from the ring only. Every replica is sent the same
+// delete version, which its tombstone keeps so that read repair and
+// anti-entropy spread the delete instead of the value it removed.
+func (c *Coordinator) deleteCache(ctx context.Context, key string) (bool, error) {
+	targets, spares := c.ring.writeTargets(key)
+	if len(targets) == 0 {
+		return false, ErrNoNodes
+	}
+	need := c.writeLevel(ctx).required(c.ring.replicas)
+	if len(targets) < need {
+		return false, tooFewReplicas(len(targets), need)
+	}
+	req := &cacheNodepb.DeleteRequest{Key: key, Version: time.Now().UnixNano()}
+
+	var found atomic.Bool
+	err := c.writeReplicas(ctx, key, targets, spares, need, hint{delete: true, version: req.Version}, func(ctx context.Context, n node) error {
+		res, err := n.client.Delete(ctx, req)
+		if err != nil {
+			c.log.Warn("delete failed", "key", key, "node", n.addr, "err", err)
+			return err
+		}
+		if res.Success {
+			found.Store(true)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return found.Load(), nil
+}
+
+// quorum calls fn for every node in parallel and returns nil as soon as
+// need of them have succeeded, or the first failure, classified by
+// nodeError, once too many have failed for that to happen. Calls still in
+// flight carry on in the background, bounded by ctx's deadline but not
+// cancelled with it.
+func (c *Coordinator) quorum(ctx context.Context, nodes []node, need int, fn func(ctx context.Context, n node) error) error {
+	bctx, cancel := detach(ctx)
+	results := make(chan error, len(nodes))
+	var wg sync.WaitGroup
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n node) {
+			defer wg.Done()
+			err := fn(bctx, n)
+			if err != nil {
+				err = nodeError(n.addr, err)
+			}
+			results <- err
+		}(n)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+	}()
+
+	var acks, failures int
+	var first error
+	for range nodes {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", ErrNodeUnavailable, ctx.Err())
+		case err := <-results:
+			if err == nil {
+				if acks++; acks == need {
+					return nil
+				}
+				continue
+			}
+			if first == nil {
+				first = err
+			}
+			if failures++; failures > len(nodes)-need {
+				return first
+			}
+		}
+	}
+	return first
+}
+
+// writeReplicas calls write for every target as quorum does. A write to a
+// node standing in for a replica that is down is hinted for that replica
+// with h, its holder filled in.
+// With hinted handoff, a write that fails because its node cannot be
+// reached is retried on the next spare node and hinted for it too.
+func (c *Coordinator) writeReplicas(ctx context.Context, key string, targets []writeTarget, spares []node, need int, h hint, write func(ctx context.Context, n node) error) error {
+	nodes := make([]node, len(targets))
+	owners := make(map[string]string, len(targets))
+	for i, t := range targets {
+		nodes[i] = t.node
+		owners[t.addr] = t.owner
+	}
+
+	var mu sync.Mutex
+	return c.quorum(ctx, nodes, need, func(ctx context.Context, n node) error {
+		owner := owners[n.addr]
+		err := write(ctx, n)
+		if err != nil && c.hints != nil && status.Code(err) == codes.Unavailable {
+			mu.Lock()
+			if len(spares) == 0 {
+				mu.Unlock()
+				return err
+			}
+			spare := spares[0]
+			spares = spares[1:]
+			mu.Unlock()
+
+			if serr := write(ctx, spare); serr != nil {
+				return err
+			}
+			if owner == "" {
+				owner = n.addr
+			}
+			n, err = spare, nil
+		}
+		if err == nil && owner != "" {
+			h := h
+			h.holder = n.addr
+			c.hint(owner, key, h)
+		}
+		return err
+	})
+}
+
+// detach returns a context that keeps ctx's deadline and values but is not
+// cancelled with it, for work shared with or outliving the caller.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	dctx := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(dctx, deadline)
+	}
+	return context.WithCancel(dctx)
+}
+
+// AddNode places addr on the ring with the given weight and, for each of
+// its virtual nodes, pulls over the range it is now a replica of from the
+// node that already holds it. It fails with ErrInvalidNode for an address
+// that is not host:port or a weight outside 1..MaxWeight, and with
+// ErrNodeExists for a member.
+func (c *Coordinator) AddNode(addr string, weight int) error {
+	if err := checkAddr(addr); err != nil {
+		return err
+	}
+	if weight < 1 || weight > MaxWeight {
+		return fmt.Errorf("%w: weight %d outside 1..%d", ErrInvalidNode, weight, MaxWeight)
+	}
+	if err := c.ring.addNode(addr, weight); err != nil {
+		return err
+	}
+
+	sources := make(map[string][]keyRange)
+	stale := make(map[string][]keyRange)
+	nodes := make(map[string]node)
+	for _, h := range c.ring.handoffs(addr) {
+		if h.first.client == nil {
+			continue
+		}
+		sources[h.first.addr] = append(sources[h.first.addr], h.keyRange)
+		nodes[h.first.addr] = h.first
+		if h.last.client != nil && h.last.addr != h.first.addr {
+			stale[h.last.addr] = append(stale[h.last.addr], h.keyRange)
+			nodes[h.last.addr] = h.last
+		}
+	}
+
+	c.ring.mu.RLock()
+	to := c.ring.members[addr]
+	c.ring.mu.RUnlock()
+
+	go func() {
+		for from, ranges := range sources {
+			if err := c.ring.migrateData(nodes[from], to, ranges); err != nil {
+				c.log.Error("migration failed", "from", from, "to", addr, "err", err)
+			}
+		}
+		// Replicas pushed out of a range by the new node drop their copies.
+		for last, ranges := range stale {
+			if err := c.ring.dropStale(nodes[last], ranges); err != nil {
+				c.log.Error("dropping stale keys failed", "node", last, "err", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// checkAddr reports whether addr is a host:port a node could be dialed at.
+func checkAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidNode, err)
+	}
+	if port == "" {
+		return fmt.Errorf("%w: address %q has no port", ErrInvalidNode, addr)
+	}
+	return nil
+}
+
+// DrainNode hands every range addr is a replica of over to the node that
+// takes its place in the replica set, then drops addr from the ring. It
+// blocks until the data has been copied.
+func (c *Coordinator) DrainNode(addr string) error {
 	c.ring.mu.RLock()
-	h := util.Hash(addr)
-	idx := sort.Search(len(c.ring.keys), func(i int) bool { return c.ring.keys[i] >= h })
-	if idx == len(c.ring.keys) {
-		idx = 0
+	from, ok := c.ring.members[addr]
+	members := len(c.ring.members)
+	c.ring.mu.RUnlock()
+	if !ok {
+		return ErrUnknownNode
+	}
+	if members == 1 {
+		return ErrLastNode
+	}
+
+	targets := make(map[string][]keyRange)
+	nodes := make(map[string]node)
+	for _, h := range c.ring.handoffs(addr) {
+		// With no last node every other node already holds the range.
+		if h.last.client == nil {
+			continue
+		}
+		targets[h.last.addr] = append(targets[h.last.addr], h.keyRange)
+		nodes[h.last.addr] = h.last
 	}
-	successorAddr := c.ring.nodes[c.ring.keys[idx]].addr
-	predIdx := idx - 1
-	if predIdx < 0 {
-		predIdx = len(c.ring.keys) - 1
+
+	for to, ranges := range targets {
+		if err := c.ring.migrateData(from, nodes[to], ranges); err != nil {
+			return fmt.Errorf("handing off to %s: %w", to, err)
+		}
+	}
+
+	c.ring.removeNode(addr)
+	c.forgetHints(addr)
+	c.log.Info("drained and removed node", "node", addr)
+	return nil
+}
+
+// RemoveNode drops addr from the ring straight away without moving its data,
+// for nodes that are already gone. Keys they were the only replica of are
+// lost.
+func (c *Coordinator) RemoveNode(addr string) error {
+	c.ring.mu.RLock()
+	_, ok := c.ring.members[addr]
+	members := len(c.ring.members)
+	c.ring.mu.RUnlock()
+	if !ok {
+		return ErrUnknownNode
+	}
+	if members == 1 {
+		return ErrLastNode
+	}
+
+	c.ring.removeNode(addr)
+	c.forgetHints(addr)
+	c.log.Info("removed node", "node", addr)
+	return nil
+}
+
+// SetMembers makes the ring consist of exactly members, placing and dropping
+// nodes without moving any data. It is meant for a ring that mirrors one
+// managed elsewhere, as the client library's does; use AddNode and
+// DrainNode to change the cluster itself.
+func (c *Coordinator) SetMembers(members []Member) {
+	c.ring.setMembers(members)
+}
+
+// Topology describes how keys are placed on the ring, so that clients can
+// route to the nodes themselves.
+type Topology struct {
+	Replicas int
+	Vnodes   int
+	Nodes    []NodeStatus
+}
+
+func (c *Coordinator) Topology() Topology {
+	return Topology{Replicas: c.ring.replicas, Vnodes: c.ring.vnodes, Nodes: c.Nodes()}
+}
+
+// Nodes reports the health of every node on the ring, sorted by address.
+func (c *Coordinator) Nodes() []NodeStatus {
+	c.ring.mu.RLock()
+	out := make([]NodeStatus, 0, len(c.ring.status))
+	for _, st := range c.ring.status {
+		out = append(out, *st)
 	}
-	predecessorHash := c.ring.keys[predIdx]
 	c.ring.mu.RUnlock()
 
-	go c.ring.migrateData(successorAddr, addr, predecessorHash)
+	sort.Slice(out, func(i, j int) bool { return out[i].Addr < out[j].Addr })
+	return out
 }
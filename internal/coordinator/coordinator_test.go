@@ -0,0 +1,141 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gateLoader counts its loads and holds each one until release is closed.
+type gateLoader struct {
+	loads   atomic.Int32
+	started chan struct{} // receives once per load
+	release chan struct{}
+}
+
+func newGateLoader() *gateLoader {
+	return &gateLoader{started: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (l *gateLoader) Load(ctx context.Context, key string) ([]byte, error) {
+	l.loads.Add(1)
+	l.started <- struct{}{}
+	<-l.release
+	return []byte("loaded"), nil
+}
+
+func TestGetCoalesces(t *testing.T) {
+	nodes := startTestNodes(t, 1)
+	l := newGateLoader()
+	c := newTestCoordinator(t, nodes, Config{Replicas: 1, Loader: l})
+
+	// Every Get of the missing key waits on the same load.
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			it, err := c.Get(context.Background(), "k")
+			if err == nil && string(it.Value) != "loaded" {
+				err = errors.New("got " + string(it.Value))
+			}
+			errs <- err
+		}()
+	}
+	<-l.started
+	time.Sleep(50 * time.Millisecond) // let the other callers join the flight
+	close(l.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if n := l.loads.Load(); n != 1 {
+		t.Errorf("%d loads for %d concurrent Gets, want 1", n, callers)
+	}
+}
+
+func TestGetCallerGivesUp(t *testing.T) {
+	nodes := startTestNodes(t, 1)
+	l := newGateLoader()
+	c := newTestCoordinator(t, nodes, Config{Replicas: 1, Loader: l})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Get(context.Background(), "k")
+		done <- err
+	}()
+	<-l.started
+
+	// A caller that gives up gets its own context's error, not a node
+	// failure, and the shared lookup carries on without it.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrNodeUnavailable) {
+		t.Errorf("Get past the caller's deadline = %v, want %v", err, context.DeadlineExceeded)
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Get(canceled, "k"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get with a cancelled context = %v, want %v", err, context.Canceled)
+	}
+
+	close(l.release)
+	if err := <-done; err != nil {
+		t.Errorf("the caller that waited got %v", err)
+	}
+	if n := l.loads.Load(); n != 1 {
+		t.Errorf("%d loads, want 1", n)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	nodes := startTestNodes(t, 1)
+	c := newTestCoordinator(t, nodes, Config{Replicas: 1})
+	ctx := context.Background()
+
+	if err := c.SetEntry(ctx, Entry{Key: "k", Value: []byte("v"), SoftTTL: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// Past the soft ttl every reader still gets the value, marked stale,
+	// and only the first is asked to refresh it.
+	const callers = 10
+	var (
+		wg          sync.WaitGroup
+		revalidates atomic.Int32
+	)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			it, err := c.Get(ctx, "k")
+			if err != nil || string(it.Value) != "v" || !it.Stale {
+				t.Errorf("Get = %q stale %v, %v", it.Value, it.Stale, err)
+			}
+			if it.Revalidate {
+				revalidates.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := revalidates.Load(); n != 1 {
+		t.Errorf("%d of %d readers asked to revalidate, want 1", n, callers)
+	}
+
+	// The refreshed value is fresh again.
+	if err := c.Set(ctx, "k", []byte("new"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if it, err := c.Get(ctx, "k"); err != nil || string(it.Value) != "new" || it.Stale || it.Revalidate {
+		t.Errorf("Get after refresh = %q stale %v revalidate %v, %v", it.Value, it.Stale, it.Revalidate, err)
+	}
+}
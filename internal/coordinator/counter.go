@@ -0,0 +1,112 @@
+package coordinator
+
+import (
+	"context"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/logging"
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+)
+
+// Overflow says how an integer counter behaves at the ends of its range.
+type Overflow int
+
+const (
+	// OverflowFail keeps counters signed 64-bit integers and refuses a
+	// change past either end with ErrOverflow.
+	OverflowFail Overflow = iota
+	// OverflowUnsigned keeps counters unsigned 64-bit integers that wrap
+	// around on increment and stop at zero on decrement, as memcached's do.
+	OverflowUnsigned
+)
+
+// CounterOptions control Incr and Decr.
+type CounterOptions struct {
+	Overflow Overflow
+	// Create makes a missing key count as Initial instead of failing with
+	// ErrNotFound. The key is created to expire after TTL, 0 for never; a
+	// key that exists keeps its own expiry.
+	Create  bool
+	Initial int64
+	TTL     time.Duration
+}
+
+// FloatCounterOptions control IncrByFloat, as CounterOptions do Incr.
+type FloatCounterOptions struct {
+	Create  bool
+	Initial float64
+	TTL     time.Duration
+}
+
+func (o CounterOptions) request(key string, delta uint64) *cacheNodepb.IncrRequest {
+	overflow := cacheNodepb.Overflow_OVERFLOW_FAIL
+	if o.Overflow == OverflowUnsigned {
+		overflow = cacheNodepb.Overflow_OVERFLOW_UNSIGNED
+	}
+	return &cacheNodepb.IncrRequest{Key: key, Delta: delta, Overflow: overflow, Create: o.Create, Initial: o.Initial, TtlMs: o.TTL.Milliseconds()}
+}
+
+// Incr atomically adds delta to the decimal integer stored under key and
+// returns the new value. It fails with ErrNotNumeric if the value is not
+// one, and with ErrOverflow if the result does not fit.
+func (c *Coordinator) Incr(ctx context.Context, key string, delta uint64, opts CounterOptions) (Item, error) {
+	return c.counter(ctx, key, func(ctx context.Context, n node) (*cacheNodepb.CounterResponse, error) {
+		return n.client.Incr(ctx, opts.request(key, delta))
+	})
+}
+
+// Decr is Incr subtracting delta.
+func (c *Coordinator) Decr(ctx context.Context, key string, delta uint64, opts CounterOptions) (Item, error) {
+	return c.counter(ctx, key, func(ctx context.Context, n node) (*cacheNodepb.CounterResponse, error) {
+		return n.client.Decr(ctx, opts.request(key, delta))
+	})
+}
+
+// IncrByFloat atomically adds delta, which may be negative, to the decimal
+// number stored under key and returns the new value. It fails with
+// ErrNotNumeric if the value is not one, and with ErrOverflow if the
+// result is not finite.
+func (c *Coordinator) IncrByFloat(ctx context.Context, key string, delta float64, opts FloatCounterOptions) (Item, error) {
+	return c.counter(ctx, key, func(ctx context.Context, n node) (*cacheNodepb.CounterResponse, error) {
+		return n.client.IncrByFloat(ctx, &cacheNodepb.IncrByFloatRequest{Key: key, Delta: delta, Create: opts.Create, Initial: opts.Initial, TtlMs: opts.TTL.Milliseconds()})
+	})
+}
+
+// counter runs a counter operation. Like a conditional write, it is applied
+// by the first node key is written to, under that node's lock, and the
+// result is propagated from there.
+func (c *Coordinator) counter(ctx context.Context, key string, call func(ctx context.Context, n node) (*cacheNodepb.CounterResponse, error)) (Item, error) {
+	targets, spares := c.ring.writeTargets(key)
+	if len(targets) == 0 {
+		return Item{}, ErrNoNodes
+	}
+	need := c.writeLevel(ctx).required(c.ring.replicas)
+	if len(targets) < need {
+		return Item{}, tooFewReplicas(len(targets), need)
+	}
+
+	first := targets[0]
+	cres, err := call(ctx, first.node)
+	if err != nil {
+		c.log.Warn("counter update failed", "key", key, "node", first.addr, "err", err)
+		return Item{}, nodeError(first.addr, err)
+	}
+	res := cres.Item
+	if !res.GetFound() {
+		return Item{}, ErrNotFound
+	}
+	c.log.Debug("counter updated", "key", key, "node", first.addr, logging.Value(res.Value), "version", res.Version)
+
+	e := Entry{
+		Key:     key,
+		Value:   res.Value,
+		Flags:   res.Flags,
+		TTL:     time.Duration(res.TtlMs) * time.Millisecond,
+		SoftTTL: time.Duration(res.SoftTtlMs) * time.Millisecond,
+		Version: res.Version,
+	}
+	if err := c.propagate(ctx, e, cres.Previous, targets, spares, need); err != nil {
+		return Item{}, err
+	}
+	return itemFrom(res), nil
+}
@@ -2,8 +2,11 @@ package coordinator
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
@@ -13,142 +16,463 @@ import (
 
 type node struct {
 	addr   string
+	conn   *grpc.ClientConn
 	client cacheNodepb.CacheClient
 }
 
-type HashRing struct {
-	nodes map[uint32]node
-	keys  []uint32
-	mu    sync.RWMutex
+// keyRange is the half-open ring range (lo, hi].
+type keyRange struct {
+	lo, hi uint32
 }
 
-func NewHashRing(addresses []string) *HashRing {
-	n := make(map[uint32]node)
-	var keys []uint32
+// handoff is a range addr is a replica of, together with the first and last
+// of the other nodes sharing it. When addr joins, first already holds the
+// range and last drops out of it; when addr leaves, last takes its place.
+type handoff struct {
+	keyRange
+	first, last node
+}
 
-	for _, addr := range addresses {
-		h := util.Hash(addr)
-		conn, err := grpc.Dial(addr, grpc.WithInsecure())
-		if err != nil {
-			panic(err)
-		}
-		client := cacheNodepb.NewCacheClient(conn)
-		n[h] = node{
-			addr:   addr,
-			client: client,
-		}
-		keys = append(keys, h)
+type HashRing struct {
+	nodes    map[uint32]node // virtual node hash -> physical node
+	keys     []uint32
+	members  map[string]node // physical nodes by address
+	weights  map[string]int
+	status   map[string]*NodeStatus
+	vnodes   int // virtual nodes per unit of weight
+	replicas int // number of distinct nodes each key is stored on
+	log      *slog.Logger
+	stop     chan struct{} // closed to stop health checks
+	// recovered, if set, is called in its own goroutine when a node that
+	// was suspect or down answers a health check again.
+	recovered func(addr string)
+	mu        sync.RWMutex
+}
+
+// NewHashRing places every member on the ring at vnodes*weight points and
+// stores each key on replicas distinct members.
+func NewHashRing(members []Member, replicas, vnodes int) *HashRing {
+	if replicas < 1 {
+		replicas = 1
+	}
+	if vnodes < 1 {
+		vnodes = 1
 	}
 
-	sort.Slice(keys, func(i, j int) bool {
-		return keys[i] < keys[j]
-	})
-	return &HashRing{
-		nodes: n,
-		keys:  keys,
+	r := &HashRing{
+		nodes:    make(map[uint32]node),
+		members:  make(map[string]node),
+		weights:  make(map[string]int),
+		status:   make(map[string]*NodeStatus),
+		vnodes:   vnodes,
+		replicas: replicas,
+		log:      slog.Default(),
+		stop:     make(chan struct{}),
 	}
+	for _, m := range members {
+		if err := r.place(m.Addr, m.Weight); err != nil {
+			r.log.Error("node left out of the ring", "node", m.Addr, "err", err)
+		}
+	}
+	r.sortKeys()
+	return r
 }
 
-func (r *HashRing) addNode(addr string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	h := util.Hash(addr)
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+// place dials addr and adds its virtual nodes to the ring without sorting
+// it. Weights are clamped to 1..MaxWeight. It must be called with r.mu
+// held.
+func (r *HashRing) place(addr string, weight int) error {
+	weight = min(max(weight, 1), MaxWeight)
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithUnaryInterceptor(observeNode(addr)))
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("dial %s: %w", addr, err)
 	}
-	client := cacheNodepb.NewCacheClient(conn)
-	r.nodes[h] = node{
+	n := node{
 		addr:   addr,
-		client: client,
+		conn:   conn,
+		client: cacheNodepb.NewCacheClient(conn),
+	}
+	r.members[addr] = n
+	r.weights[addr] = weight
+	r.status[addr] = &NodeStatus{Addr: addr, Weight: weight, State: NodeAlive}
+
+	for i := 0; i < r.vnodes*weight; i++ {
+		h := util.VnodeHash(addr, i)
+		if _, taken := r.nodes[h]; taken {
+			continue
+		}
+		r.nodes[h] = n
+		r.keys = append(r.keys, h)
 	}
-	r.keys = append(r.keys, h)
+	return nil
+}
+
+func (r *HashRing) sortKeys() {
 	sort.Slice(r.keys, func(i, j int) bool {
 		return r.keys[i] < r.keys[j]
 	})
 }
 
+func (r *HashRing) addNode(addr string, weight int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.members[addr]; ok {
+		return ErrNodeExists
+	}
+	if err := r.place(addr, weight); err != nil {
+		return err
+	}
+	r.sortKeys()
+	return nil
+}
+
+// removeNode drops addr's virtual nodes from the ring and closes its
+// connection.
 func (r *HashRing) removeNode(addr string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	h := util.Hash(addr)
-	delete(r.nodes, h)
-	for i, key := range r.keys {
-		if key == h {
-			r.keys = append(r.keys[:i], r.keys[i+1:]...)
-			break
+	r.drop(addr)
+}
+
+// setMembers places and drops nodes until the ring holds exactly members.
+// A member whose weight changed is placed again.
+func (r *HashRing) setMembers(members []Member) {
+	want := make(map[string]int, len(members))
+	for _, m := range members {
+		want[m.Addr] = min(max(m.Weight, 1), MaxWeight)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for addr, weight := range r.weights {
+		if w, ok := want[addr]; !ok || w != weight {
+			r.drop(addr)
 		}
 	}
+	for addr, weight := range want {
+		if _, ok := r.members[addr]; !ok {
+			if err := r.place(addr, weight); err != nil {
+				r.log.Error("node left out of the ring", "node", addr, "err", err)
+			}
+		}
+	}
+	r.sortKeys()
+}
+
+// close stops health checks and closes every connection.
+func (r *HashRing) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	close(r.stop)
+	for _, n := range r.members {
+		n.conn.Close()
+	}
+}
+
+// drop removes addr's virtual nodes and closes its connection. It must be
+// called with r.mu held.
+func (r *HashRing) drop(addr string) {
+	n, ok := r.members[addr]
+	if !ok {
+		return
+	}
+	keys := r.keys[:0]
+	for _, h := range r.keys {
+		if r.nodes[h].addr == addr {
+			delete(r.nodes, h)
+			continue
+		}
+		keys = append(keys, h)
+	}
+	r.keys = keys
+	delete(r.members, addr)
+	delete(r.weights, addr)
+	delete(r.status, addr)
+	n.conn.Close()
+	forgetNode(addr)
+}
+
+func inRanges(h uint32, ranges []keyRange) bool {
+	for _, kr := range ranges {
+		if util.InRange(h, kr.lo, kr.hi) {
+			return true
+		}
+	}
+	return false
 }
 
-func (r *HashRing) migrateData(addrFrom, addrTo string, predHash uint32) error {
+// handoffs returns every range addr is a replica of, one per gap between
+// ring points. For each of addr's virtual nodes it walks counter-clockwise
+// until either another of addr's points is reached or enough distinct
+// nodes have been passed that addr no longer makes the replica set.
+func (r *HashRing) handoffs(addr string) []handoff {
 	r.mu.RLock()
-	hFrom := util.Hash(addrFrom)
-	hTo := util.Hash(addrTo)
+	defer r.mu.RUnlock()
 
-	nodeFrom := r.nodes[hFrom]
-	nodeTo := r.nodes[hTo]
-	r.mu.RUnlock()
+	n := len(r.keys)
+	var out []handoff
+	for idx, v := range r.keys {
+		if r.nodes[v].addr != addr {
+			continue
+		}
+		hi := v
+		seen := make(map[string]bool)
+		for step := 1; step <= n; step++ {
+			p := r.keys[((idx-step)%n+n)%n]
+			h := handoff{keyRange: keyRange{lo: p, hi: hi}}
+			others := r.successors(hi, r.replicas, func(a string) bool { return a == addr })
+			if len(others) > 0 {
+				h.first = others[0]
+			}
+			if len(others) == r.replicas {
+				h.last = others[len(others)-1]
+			}
+			out = append(out, h)
 
-	keys, err := nodeFrom.client.GetAllKeys(context.Background(), &cacheNodepb.GetAllKeysRequest{})
+			a := r.nodes[p].addr
+			if a == addr {
+				break
+			}
+			seen[a] = true
+			if len(seen) == r.replicas {
+				break
+			}
+			hi = p
+		}
+	}
+	return out
+}
+
+// migrateData copies every key of from that falls in ranges over to to.
+// Keys are deleted from from once copied unless from is still one of their
+// replicas.
+func (r *HashRing) migrateData(from, to node, ranges []keyRange) error {
+	keys, err := from.client.GetAllKeys(context.Background(), &cacheNodepb.GetAllKeysRequest{})
 	if err != nil {
 		return err
 	}
 
+	moved := 0
 	for _, key := range keys.Keys {
-		kh := util.Hash(key)
-
-		shouldMove := false
-		if predHash > hTo {
-			if kh > predHash || kh <= hTo {
-				shouldMove = true
-			}
-		} else {
-			if kh > predHash && kh <= hTo {
-				shouldMove = true
-			}
+		if !inRanges(util.Hash(key), ranges) {
+			continue
+		}
+		getRes, err := from.client.Get(context.Background(), &cacheNodepb.GetRequest{Key: key})
+		if err != nil || !getRes.Found {
+			continue
 		}
-		if shouldMove {
-			getRes, err := nodeFrom.client.Get(context.Background(), &cacheNodepb.GetRequest{Key: key})
-			if err != nil || !getRes.Found {
-				continue
-			}
 
-			_, err = nodeTo.client.Set(context.Background(), &cacheNodepb.SetRequest{
-				Key:   key,
-				Value: getRes.Value,
-			})
-			if err != nil {
-				continue
-			}
+		_, err = to.client.Set(context.Background(), copyRequest(key, getRes))
+		if err != nil {
+			continue
+		}
+		moved++
 
-			_, err = nodeFrom.client.Delete(context.Background(), &cacheNodepb.DeleteRequest{Key: key})
-			if err != nil {
-				continue
-			}
+		if r.isReplica(from.addr, key) {
+			continue
+		}
+		_, err = from.client.Delete(context.Background(), &cacheNodepb.DeleteRequest{Key: key, Drop: true})
+		if err != nil {
+			continue
 		}
 	}
+	r.log.Info("migrated keys", "from", from.addr, "to", to.addr, "keys", moved, "ranges", len(ranges))
 	return nil
 }
 
-func (r *HashRing) getNode(key string) node {
-	h := util.Hash(key)
-	log.Printf("key hash : %v", h)
-	log.Printf("node hashes : %v", r.keys)
+// copyRequest is the write that copies a value read from one node onto
+// another with its remaining ttls, flags and version intact.
+func copyRequest(key string, res *cacheNodepb.GetResponse) *cacheNodepb.SetRequest {
+	softTTL := res.SoftTtlMs
+	if res.Stale {
+		softTTL = 1 // keep it stale on the new node
+	}
+	return &cacheNodepb.SetRequest{
+		Key:       key,
+		Value:     res.Value,
+		Flags:     res.Flags,
+		TtlMs:     res.TtlMs,
+		SoftTtlMs: softTTL,
+		Version:   res.Version,
+	}
+}
 
+// replicaSet is a group of nodes together with every ring range that is
+// stored on exactly them.
+type replicaSet struct {
+	nodes  []node
+	ranges []keyRange
+}
+
+// replicaSets splits the ring into the ranges between consecutive points
+// and groups them by the nodes that replicate them, skipping nodes that
+// are down. Ranges held by a single node are left out.
+func (r *HashRing) replicaSets() []*replicaSet {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	n := len(r.keys)
+	sets := make(map[string]*replicaSet)
+	var out []*replicaSet
+	for idx, hi := range r.keys {
+		nodes := r.successors(hi, r.replicas, r.isDown)
+		if len(nodes) < 2 {
+			continue
+		}
+		addrs := make([]string, len(nodes))
+		for i, nd := range nodes {
+			addrs[i] = nd.addr
+		}
+		sort.Strings(addrs)
+		id := strings.Join(addrs, ",")
+
+		set, ok := sets[id]
+		if !ok {
+			set = &replicaSet{nodes: nodes}
+			sets[id] = set
+			out = append(out, set)
+		}
+		set.ranges = append(set.ranges, keyRange{lo: r.keys[(idx+n-1)%n], hi: hi})
+	}
+	return out
+}
+
+// dropStale deletes the keys in ranges that n holds but is no longer a
+// replica of, which happens to the last replica of a range when a node
+// joins in front of it.
+func (r *HashRing) dropStale(n node, ranges []keyRange) error {
+	keys, err := n.client.GetAllKeys(context.Background(), &cacheNodepb.GetAllKeysRequest{})
+	if err != nil {
+		return err
+	}
+	for _, key := range keys.Keys {
+		if !inRanges(util.Hash(key), ranges) || r.isReplica(n.addr, key) {
+			continue
+		}
+		if _, err := n.client.Delete(context.Background(), &cacheNodepb.DeleteRequest{Key: key, Drop: true}); err != nil {
+			continue
+		}
+	}
+	return nil
+}
+
+// isReplica reports whether addr is one of the nodes key is stored on.
+func (r *HashRing) isReplica(addr, key string) bool {
+	for _, n := range r.getNodes(key, r.replicas) {
+		if n.addr == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// search returns the index of the first ring point at or after h, wrapping
+// around to 0. It must be called with r.mu held.
+func (r *HashRing) search(h uint32) int {
 	idx := sort.Search(len(r.keys), func(i int) bool {
 		return r.keys[i] >= h
 	})
 	if idx == len(r.keys) {
 		idx = 0
 	}
-	n := r.nodes[r.keys[idx]]
-	log.Printf("[ring] key=%s hash=%d -> node=%s nodeHash=%d (idx=%d)", key, h, n.addr, r.keys[idx], idx)
+	return idx
+}
 
-	log.Printf("ring.keys=%v (sorted=%t)", r.keys, sort.SliceIsSorted(r.keys, func(i, j int) bool { return r.keys[i] < r.keys[j] }))
+// successors returns up to n distinct nodes for which skip (if set) is false,
+// starting at the first ring point at or after h and walking clockwise. It
+// must be called with r.mu held.
+func (r *HashRing) successors(h uint32, n int, skip func(addr string) bool) []node {
+	if len(r.keys) == 0 {
+		return nil
+	}
+	idx := r.search(h)
+	out := make([]node, 0, n)
+	seen := make(map[string]bool, n)
+	for i := 0; i < len(r.keys) && len(out) < n; i++ {
+		nd := r.nodes[r.keys[(idx+i)%len(r.keys)]]
+		if seen[nd.addr] || (skip != nil && skip(nd.addr)) {
+			continue
+		}
+		seen[nd.addr] = true
+		out = append(out, nd)
+	}
+	return out
+}
+
+// getNodes returns the owner of key followed by its next n-1 distinct
+// successors on the ring. Nodes marked down are skipped, so their keys are
+// served by the following nodes until they recover.
+func (r *HashRing) getNodes(key string, n int) []node {
+	h := util.Hash(key)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.successors(h, n, r.isDown)
+}
+
+// writeTarget is a node a key is written to. owner is set when the node
+// stands in for a replica that is down, and names that replica.
+type writeTarget struct {
+	node
+	owner string
+}
+
+// writeTargets returns the nodes key is written to, the same as getNodes,
+// pairing every node that stands in for a replica that is down with it.
+// spares are the healthy nodes after them, for writes that fail to fall
+// back to.
+func (r *HashRing) writeTargets(key string) (targets []writeTarget, spares []node) {
+	h := util.Hash(key)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	preferred := r.successors(h, r.replicas, nil)
+	healthy := r.successors(h, 2*r.replicas, r.isDown)
+	n := min(len(healthy), r.replicas)
+	holds := func(nodes []node, addr string) bool {
+		return slices.ContainsFunc(nodes, func(nd node) bool { return nd.addr == addr })
+	}
 
-	return n
+	var down []string
+	for _, p := range preferred {
+		if !holds(healthy[:n], p.addr) {
+			down = append(down, p.addr)
+		}
+	}
+	for _, nd := range healthy[:n] {
+		t := writeTarget{node: nd}
+		if len(down) > 0 && !holds(preferred, nd.addr) {
+			t.owner, down = down[0], down[1:]
+		}
+		targets = append(targets, t)
+	}
+	return targets, healthy[n:]
+}
+
+// member returns the node at addr.
+func (r *HashRing) member(addr string) (node, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n, ok := r.members[addr]
+	return n, ok
+}
+
+// getNode returns the first node that is not down at or after key's position
+// on the ring. ok is false when every node is down.
+func (r *HashRing) getNode(key string) (n node, ok bool) {
+	h := util.Hash(key)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := r.successors(h, 1, r.isDown)
+	if len(nodes) == 0 {
+		r.log.Debug("ring lookup", "key", key, "hash", h, "node", "none")
+		return node{}, false
+	}
+	n = nodes[0]
+	r.log.Debug("ring lookup", "key", key, "hash", h, "node", n.addr)
+	return n, true
 }
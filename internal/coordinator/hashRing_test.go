@@ -0,0 +1,194 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sakshamg567/cachy/util"
+)
+
+// Connections are dialed lazily, so rings built over addresses nothing
+// listens on are fine as long as no request is sent.
+func newTestRing(t *testing.T, members []Member, replicas, vnodes int) *HashRing {
+	t.Helper()
+	r := NewHashRing(members, replicas, vnodes)
+	t.Cleanup(r.close)
+	return r
+}
+
+func testKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+	return keys
+}
+
+// owners maps every key to the address of its first replica.
+func owners(r *HashRing, keys []string) map[string]string {
+	out := make(map[string]string, len(keys))
+	for _, k := range keys {
+		n, _ := r.getNode(k)
+		out[k] = n.addr
+	}
+	return out
+}
+
+func TestRingVnodesByWeight(t *testing.T) {
+	tests := []struct {
+		weight, want int
+	}{
+		{1, 1},
+		{3, 3},
+		{0, 1},  // clamped up
+		{-2, 1}, // clamped up
+		{MaxWeight, MaxWeight},
+		{MaxWeight + 50, MaxWeight}, // clamped down
+	}
+	const vnodes = 8
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.weight), func(t *testing.T) {
+			r := newTestRing(t, []Member{{"127.0.0.1:1", tt.weight}}, 1, vnodes)
+			if got := len(r.keys); got != vnodes*tt.want {
+				t.Errorf("%d points on the ring, want %d", got, vnodes*tt.want)
+			}
+			if got := r.weights["127.0.0.1:1"]; got != tt.want {
+				t.Errorf("weight = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRingWeightShare(t *testing.T) {
+	// The share of keys a node owns follows its weight.
+	tests := []struct {
+		weights []int
+	}{
+		{[]int{1, 1}},
+		{[]int{1, 3}},
+		{[]int{1, 2, 5}},
+	}
+	keys := testKeys(20000)
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.weights), func(t *testing.T) {
+			var members []Member
+			total := 0
+			for i, w := range tt.weights {
+				members = append(members, Member{fmt.Sprintf("127.0.0.1:%d", i+1), w})
+				total += w
+			}
+			r := newTestRing(t, members, 1, 100)
+			counts := map[string]int{}
+			for _, addr := range owners(r, keys) {
+				counts[addr]++
+			}
+			for _, m := range members {
+				got := float64(counts[m.Addr]) / float64(len(keys))
+				want := float64(m.Weight) / float64(total)
+				if got < want-0.05 || got > want+0.05 {
+					t.Errorf("%s owns %.3f of the keys, want about %.3f", m.Addr, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRingPlacement(t *testing.T) {
+	members := []Member{{"127.0.0.1:1", 1}, {"127.0.0.1:2", 1}, {"127.0.0.1:3", 2}, {"127.0.0.1:4", 1}}
+	tests := []struct {
+		replicas, want int
+	}{
+		{1, 1},
+		{3, 3},
+		{4, 4},
+		{6, 4}, // no more replicas than members
+	}
+	keys := testKeys(500)
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.replicas), func(t *testing.T) {
+			r := newTestRing(t, members, tt.replicas, 16)
+			for _, k := range keys {
+				nodes := r.getNodes(k, tt.replicas)
+				if len(nodes) != tt.want {
+					t.Fatalf("%s: %d replicas, want %d", k, len(nodes), tt.want)
+				}
+				seen := map[string]bool{}
+				for _, n := range nodes {
+					if seen[n.addr] {
+						t.Fatalf("%s: %s listed twice in %v", k, n.addr, nodes)
+					}
+					seen[n.addr] = true
+				}
+				// The owner is the node of the first point at or after the key.
+				if want := r.nodes[r.keys[r.search(util.Hash(k))]].addr; nodes[0].addr != want {
+					t.Fatalf("%s: owner %s, want %s", k, nodes[0].addr, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRingMembershipMovesOnlyAffectedKeys(t *testing.T) {
+	base := []Member{{"127.0.0.1:1", 1}, {"127.0.0.1:2", 1}, {"127.0.0.1:3", 1}}
+	keys := testKeys(2000)
+	tests := []struct {
+		name   string
+		change func(r *HashRing) error
+		node   string // the node added or removed
+		added  bool
+	}{
+		{"add", func(r *HashRing) error { return r.addNode("127.0.0.1:4", 2) }, "127.0.0.1:4", true},
+		{"remove", func(r *HashRing) error { r.removeNode("127.0.0.1:2"); return nil }, "127.0.0.1:2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRing(t, base, 1, 32)
+			before := owners(r, keys)
+			if err := tt.change(r); err != nil {
+				t.Fatal(err)
+			}
+			after := owners(r, keys)
+			moved := 0
+			for _, k := range keys {
+				if before[k] == after[k] {
+					continue
+				}
+				moved++
+				// Keys only move to a node that joined or off one that left.
+				if tt.added && after[k] != tt.node || !tt.added && before[k] != tt.node {
+					t.Fatalf("%s moved from %s to %s", k, before[k], after[k])
+				}
+			}
+			if moved == 0 {
+				t.Error("no keys moved")
+			}
+		})
+	}
+}
+
+func TestAddNodeValidation(t *testing.T) {
+	tests := []struct {
+		addr   string
+		weight int
+		err    error
+	}{
+		{"127.0.0.1:1", 1, ErrNodeExists},
+		{"127.0.0.1", 1, ErrInvalidNode},
+		{"127.0.0.1:", 1, ErrInvalidNode},
+		{"", 1, ErrInvalidNode},
+		{"127.0.0.1:9", 0, ErrInvalidNode},
+		{"127.0.0.1:9", MaxWeight + 1, ErrInvalidNode},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tt.addr, tt.weight), func(t *testing.T) {
+			c := &Coordinator{ring: newTestRing(t, []Member{{"127.0.0.1:1", 1}}, 1, 4)}
+			if err := c.AddNode(tt.addr, tt.weight); !errors.Is(err, tt.err) {
+				t.Errorf("AddNode = %v, want %v", err, tt.err)
+			}
+			if n := len(c.ring.members); n != 1 {
+				t.Errorf("ring has %d members after a refused add, want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package coordinator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type NodeState int
+
+const (
+	NodeAlive NodeState = iota
+	// NodeSuspect nodes have missed a heartbeat but still receive traffic.
+	NodeSuspect
+	// NodeDown nodes are skipped when routing keys until they answer again.
+	NodeDown
+)
+
+func (s NodeState) String() string {
+	switch s {
+	case NodeAlive:
+		return "alive"
+	case NodeSuspect:
+		return "suspect"
+	case NodeDown:
+		return "down"
+	}
+	return "unknown"
+}
+
+// NodeStatus is a snapshot of what the coordinator knows about a cache node.
+type NodeStatus struct {
+	Addr     string
+	Weight   int
+	State    NodeState
+	Failures int       // consecutive failed heartbeats
+	LastSeen time.Time // last successful heartbeat, zero if never
+}
+
+// checkHealth sends one heartbeat to every member and updates its state.
+// A node turns suspect on its first missed heartbeat and down after
+// downAfter consecutive misses.
+func (r *HashRing) checkHealth(timeout time.Duration, downAfter int) {
+	r.mu.RLock()
+	members := make([]node, 0, len(r.members))
+	for _, n := range r.members {
+		members = append(members, n)
+	}
+	r.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, n := range members {
+		wg.Add(1)
+		go func(n node) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			res, err := grpc_health_v1.NewHealthClient(n.conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: cacheNodepb.Cache_ServiceDesc.ServiceName})
+			ok := err == nil && res.Status == grpc_health_v1.HealthCheckResponse_SERVING
+			r.recordHeartbeat(n.addr, ok, downAfter)
+		}(n)
+	}
+	wg.Wait()
+}
+
+func (r *HashRing) recordHeartbeat(addr string, ok bool, downAfter int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st, exists := r.status[addr]
+	if !exists {
+		// removed while the check was in flight
+		return
+	}
+	prev := st.State
+	if ok {
+		st.Failures = 0
+		st.LastSeen = time.Now()
+		st.State = NodeAlive
+	} else {
+		st.Failures++
+		st.State = NodeSuspect
+		if st.Failures >= downAfter {
+			st.State = NodeDown
+		}
+	}
+	if st.State != prev {
+		r.log.Warn("node state changed", "node", addr, "from", prev.String(), "to", st.State.String(), "failures", st.Failures)
+	}
+	if prev != NodeAlive && st.State == NodeAlive && r.recovered != nil {
+		go r.recovered(addr)
+	}
+}
+
+// isDown reports whether addr should be skipped when routing keys. It must
+// be called with r.mu held.
+func (r *HashRing) isDown(addr string) bool {
+	st, ok := r.status[addr]
+	return ok && st.State == NodeDown
+}
+
+// down reports whether addr is currently skipped when routing keys.
+func (r *HashRing) down(addr string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.isDown(addr)
+}
+
+// startHealthChecks heartbeats every member each interval until the ring is
+// closed.
+func (r *HashRing) startHealthChecks(interval, timeout time.Duration, downAfter int) {
+	if interval <= 0 {
+		return
+	}
+	if timeout <= 0 || timeout > interval {
+		timeout = interval
+	}
+	if downAfter < 1 {
+		downAfter = 1
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.checkHealth(timeout, downAfter)
+			}
+		}
+	}()
+}
@@ -0,0 +1,121 @@
+package coordinator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// nodeStatus returns what c knows about the node at addr.
+func nodeStatus(c *Coordinator, addr string) NodeStatus {
+	for _, st := range c.Nodes() {
+		if st.Addr == addr {
+			return st
+		}
+	}
+	return NodeStatus{}
+}
+
+func TestHealthStates(t *testing.T) {
+	nodes := startTestNodes(t, 2)
+	c := newTestCoordinator(t, nodes, Config{Replicas: 1})
+	n := nodes[0]
+	recovered := make(chan string, 1)
+	c.ring.recovered = func(addr string) { recovered <- addr }
+
+	// Each step sets how n answers heartbeats, or stops it, then runs one
+	// round of checks with nodes going down after two misses.
+	tests := []struct {
+		name     string
+		serving  grpc_health_v1.HealthCheckResponse_ServingStatus
+		stopped  bool
+		want     NodeState
+		failures int
+	}{
+		{"serving", grpc_health_v1.HealthCheckResponse_SERVING, false, NodeAlive, 0},
+		{"not serving", grpc_health_v1.HealthCheckResponse_NOT_SERVING, false, NodeSuspect, 1},
+		{"still not serving", grpc_health_v1.HealthCheckResponse_NOT_SERVING, false, NodeDown, 2},
+		{"serving again", grpc_health_v1.HealthCheckResponse_SERVING, false, NodeAlive, 0},
+		{"stopped", grpc_health_v1.HealthCheckResponse_SERVING, true, NodeSuspect, 1},
+		{"still stopped", grpc_health_v1.HealthCheckResponse_SERVING, true, NodeDown, 2},
+	}
+	for _, tt := range tests {
+		n.health.SetServingStatus(cacheNodepb.Cache_ServiceDesc.ServiceName, tt.serving)
+		if tt.stopped {
+			n.stop()
+		}
+		c.ring.checkHealth(time.Second, 2)
+
+		st := nodeStatus(c, n.addr)
+		if st.State != tt.want || st.Failures != tt.failures {
+			t.Fatalf("%s: node is %v after %d failures, want %v after %d", tt.name, st.State, st.Failures, tt.want, tt.failures)
+		}
+		if down := c.ring.down(n.addr); down != (tt.want == NodeDown) {
+			t.Errorf("%s: skipped when routing = %v", tt.name, down)
+		}
+		if other := nodeStatus(c, nodes[1].addr); other.State != NodeAlive {
+			t.Errorf("%s: the other node is %v", tt.name, other.State)
+		}
+		// Only a node coming back from suspect or down counts as recovered.
+		select {
+		case addr := <-recovered:
+			if tt.name != "serving again" || addr != n.addr {
+				t.Errorf("%s: %s recovered", tt.name, addr)
+			}
+		case <-time.After(50 * time.Millisecond):
+			if tt.name == "serving again" {
+				t.Errorf("%s: recovery not reported", tt.name)
+			}
+		}
+	}
+}
+
+func TestHintsReplayedOnRecovery(t *testing.T) {
+	nodes := startTestNodes(t, 3)
+	c := newTestCoordinator(t, nodes, Config{Replicas: 2, MaxHints: 100, HintReplayInterval: time.Hour})
+	ctx := WithConsistency(context.Background(), All)
+
+	const key = "k"
+	owner := c.ring.getNodes(key, 2)[0].addr
+	var down *testNode
+	for _, n := range nodes {
+		if n.addr == owner {
+			down = n
+		}
+	}
+
+	// The owner still answers requests, but not heartbeats, so it is
+	// taken out of routing and its write is held as a hint.
+	down.health.SetServingStatus(cacheNodepb.Cache_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	c.ring.checkHealth(time.Second, 1)
+	if !c.ring.down(owner) {
+		t.Fatal("owner not marked down")
+	}
+	if err := c.Set(ctx, key, []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := valueOn(c, owner, key); ok {
+		t.Fatal("write reached the owner while it was down")
+	}
+
+	// Its first good heartbeat replays the hint through the recovered
+	// callback, without waiting for the replay interval.
+	down.health.SetServingStatus(cacheNodepb.Cache_ServiceDesc.ServiceName, grpc_health_v1.HealthCheckResponse_SERVING)
+	c.ring.checkHealth(time.Second, 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if got, _ := valueOn(c, owner, key); got == "v" {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got, _ := valueOn(c, owner, key); got != "v" {
+		t.Fatalf("owner holds %q after recovering, want %q", got, "v")
+	}
+	if owners := c.hints.owners(); len(owners) > 0 {
+		t.Errorf("hints still pending for %v", owners)
+	}
+}
@@ -0,0 +1,218 @@
+package coordinator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// hint records that a write meant for a replica that was unavailable went
+// to another node instead.
+type hint struct {
+	holder  string // node the write went to
+	delete  bool
+	version int64 // of the delete
+}
+
+// hintStore keeps the hints for every owner, at most max of them. Only the
+// latest hint for a key is kept, since replaying it brings the owner up to
+// date.
+type hintStore struct {
+	mu      sync.Mutex
+	max     int
+	n       int
+	byOwner map[string]map[string]hint // owner -> key -> hint
+}
+
+func newHintStore(max int) *hintStore {
+	return &hintStore{max: max, byOwner: make(map[string]map[string]hint)}
+}
+
+// add records h for key, replacing any earlier hint for it. It reports
+// false if the store is full.
+func (s *hintStore) add(owner, key string, h hint) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addLocked(owner, key, h)
+}
+
+// addLocked is add for a caller that holds s.mu.
+func (s *hintStore) addLocked(owner, key string, h hint) bool {
+	keys := s.byOwner[owner]
+	if _, ok := keys[key]; !ok {
+		if s.n >= s.max {
+			return false
+		}
+		if keys == nil {
+			keys = make(map[string]hint)
+			s.byOwner[owner] = keys
+		}
+		s.n++
+		hintsPending.Inc()
+	}
+	keys[key] = h
+	return true
+}
+
+// restore puts back a hint that could not be replayed, unless a newer one
+// has been recorded since.
+func (s *hintStore) restore(owner, key string, h hint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, newer := s.byOwner[owner][key]; !newer && !s.addLocked(owner, key, h) {
+		hintsTotal.WithLabelValues("dropped").Inc()
+	}
+}
+
+// owners returns the owners that have hints waiting.
+func (s *hintStore) owners() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	owners := make([]string, 0, len(s.byOwner))
+	for owner := range s.byOwner {
+		owners = append(owners, owner)
+	}
+	return owners
+}
+
+// take removes and returns every hint for owner.
+func (s *hintStore) take(owner string) map[string]hint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := s.byOwner[owner]
+	delete(s.byOwner, owner)
+	s.n -= len(keys)
+	hintsPending.Sub(float64(len(keys)))
+	return keys
+}
+
+// hint records that a write of key meant for owner went to holder. Writes
+// that do not fit in the store are only repaired by anti-entropy.
+func (c *Coordinator) hint(owner, key string, h hint) {
+	if c.hints == nil {
+		return
+	}
+	if !c.hints.add(owner, key, h) {
+		hintsTotal.WithLabelValues("dropped").Inc()
+		c.log.Warn("hint store full, dropping hint", "key", key, "owner", owner)
+		return
+	}
+	hintsTotal.WithLabelValues("stored").Inc()
+	c.log.Debug("hinted handoff", "key", key, "owner", owner, "holder", h.holder)
+}
+
+// hintTimeout bounds the replay of the hints for one owner.
+const hintTimeout = time.Minute
+
+// defaultHintReplayInterval is how often hints are replayed when
+// Config.HintReplayInterval is not set.
+const defaultHintReplayInterval = 10 * time.Second
+
+// startHintReplay replays the hints for every owner not known to be down
+// each interval until the ring is closed. Replaying on recovery alone
+// misses owners that were only briefly unreachable and never marked down,
+// and every owner when health checks are off.
+func (c *Coordinator) startHintReplay(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultHintReplayInterval
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.ring.stop:
+				return
+			case <-ticker.C:
+			}
+			for _, owner := range c.hints.owners() {
+				if !c.ring.down(owner) {
+					c.replayHints(owner)
+				}
+			}
+		}
+	}()
+}
+
+// replayHints brings owner up to date with the writes handed off while it
+// was unavailable. The current value of each key is copied over from the
+// node that took it, which then drops the key unless it is a replica of it
+// in its own right or has taken a newer write of it since. Hints that fail to replay are kept for the next pass,
+// and once owner turns out to be unreachable the rest are put back
+// untried.
+func (c *Coordinator) replayHints(owner string) {
+	if c.hints == nil {
+		return
+	}
+	hints := c.hints.take(owner)
+	if len(hints) == 0 {
+		return
+	}
+	to, ok := c.ring.member(owner)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hintTimeout)
+	defer cancel()
+	replayed := 0
+	var unreachable error
+	for key, h := range hints {
+		if unreachable != nil {
+			c.hints.restore(owner, key, h)
+			continue
+		}
+		if err := c.replayHint(ctx, to, key, h); err != nil {
+			c.hints.restore(owner, key, h)
+			if status.Code(err) == codes.Unavailable {
+				unreachable = err
+				continue
+			}
+			c.log.Warn("hint replay failed", "key", key, "owner", owner, "holder", h.holder, "err", err)
+			continue
+		}
+		replayed++
+		hintsTotal.WithLabelValues("replayed").Inc()
+	}
+	if unreachable != nil {
+		c.log.Debug("hint replay deferred", "owner", owner, "replayed", replayed, "hints", len(hints), "err", unreachable)
+		return
+	}
+	c.log.Info("replayed hints", "owner", owner, "replayed", replayed, "hints", len(hints))
+}
+
+func (c *Coordinator) replayHint(ctx context.Context, to node, key string, h hint) error {
+	if h.delete {
+		_, err := to.client.Delete(ctx, &cacheNodepb.DeleteRequest{Key: key, Version: h.version})
+		return err
+	}
+	from, ok := c.ring.member(h.holder)
+	if !ok {
+		return nil // the holder left the ring, and the value with it
+	}
+	res, err := from.client.Get(ctx, &cacheNodepb.GetRequest{Key: key})
+	if err != nil {
+		return err
+	}
+	if !res.Found {
+		return nil // expired or evicted since
+	}
+	if _, err := to.client.Set(ctx, copyRequest(key, res)); err != nil {
+		return err
+	}
+	if !c.ring.isReplica(from.addr, key) {
+		from.client.CompareAndDelete(ctx, &cacheNodepb.CompareAndDeleteRequest{Key: key, ExpectedVersion: res.Version, Drop: true})
+	}
+	return nil
+}
+
+// forgetHints drops the hints for a node that left the ring.
+func (c *Coordinator) forgetHints(owner string) {
+	if c.hints != nil {
+		c.hints.take(owner)
+	}
+}
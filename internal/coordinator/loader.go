@@ -0,0 +1,296 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBackend is returned when the loader behind the cache fails.
+var ErrBackend = errors.New("backend unavailable")
+
+// Loader is the system of record behind the cache. A key missing from the
+// ring is loaded from it and cached.
+type Loader interface {
+	// Load returns the value stored under key, or ErrNotFound.
+	Load(ctx context.Context, key string) ([]byte, error)
+}
+
+// Writer is a Loader that also accepts writes, as needed for write-through
+// and write-behind. Only keys and values are passed on; ttls and flags
+// belong to the cache.
+type Writer interface {
+	Loader
+	Write(ctx context.Context, entries []Entry) error
+	Delete(ctx context.Context, keys []string) error
+}
+
+// WriteMode says whether writes to the cache also go to the loader.
+type WriteMode string
+
+const (
+	// WriteNone only writes to the cache.
+	WriteNone WriteMode = "none"
+	// WriteThrough writes to the backend first and only then to the cache,
+	// failing the write if the backend does.
+	WriteThrough WriteMode = "through"
+	// WriteBehind writes to the cache and queues the backend write, which
+	// is batched with others and retried in the background.
+	WriteBehind WriteMode = "behind"
+)
+
+// WriteModes lists every supported write mode.
+var WriteModes = []WriteMode{WriteNone, WriteThrough, WriteBehind}
+
+// ParseWriteMode maps a case-insensitive name onto a mode.
+func ParseWriteMode(name string) (WriteMode, error) {
+	for _, m := range WriteModes {
+		if strings.EqualFold(name, string(m)) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown write mode %q", name)
+}
+
+// retryBackoff is the wait before the first retry of a backend call; it
+// doubles with each further attempt.
+const retryBackoff = 100 * time.Millisecond
+
+// retry calls fn up to attempts times, backing off in between, and returns
+// its last error.
+func retry(ctx context.Context, attempts int, fn func() error) error {
+	wait := retryBackoff
+	var err error
+	for i := 0; i < max(attempts, 1); i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// load reads key from the loader and caches it. The value is returned even
+// if caching it fails.
+func (c *Coordinator) load(ctx context.Context, key string) (Item, error) {
+	value, err := c.loader.Load(ctx, key)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		loadsTotal.WithLabelValues("miss").Inc()
+		return Item{}, ErrNotFound
+	case err != nil:
+		loadsTotal.WithLabelValues("error").Inc()
+		c.log.Warn("load failed", "key", key, "err", err)
+		return Item{}, fmt.Errorf("%w: %v", ErrBackend, err)
+	}
+	loadsTotal.WithLabelValues("hit").Inc()
+
+	e := Entry{Key: key, Value: value, TTL: c.loadTTL, SoftTTL: c.loadSoftTTL}
+	if err := c.setCache(ctx, e); err != nil {
+		c.log.Warn("caching loaded value failed", "key", key, "err", err)
+	}
+	return Item{Value: value, TTL: c.loadTTL}, nil
+}
+
+// refresh reloads a stale key in the background. A key the backend no
+// longer has is dropped from the cache.
+func (c *Coordinator) refresh(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), refreshTimeout)
+	defer cancel()
+	if _, err := c.load(ctx, key); errors.Is(err, ErrNotFound) {
+		c.deleteCache(ctx, key)
+	}
+}
+
+// refreshTimeout bounds a background refresh of a stale key.
+const refreshTimeout = 10 * time.Second
+
+// writeBackend passes writes on to the backend according to the write
+// mode. Under write-through it returns once the backend has them.
+func (c *Coordinator) writeBackend(ctx context.Context, entries []Entry) error {
+	switch c.writeMode {
+	case WriteThrough:
+		err := retry(ctx, c.writeRetries, func() error { return c.writer.Write(ctx, entries) })
+		backendWritesTotal.WithLabelValues(resultLabel(err)).Inc()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBackend, err)
+		}
+	case WriteBehind:
+		return c.behind.enqueue(entries, nil)
+	}
+	return nil
+}
+
+// deleteBackend is writeBackend for deletes.
+func (c *Coordinator) deleteBackend(ctx context.Context, keys []string) error {
+	switch c.writeMode {
+	case WriteThrough:
+		err := retry(ctx, c.writeRetries, func() error { return c.writer.Delete(ctx, keys) })
+		backendWritesTotal.WithLabelValues(resultLabel(err)).Inc()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBackend, err)
+		}
+	case WriteBehind:
+		return c.behind.enqueue(nil, keys)
+	}
+	return nil
+}
+
+func resultLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
+
+// writeBehind queues backend writes and flushes them in batches. Only the
+// latest write to each key is kept, so a hot key costs one backend write
+// per flush at most.
+type writeBehind struct {
+	w          Writer
+	log        *slog.Logger
+	batch      int
+	delay      time.Duration
+	retries    int
+	maxPending int
+
+	mu      sync.Mutex
+	pending map[string]*Entry // nil entry for a delete
+	closed  bool
+	kick    chan struct{}
+	done    chan struct{}
+}
+
+// maxPendingWrites bounds the write-behind queue. Writes are refused once
+// the backend has fallen this far behind.
+const maxPendingWrites = 100000
+
+func newWriteBehind(w Writer, logger *slog.Logger, batch int, delay time.Duration, retries int) *writeBehind {
+	wb := &writeBehind{
+		w:          w,
+		log:        logger,
+		batch:      max(batch, 1),
+		delay:      delay,
+		retries:    retries,
+		maxPending: maxPendingWrites,
+		pending:    make(map[string]*Entry),
+		kick:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	if wb.delay <= 0 {
+		wb.delay = time.Second
+	}
+	go wb.run()
+	return wb
+}
+
+func (wb *writeBehind) enqueue(entries []Entry, deletes []string) error {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	if wb.closed {
+		return fmt.Errorf("%w: shutting down", ErrBackend)
+	}
+	if len(wb.pending)+len(entries)+len(deletes) > wb.maxPending {
+		return fmt.Errorf("%w: write-behind queue full", ErrBackend)
+	}
+	for i := range entries {
+		e := entries[i]
+		wb.pending[e.Key] = &e
+	}
+	for _, key := range deletes {
+		wb.pending[key] = nil
+	}
+	writeBehindPending.Set(float64(len(wb.pending)))
+	if len(wb.pending) >= wb.batch {
+		select {
+		case wb.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// run flushes every delay, or sooner once a full batch is waiting, until
+// the queue is closed and drained.
+func (wb *writeBehind) run() {
+	defer close(wb.done)
+	ticker := time.NewTicker(wb.delay)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-wb.kick:
+		}
+		for wb.flush() {
+		}
+		wb.mu.Lock()
+		closed := wb.closed && len(wb.pending) == 0
+		wb.mu.Unlock()
+		if closed {
+			return
+		}
+	}
+}
+
+// flush sends one batch and reports whether a full batch was taken, in
+// which case more may be waiting.
+func (wb *writeBehind) flush() bool {
+	wb.mu.Lock()
+	var (
+		writes  []Entry
+		deletes []string
+	)
+	for key, e := range wb.pending {
+		if len(writes)+len(deletes) == wb.batch {
+			break
+		}
+		if e == nil {
+			deletes = append(deletes, key)
+		} else {
+			writes = append(writes, *e)
+		}
+		delete(wb.pending, key)
+	}
+	writeBehindPending.Set(float64(len(wb.pending)))
+	wb.mu.Unlock()
+
+	ctx := context.Background()
+	if len(writes) > 0 {
+		err := retry(ctx, wb.retries, func() error { return wb.w.Write(ctx, writes) })
+		backendWritesTotal.WithLabelValues(resultLabel(err)).Inc()
+		if err != nil {
+			wb.log.Error("write-behind batch dropped", "writes", len(writes), "err", err)
+		}
+	}
+	if len(deletes) > 0 {
+		err := retry(ctx, wb.retries, func() error { return wb.w.Delete(ctx, deletes) })
+		backendWritesTotal.WithLabelValues(resultLabel(err)).Inc()
+		if err != nil {
+			wb.log.Error("write-behind batch dropped", "deletes", len(deletes), "err", err)
+		}
+	}
+	return len(writes)+len(deletes) == wb.batch
+}
+
+// close stops taking writes and waits for the queue to drain.
+func (wb *writeBehind) close() {
+	wb.mu.Lock()
+	wb.closed = true
+	wb.mu.Unlock()
+	select {
+	case wb.kick <- struct{}{}:
+	default:
+	}
+	<-wb.done
+}
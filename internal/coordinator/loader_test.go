@@ -0,0 +1,322 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memBackend is a Writer over a map. While fail is set every call returns
+// it.
+type memBackend struct {
+	mu     sync.Mutex
+	values map[string]string
+	writes int // calls to Write and Delete
+	fail   error
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{values: make(map[string]string)}
+}
+
+func (b *memBackend) Load(ctx context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fail != nil {
+		return nil, b.fail
+	}
+	v, ok := b.values[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return []byte(v), nil
+}
+
+func (b *memBackend) Write(ctx context.Context, entries []Entry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writes++
+	if b.fail != nil {
+		return b.fail
+	}
+	for _, e := range entries {
+		b.values[e.Key] = string(e.Value)
+	}
+	return nil
+}
+
+func (b *memBackend) Delete(ctx context.Context, keys []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writes++
+	if b.fail != nil {
+		return b.fail
+	}
+	for _, k := range keys {
+		delete(b.values, k)
+	}
+	return nil
+}
+
+func (b *memBackend) set(key, value string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[key] = value
+}
+
+func (b *memBackend) get(key string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.values[key]
+	return v, ok
+}
+
+func (b *memBackend) setFail(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.fail = err
+}
+
+// eventually polls cond for up to five seconds.
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
+
+func TestRetry(t *testing.T) {
+	errFail := errors.New("fail")
+	tests := []struct {
+		name     string
+		attempts int
+		failures int // calls that fail before one succeeds
+		calls    int
+		err      error
+	}{
+		{"first try", 3, 0, 1, nil},
+		{"after failures", 3, 2, 3, nil},
+		{"out of attempts", 2, 5, 2, errFail},
+		{"at least once", 0, 5, 1, errFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := retry(context.Background(), tt.attempts, func() error {
+				calls++
+				if calls <= tt.failures {
+					return errFail
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.err) || calls != tt.calls {
+				t.Errorf("retry = %v after %d calls, want %v after %d", err, calls, tt.err, tt.calls)
+			}
+		})
+	}
+
+	// A cancelled context stops the retries, keeping the last error.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls := 0
+	err := retry(ctx, 5, func() error { calls++; return errFail })
+	if !errors.Is(err, errFail) || calls != 1 {
+		t.Errorf("retry with a cancelled context = %v after %d calls, want %v after 1", err, calls, errFail)
+	}
+}
+
+func TestWriteModeNeedsWriter(t *testing.T) {
+	for _, mode := range []WriteMode{WriteThrough, WriteBehind} {
+		if _, err := NewCoordinator(Config{WriteMode: mode, Loader: newGateLoader()}); err == nil {
+			t.Errorf("write mode %s with a read-only loader: no error", mode)
+		}
+		if _, err := NewCoordinator(Config{WriteMode: mode}); err == nil {
+			t.Errorf("write mode %s without a loader: no error", mode)
+		}
+	}
+}
+
+func TestReadThrough(t *testing.T) {
+	nodes := startTestNodes(t, 1)
+	b := newMemBackend()
+	b.set("k", "v")
+	c := newTestCoordinator(t, nodes, Config{Replicas: 1, Loader: b, LoadTTL: time.Minute})
+	ctx := context.Background()
+
+	if it, err := c.Get(ctx, "k"); err != nil || string(it.Value) != "v" {
+		t.Fatalf("Get = %q, %v", it.Value, err)
+	}
+	// The loaded value is cached with the loader ttl.
+	if it, err := c.get(ctx, "k"); err != nil || string(it.Value) != "v" || it.TTL <= 0 || it.TTL > time.Minute {
+		t.Errorf("cached = %q ttl %v, %v", it.Value, it.TTL, err)
+	}
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get missing = %v, want %v", err, ErrNotFound)
+	}
+	b.setFail(errors.New("down"))
+	if _, err := c.Get(ctx, "other"); !errors.Is(err, ErrBackend) {
+		t.Errorf("Get with the backend down = %v, want %v", err, ErrBackend)
+	}
+}
+
+func TestWriteThrough(t *testing.T) {
+	nodes := startTestNodes(t, 1)
+	b := newMemBackend()
+	c := newTestCoordinator(t, nodes, Config{Replicas: 1, Loader: b, WriteMode: WriteThrough, WriteRetries: 2})
+	ctx := context.Background()
+
+	if err := c.Set(ctx, "k", []byte("v"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := b.get("k"); v != "v" {
+		t.Errorf("backend holds %q, want %q", v, "v")
+	}
+
+	// A write the backend refuses, even after retrying, is not cached.
+	b.setFail(errors.New("down"))
+	if err := c.Set(ctx, "k", []byte("w"), 0); !errors.Is(err, ErrBackend) {
+		t.Errorf("Set with the backend down = %v, want %v", err, ErrBackend)
+	}
+	if b.writes != 3 {
+		t.Errorf("%d backend writes, want 1 and then 2 attempts", b.writes)
+	}
+	if it, _ := c.get(ctx, "k"); string(it.Value) != "v" {
+		t.Errorf("cache holds %q after a failed write, want %q", it.Value, "v")
+	}
+
+	b.setFail(nil)
+	if _, err := c.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.get("k"); ok {
+		t.Error("key still in the backend after Delete")
+	}
+}
+
+func TestWriteBehind(t *testing.T) {
+	nodes := startTestNodes(t, 1)
+	b := newMemBackend()
+	c, err := NewCoordinator(Config{
+		Nodes:      []Member{{Addr: nodes[0].addr, Weight: 1}},
+		Replicas:   1,
+		Vnodes:     16,
+		Loader:     b,
+		WriteMode:  WriteBehind,
+		WriteBatch: 3,
+		WriteDelay: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// A full batch is sent at once, without waiting for the delay.
+	for _, err := range c.MSet(ctx, entries([]string{"a", "b", "x"})) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !eventually(func() bool { _, ok := b.get("b"); return ok }) {
+		t.Fatal("full batch not written to the backend")
+	}
+
+	// Writes are cached at once and queued for the backend; Close sends
+	// what is left.
+	if err := c.Set(ctx, "c", []byte("c"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.get("c"); ok {
+		t.Error("write sent before the batch filled or the delay passed")
+	}
+	if it, err := c.get(ctx, "c"); err != nil || string(it.Value) != "c" {
+		t.Errorf("cache holds %q, %v", it.Value, err)
+	}
+	c.Close()
+	if _, ok := b.get("c"); !ok {
+		t.Error("queued write not flushed by Close")
+	}
+	if _, ok := b.get("a"); ok {
+		t.Error("queued delete not flushed by Close")
+	}
+	if err := c.behind.enqueue(entries([]string{"d"}), nil); !errors.Is(err, ErrBackend) {
+		t.Errorf("write after Close = %v, want %v", err, ErrBackend)
+	}
+}
+
+func TestWriteBehindQueueFull(t *testing.T) {
+	if maxPendingWrites != 100000 {
+		t.Errorf("queue limit = %d, want 100000", maxPendingWrites)
+	}
+	nodes := startTestNodes(t, 1)
+	b := newMemBackend()
+	c := newTestCoordinator(t, nodes, Config{Replicas: 1, Loader: b, WriteMode: WriteBehind, WriteBatch: 100, WriteDelay: time.Hour})
+	c.behind.maxPending = 2
+	ctx := context.Background()
+
+	for _, k := range []string{"a", "b"} {
+		if err := c.Set(ctx, k, []byte(k), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Past the limit writes are refused, and so neither cached nor queued.
+	if err := c.Set(ctx, "c", []byte("c"), 0); !errors.Is(err, ErrBackend) {
+		t.Fatalf("Set with the queue full = %v, want %v", err, ErrBackend)
+	}
+	if _, err := c.get(ctx, "c"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("refused write cached: %v", err)
+	}
+	c.behind.close()
+	if _, ok := b.get("c"); ok {
+		t.Error("refused write reached the backend")
+	}
+	if _, ok := b.get("b"); !ok {
+		t.Error("queued write lost")
+	}
+}
+
+func TestLoaderRefresh(t *testing.T) {
+	nodes := startTestNodes(t, 1)
+	b := newMemBackend()
+	b.set("k", "v1")
+	b.set("gone", "x")
+	c := newTestCoordinator(t, nodes, Config{Replicas: 1, Loader: b, LoadSoftTTL: time.Millisecond})
+	ctx := context.Background()
+
+	for _, k := range []string{"k", "gone"} {
+		if _, err := c.Get(ctx, k); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.set("k", "v2")
+	b.mu.Lock()
+	delete(b.values, "gone")
+	b.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+
+	// A stale value is served as is while it is refreshed in the
+	// background; no caller is asked to revalidate it.
+	it, err := c.Get(ctx, "k")
+	if err != nil || string(it.Value) != "v1" || !it.Stale || it.Revalidate {
+		t.Fatalf("stale Get = %q stale %v revalidate %v, %v", it.Value, it.Stale, it.Revalidate, err)
+	}
+	if !eventually(func() bool { it, _ := c.get(ctx, "k"); return string(it.Value) == "v2" }) {
+		t.Error("stale value not refreshed from the loader")
+	}
+
+	// A key the backend no longer has is dropped once it turns stale.
+	if _, err := c.Get(ctx, "gone"); err != nil {
+		t.Fatal(err)
+	}
+	if !eventually(func() bool { _, err := c.get(ctx, "gone"); return errors.Is(err, ErrNotFound) }) {
+		t.Error("key deleted from the backend still cached")
+	}
+}
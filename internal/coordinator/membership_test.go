@@ -0,0 +1,144 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+)
+
+// holds reports whether n stores key, asking the node itself so it works
+// for nodes that have left the ring.
+func (n *testNode) holds(key string) bool {
+	res, err := n.node.Get(context.Background(), &cacheNodepb.GetRequest{Key: key})
+	return err == nil && res.Found
+}
+
+// setKeys writes keys through c, each with its own name as the value.
+func setKeys(t *testing.T, c *Coordinator, keys []string) {
+	t.Helper()
+	ctx := WithConsistency(context.Background(), All)
+	for _, k := range keys {
+		if err := c.Set(ctx, k, []byte(k), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// misplaced returns a key some node holds without being one of its
+// replicas, or is missing from a replica, or "" if every key is exactly
+// where the ring places it.
+func misplaced(c *Coordinator, nodes []*testNode, keys []string) string {
+	for _, k := range keys {
+		for _, n := range nodes {
+			if n.holds(k) != c.ring.isReplica(n.addr, k) {
+				return fmt.Sprintf("%s on %s", k, n.addr)
+			}
+		}
+	}
+	return ""
+}
+
+func TestAddNodeMigratesData(t *testing.T) {
+	for _, replicas := range []int{1, 2} {
+		t.Run(fmt.Sprint(replicas), func(t *testing.T) {
+			nodes := startTestNodes(t, 3)
+			c := newTestCoordinator(t, nodes[:2], Config{Replicas: replicas})
+			keys := testKeys(200)
+			setKeys(t, c, keys)
+
+			if err := c.AddNode(nodes[2].addr, 1); err != nil {
+				t.Fatal(err)
+			}
+			// Keys are copied to the new node in the background. A source
+			// keeps its copy only while it is still a replica of the key.
+			deadline := time.Now().Add(5 * time.Second)
+			for misplaced(c, nodes, keys) != "" && time.Now().Before(deadline) {
+				time.Sleep(20 * time.Millisecond)
+			}
+			if k := misplaced(c, nodes, keys); k != "" {
+				t.Fatalf("after adding a node: %s", k)
+			}
+			for _, k := range keys {
+				if it, err := c.Get(context.Background(), k); err != nil || string(it.Value) != k {
+					t.Fatalf("Get(%s) = %q, %v", k, it.Value, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDrainNode(t *testing.T) {
+	for _, replicas := range []int{1, 2} {
+		t.Run(fmt.Sprint(replicas), func(t *testing.T) {
+			nodes := startTestNodes(t, 3)
+			c := newTestCoordinator(t, nodes, Config{Replicas: replicas})
+			keys := testKeys(200)
+			setKeys(t, c, keys)
+
+			drained := nodes[0]
+			if err := c.DrainNode(drained.addr); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := c.ring.member(drained.addr); ok {
+				t.Fatal("drained node is still on the ring")
+			}
+			// DrainNode returns once the keys are copied, so every key is
+			// already on all of its new replicas.
+			if k := misplaced(c, nodes[1:], keys); k != "" {
+				t.Fatalf("after draining: %s", k)
+			}
+			for _, k := range keys {
+				if it, err := c.Get(context.Background(), k); err != nil || string(it.Value) != k {
+					t.Fatalf("Get(%s) = %q, %v", k, it.Value, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoveNode(t *testing.T) {
+	nodes := startTestNodes(t, 3)
+	c := newTestCoordinator(t, nodes, Config{Replicas: 2})
+	keys := testKeys(200)
+	setKeys(t, c, keys)
+
+	// Nothing is moved, but every key still has its other replica.
+	nodes[0].stop()
+	if err := c.RemoveNode(nodes[0].addr); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		if it, err := c.Get(context.Background(), k); err != nil || string(it.Value) != k {
+			t.Fatalf("Get(%s) = %q, %v", k, it.Value, err)
+		}
+	}
+}
+
+func TestRemoveNodeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove func(c *Coordinator, addr string) error
+	}{
+		{"drain", (*Coordinator).DrainNode},
+		{"remove", (*Coordinator).RemoveNode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes := startTestNodes(t, 2)
+			c := newTestCoordinator(t, nodes, Config{Replicas: 1})
+			if err := tt.remove(c, "127.0.0.1:1"); !errors.Is(err, ErrUnknownNode) {
+				t.Errorf("unknown node: %v, want %v", err, ErrUnknownNode)
+			}
+			if err := tt.remove(c, nodes[0].addr); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.remove(c, nodes[1].addr); !errors.Is(err, ErrLastNode) {
+				t.Errorf("last node: %v, want %v", err, ErrLastNode)
+			}
+		})
+	}
+}
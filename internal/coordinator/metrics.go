@@ -0,0 +1,80 @@
+package coordinator
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+)
+
+// Per-node metrics for every RPC the coordinator sends, heartbeats and
+// migrations included.
+var (
+	nodeRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cachy_coordinator_node_requests_total",
+		Help: "RPCs sent to cache nodes, by node and method.",
+	}, []string{"node", "method"})
+	nodeErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cachy_coordinator_node_errors_total",
+		Help: "RPCs to cache nodes that failed, by node and method.",
+	}, []string{"node", "method"})
+	nodeRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "cachy_coordinator_node_request_duration_seconds",
+		Help:    "Round trip time of RPCs to cache nodes, by node and method.",
+		Buckets: prometheus.ExponentialBuckets(0.0001, 2, 16), // 100µs to ~3.3s
+	}, []string{"node", "method"})
+
+	loadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cachy_coordinator_loads_total",
+		Help: "Cache misses sent to the loader, by result (hit, miss or error).",
+	}, []string{"result"})
+	backendWritesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cachy_coordinator_backend_writes_total",
+		Help: "Write and delete calls to the loader backend after retries, by result (ok or error).",
+	}, []string{"result"})
+	writeBehindPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cachy_coordinator_write_behind_pending",
+		Help: "Keys waiting to be written to the backend.",
+	})
+
+	repairsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cachy_coordinator_repairs_total",
+		Help: "Stale or missing replicas overwritten with the newest version, by source (read or anti_entropy).",
+	}, []string{"source"})
+
+	hintsPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "cachy_coordinator_hints_pending",
+		Help: "Writes handed off to another node, waiting to be replayed to their owner.",
+	})
+	hintsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cachy_coordinator_hints_total",
+		Help: "Hinted handoffs, by result (stored, replayed or dropped).",
+	}, []string{"result"})
+)
+
+// observeNode returns a client interceptor that records every RPC sent to
+// addr.
+func observeNode(addr string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		name := path.Base(method)
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		nodeRequestDuration.WithLabelValues(addr, name).Observe(time.Since(start).Seconds())
+		nodeRequestsTotal.WithLabelValues(addr, name).Inc()
+		if err != nil {
+			nodeErrorsTotal.WithLabelValues(addr, name).Inc()
+		}
+		return err
+	}
+}
+
+// forgetNode drops the series of a node that left the ring.
+func forgetNode(addr string) {
+	labels := prometheus.Labels{"node": addr}
+	nodeRequestsTotal.DeletePartialMatch(labels)
+	nodeErrorsTotal.DeletePartialMatch(labels)
+	nodeRequestDuration.DeletePartialMatch(labels)
+}
@@ -0,0 +1,267 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+)
+
+// replicaAnswer is one replica's answer to a read.
+type replicaAnswer struct {
+	n   node
+	res *cacheNodepb.GetResponse
+}
+
+// repairTimeout bounds the writes of one read repair.
+const repairTimeout = 5 * time.Second
+
+// newer reports whether res is a newer answer for a key than cur, which
+// may be nil. Answers are values or tombstones; a miss without a tombstone
+// is never newer. A tombstone wins a tie with a value.
+func newer(res, cur *cacheNodepb.GetResponse) bool {
+	if !res.Found && !res.Deleted {
+		return false
+	}
+	if cur == nil || res.Version > cur.Version {
+		return true
+	}
+	return res.Version == cur.Version && res.Deleted && !cur.Deleted
+}
+
+// readRepair copies the newest of answers onto the replicas that answered
+// with an older version or without the key. If the newest answer is a
+// tombstone, the delete is repeated on them instead, so a key deleted from
+// only some replicas is not brought back.
+func (c *Coordinator) readRepair(key string, answers []replicaAnswer) {
+	var newest *cacheNodepb.GetResponse
+	for _, a := range answers {
+		if newer(a.res, newest) {
+			newest = a.res
+		}
+	}
+	if newest == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), repairTimeout)
+	defer cancel()
+	for _, a := range answers {
+		if !newer(newest, a.res) {
+			continue
+		}
+		if err := c.repair(ctx, a.n, key, newest); err != nil {
+			c.log.Warn("read repair failed", "key", key, "node", a.n.addr, "err", err)
+			continue
+		}
+		repairsTotal.WithLabelValues("read").Inc()
+		c.log.Debug("read repair", "key", key, "node", a.n.addr, "version", newest.Version, "deleted", newest.Deleted)
+	}
+}
+
+// repair brings n up to res, copying its value or, for a tombstone,
+// deleting key at its version.
+func (c *Coordinator) repair(ctx context.Context, n node, key string, res *cacheNodepb.GetResponse) error {
+	if res.Deleted {
+		_, err := n.client.Delete(ctx, &cacheNodepb.DeleteRequest{Key: key, Version: res.Version})
+		return err
+	}
+	_, err := n.client.Set(ctx, copyRequest(key, res))
+	return err
+}
+
+// Anti-entropy compares replicas with Merkle trees of this depth, so a
+// difference narrows the keys that are scanned to 1/1024 of a replica set.
+const antiEntropyDepth = 10
+
+// startAntiEntropy runs RunAntiEntropy every interval until the ring is
+// closed.
+func (c *Coordinator) startAntiEntropy(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.ring.stop:
+				return
+			case <-ticker.C:
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			c.RunAntiEntropy(ctx)
+			cancel()
+		}
+	}()
+}
+
+// RunAntiEntropy brings the replicas of every range in line with each
+// other. For each set of nodes sharing ranges, it compares their Merkle
+// trees, scans only the leaves that differ and copies the newest version of
+// each differing key onto the replicas behind, or repeats its delete if the
+// newest version is a tombstone. It returns how many copies were made.
+func (c *Coordinator) RunAntiEntropy(ctx context.Context) (int, error) {
+	start := time.Now()
+	repaired := 0
+	var errs []error
+	for _, set := range c.ring.replicaSets() {
+		n, err := c.syncReplicas(ctx, set)
+		repaired += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		c.log.Warn("anti-entropy incomplete", "repaired", repaired, "err", err)
+	} else {
+		c.log.Info("anti-entropy done", "repaired", repaired, "took", time.Since(start).Round(time.Millisecond))
+	}
+	return repaired, err
+}
+
+// syncReplicas runs anti-entropy over one replica set. Nodes that cannot be
+// reached are left out of the comparison.
+func (c *Coordinator) syncReplicas(ctx context.Context, set *replicaSet) (int, error) {
+	ranges := make([]*cacheNodepb.KeyRange, len(set.ranges))
+	for i, kr := range set.ranges {
+		ranges[i] = &cacheNodepb.KeyRange{Lo: kr.lo, Hi: kr.hi}
+	}
+
+	trees := make([][]uint64, len(set.nodes))
+	errs := make([]error, len(set.nodes))
+	var wg sync.WaitGroup
+	for i, n := range set.nodes {
+		wg.Add(1)
+		go func(i int, n node) {
+			defer wg.Done()
+			res, err := n.client.MerkleTree(ctx, &cacheNodepb.MerkleTreeRequest{Ranges: ranges, Depth: antiEntropyDepth})
+			if err != nil {
+				errs[i] = nodeError(n.addr, err)
+				return
+			}
+			trees[i] = res.Nodes
+		}(i, n)
+	}
+	wg.Wait()
+
+	var nodes []node
+	var reachable [][]uint64
+	for i, t := range trees {
+		if t != nil {
+			nodes = append(nodes, set.nodes[i])
+			reachable = append(reachable, t)
+		}
+	}
+	err := errors.Join(errs...)
+	if len(nodes) < 2 {
+		return 0, err
+	}
+	leaves := diffLeaves(reachable, antiEntropyDepth)
+	if len(leaves) == 0 {
+		return 0, err
+	}
+
+	// versions[key][i] is what nodes[i] holds, absent if nothing.
+	versions := make(map[string]map[int]*cacheNodepb.GetResponse)
+	for i, n := range nodes {
+		if serr := scan(ctx, n, &cacheNodepb.ScanRequest{Ranges: ranges, Depth: antiEntropyDepth, Leaves: leaves}, func(e *cacheNodepb.ScanEntry) {
+			if versions[e.Key] == nil {
+				versions[e.Key] = make(map[int]*cacheNodepb.GetResponse)
+			}
+			versions[e.Key][i] = &cacheNodepb.GetResponse{Found: !e.Deleted, Deleted: e.Deleted, Version: e.Version}
+		}); serr != nil {
+			return 0, errors.Join(err, nodeError(n.addr, serr))
+		}
+	}
+
+	repaired := 0
+	for key, held := range versions {
+		var newest *cacheNodepb.GetResponse
+		from := -1
+		for i, v := range held {
+			if newer(v, newest) {
+				newest, from = v, i
+			}
+		}
+		var behind []node
+		for i, n := range nodes {
+			if v, ok := held[i]; !ok || newer(newest, v) {
+				behind = append(behind, n)
+			}
+		}
+		if len(behind) == 0 {
+			continue
+		}
+		res := newest
+		if !newest.Deleted {
+			var gerr error
+			res, gerr = nodes[from].client.Get(ctx, &cacheNodepb.GetRequest{Key: key})
+			if gerr != nil || !res.Found {
+				continue // gone or unreachable since the scan
+			}
+		}
+		for _, n := range behind {
+			if serr := c.repair(ctx, n, key, res); serr != nil {
+				c.log.Warn("anti-entropy repair failed", "key", key, "node", n.addr, "err", serr)
+				continue
+			}
+			repaired++
+			repairsTotal.WithLabelValues("anti_entropy").Inc()
+		}
+	}
+	return repaired, err
+}
+
+// scan calls fn for every entry n streams back for req.
+func scan(ctx context.Context, n node, req *cacheNodepb.ScanRequest, fn func(e *cacheNodepb.ScanEntry)) error {
+	stream, err := n.client.Scan(ctx, req)
+	if err != nil {
+		return err
+	}
+	for {
+		e, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(e)
+	}
+}
+
+// diffLeaves walks trees of the given depth, laid out in heap order, from
+// the root down and returns the leaves on which they do not all agree.
+// Subtrees whose roots agree are skipped.
+func diffLeaves(trees [][]uint64, depth uint32) []uint32 {
+	first := 1<<depth - 1
+	var leaves []uint32
+	stack := []int{0}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		same := true
+		for _, t := range trees[1:] {
+			if len(t) != len(trees[0]) {
+				return nil // not built with the same depth
+			}
+			if t[i] != trees[0][i] {
+				same = false
+				break
+			}
+		}
+		switch {
+		case same:
+		case i >= first:
+			leaves = append(leaves, uint32(i-first))
+		default:
+			stack = append(stack, 2*i+1, 2*i+2)
+		}
+	}
+	return leaves
+}
@@ -0,0 +1,155 @@
+// Package logging builds the structured loggers used by the server and the
+// cache nodes.
+//
+// Per-request records are logged at debug level and can be sampled. Cached
+// values are attached with Value and are replaced by their length unless
+// values are explicitly enabled.
+package logging
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// Options configure a logger.
+type Options struct {
+	Level  slog.Level
+	Format Format
+	// Sample keeps one in every Sample records below info level; 0 or 1
+	// keeps them all. Info and above are never sampled.
+	Sample int
+	// LogValues logs cached values in full instead of only their length.
+	LogValues bool
+}
+
+// New returns a logger writing to w.
+func New(w io.Writer, opts Options) *slog.Logger {
+	hopts := &slog.HandlerOptions{
+		Level:       opts.Level,
+		ReplaceAttr: redact(opts.LogValues),
+	}
+	var h slog.Handler
+	if opts.Format == FormatJSON {
+		h = slog.NewJSONHandler(w, hopts)
+	} else {
+		h = slog.NewTextHandler(w, hopts)
+	}
+	if opts.Sample > 1 {
+		h = &sampler{Handler: h, every: uint64(opts.Sample), n: new(atomic.Uint64)}
+	}
+	return slog.New(h)
+}
+
+// ParseLevel accepts debug, info, warn or error.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+	return l, nil
+}
+
+// ParseFormat accepts text or json.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown log format %q", s)
+}
+
+// Flags are the logging command line flags shared by the binaries.
+type Flags struct {
+	level, format string
+	sample        int
+	values        bool
+}
+
+// RegisterFlags defines the logging flags on fs.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{}
+	fs.StringVar(&f.level, "log-level", "info", "minimum level to log: debug, info, warn or error")
+	fs.StringVar(&f.format, "log-format", string(FormatText), "log output format: text or json")
+	fs.IntVar(&f.sample, "log-sample", 1, "log one in every N per-request (debug) records")
+	fs.BoolVar(&f.values, "log-values", false, "include cached values in logs instead of only their length")
+	return f
+}
+
+// Logger builds a logger writing to stderr from the parsed flags.
+func (f *Flags) Logger() (*slog.Logger, error) {
+	level, err := ParseLevel(f.level)
+	if err != nil {
+		return nil, err
+	}
+	format, err := ParseFormat(f.format)
+	if err != nil {
+		return nil, err
+	}
+	return New(os.Stderr, Options{Level: level, Format: format, Sample: f.sample, LogValues: f.values}), nil
+}
+
+// Fatal logs msg at error level and exits.
+func Fatal(l *slog.Logger, msg string, args ...any) {
+	l.Error(msg, args...)
+	os.Exit(1)
+}
+
+// value is a cached value attached to a record. It is only written out when
+// the logger allows values.
+type value []byte
+
+// Value returns an attribute carrying a cached value.
+func Value(v []byte) slog.Attr {
+	return slog.Any("value", value(v))
+}
+
+func redact(logValues bool) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() != slog.KindAny {
+			return a
+		}
+		v, ok := a.Value.Any().(value)
+		if !ok {
+			return a
+		}
+		if logValues {
+			return slog.String(a.Key, string(v))
+		}
+		return slog.Int(a.Key+"_len", len(v))
+	}
+}
+
+// sampler passes through one in every few debug records.
+type sampler struct {
+	slog.Handler
+	every uint64
+	n     *atomic.Uint64 // shared with derived handlers
+}
+
+func (s *sampler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelInfo && s.n.Add(1)%s.every != 0 {
+		return nil
+	}
+	return s.Handler.Handle(ctx, r)
+}
+
+func (s *sampler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampler{Handler: s.Handler.WithAttrs(attrs), every: s.every, n: s.n}
+}
+
+func (s *sampler) WithGroup(name string) slog.Handler {
+	return &sampler{Handler: s.Handler.WithGroup(name), every: s.every, n: s.n}
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestValueRedaction(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		log  func(l *slog.Logger)
+		want string
+	}{
+		{"text", Options{}, func(l *slog.Logger) { l.Info("set", "key", "k", Value([]byte("secret"))) }, "value_len=6"},
+		{"json", Options{Format: FormatJSON}, func(l *slog.Logger) { l.Info("set", Value([]byte("secret"))) }, `"value_len":6`},
+		{"with", Options{}, func(l *slog.Logger) { l.With(Value([]byte("secret"))).Info("set") }, "value_len=6"},
+		{"group", Options{}, func(l *slog.Logger) { l.WithGroup("req").Info("set", Value([]byte("secret"))) }, "req.value_len=6"},
+		{"values on", Options{LogValues: true}, func(l *slog.Logger) { l.Info("set", Value([]byte("secret"))) }, "value=secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(New(&buf, tt.opts))
+			out := buf.String()
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("logged %q, want it to contain %q", out, tt.want)
+			}
+			if strings.Contains(out, "secret") != tt.opts.LogValues {
+				t.Errorf("logged %q, value shown = %v", out, !tt.opts.LogValues)
+			}
+		})
+	}
+}
+
+func TestSampling(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Options{Level: slog.LevelDebug, Sample: 3})
+
+	// One in three debug records is kept, counted across derived loggers;
+	// info and above always are.
+	derived := l.With("service", "test")
+	for i := range 9 {
+		if i%2 == 0 {
+			l.Debug("request")
+		} else {
+			derived.Debug("request")
+		}
+	}
+	for range 4 {
+		l.Info("event")
+		l.Warn("problem")
+	}
+	out := buf.String()
+	if n := strings.Count(out, "msg=request"); n != 3 {
+		t.Errorf("%d of 9 debug records logged, want 3", n)
+	}
+	if n := strings.Count(out, "msg=event") + strings.Count(out, "msg=problem"); n != 8 {
+		t.Errorf("%d of 8 info and warn records logged, want all", n)
+	}
+
+	buf.Reset()
+	l = New(&buf, Options{Level: slog.LevelDebug, Sample: 1})
+	for range 5 {
+		l.Debug("request")
+	}
+	if n := strings.Count(buf.String(), "msg=request"); n != 5 {
+		t.Errorf("%d of 5 debug records logged without sampling", n)
+	}
+}
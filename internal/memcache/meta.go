@@ -0,0 +1,369 @@
+package memcache
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+// metaFlags are the single-letter flags of a meta command, each with an
+// optional token, in the order the client sent them.
+type metaFlags []string
+
+// parseMetaFlags checks every flag against allowed. Base64 keys (b) are not
+// supported.
+func parseMetaFlags(args []string, allowed string) (metaFlags, error) {
+	for _, f := range args {
+		if f == "" || !strings.ContainsRune(allowed, rune(f[0])) {
+			return nil, errors.New("invalid flag")
+		}
+	}
+	return metaFlags(args), nil
+}
+
+func (fs metaFlags) has(flag byte) bool {
+	_, ok := fs.get(flag)
+	return ok
+}
+
+func (fs metaFlags) get(flag byte) (string, bool) {
+	for _, f := range fs {
+		if f[0] == flag {
+			return f[1:], true
+		}
+	}
+	return "", false
+}
+
+// ret builds the return flags the client asked for, in the order it asked.
+// k and O are echoed back; the others describe it.
+func (fs metaFlags) ret(key string, it coordinator.Item) string {
+	var sb strings.Builder
+	for _, f := range fs {
+		switch f[0] {
+		case 'k':
+			fmt.Fprintf(&sb, " k%s", key)
+		case 'O':
+			fmt.Fprintf(&sb, " O%s", f[1:])
+		case 'c':
+			fmt.Fprintf(&sb, " c%d", casToken(it))
+		case 'f':
+			fmt.Fprintf(&sb, " f%d", it.Flags)
+		case 's':
+			fmt.Fprintf(&sb, " s%d", len(it.Value))
+		case 't':
+			// -1 for an item that never expires
+			fmt.Fprintf(&sb, " t%d", cmp.Or(it.TTLSeconds(), -1))
+		}
+	}
+	return sb.String()
+}
+
+// echo returns only the k and O flags, for misses and failures.
+func (fs metaFlags) echo(key string) string {
+	var sb strings.Builder
+	for _, f := range fs {
+		switch f[0] {
+		case 'k':
+			fmt.Fprintf(&sb, " k%s", key)
+		case 'O':
+			fmt.Fprintf(&sb, " O%s", f[1:])
+		}
+	}
+	return sb.String()
+}
+
+func (fs metaFlags) uint(flag byte, def uint64) (uint64, error) {
+	tok, ok := fs.get(flag)
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.ParseUint(tok, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad token in command line format")
+	}
+	return n, nil
+}
+
+// mg <key> <flags>*
+func (s *Server) cmdMetaGet(c *conn, args []string) {
+	if len(args) < 2 || !validKey(args[1]) {
+		c.clientError(errClient.Error())
+		return
+	}
+	key := args[1]
+	fs, err := parseMetaFlags(args[2:], "cfkOqstTv")
+	if err != nil {
+		c.clientError(err.Error())
+		return
+	}
+	quiet := fs.has('q')
+
+	var it coordinator.Item
+	if tok, ok := fs.get('T'); ok {
+		ttl, expired, perr := parseExptime(tok)
+		if perr != nil {
+			c.clientError("bad token in command line format")
+			return
+		}
+		it, err = s.touch(c, key, ttl, expired)
+	} else {
+		it, err = s.cd.Get(c.ctx, key)
+	}
+	switch {
+	case errors.Is(err, coordinator.ErrNotFound):
+		c.reply(quiet, "EN")
+		return
+	case err != nil:
+		c.cacheError(err)
+		return
+	}
+
+	if fs.has('v') {
+		fmt.Fprintf(c.bw, "VA %d%s\r\n", len(it.Value), fs.ret(key, it))
+		c.bw.Write(it.Value)
+		c.bw.WriteString("\r\n")
+		return
+	}
+	c.reply(false, "HD"+fs.ret(key, it))
+}
+
+// ms <key> <datalen> <flags>*
+//
+// Add, replace and the C compare flag are conditional writes, decided
+// atomically by the key's owner. Only append and prepend read the current
+// item first, then write it back with a compare-and-set on the version
+// read.
+func (s *Server) cmdMetaSet(c *conn, args []string) error {
+	if len(args) < 3 {
+		c.clientError(errClient.Error())
+		return nil
+	}
+	size, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil || size < 0 {
+		return errClient
+	}
+	if size > s.maxItemSize {
+		return s.skipData(c, size)
+	}
+	data, err := c.readData(size)
+	if err != nil {
+		return err
+	}
+
+	key := args[1]
+	fs, err := parseMetaFlags(args[3:], "cCFkOqsTM")
+	if err != nil || !validKey(key) {
+		c.clientError(errClient.Error())
+		return nil
+	}
+	quiet := fs.has('q')
+	flags, ferr := fs.uint('F', 0)
+	cas, cerr := fs.uint('C', 0)
+	if ferr != nil || cerr != nil || flags > 1<<32-1 {
+		c.clientError("bad token in command line format")
+		return nil
+	}
+	var (
+		ttl     time.Duration
+		expired bool
+	)
+	if tok, ok := fs.get('T'); ok {
+		if ttl, expired, err = parseExptime(tok); err != nil {
+			c.clientError("bad token in command line format")
+			return nil
+		}
+	}
+	mode, _ := fs.get('M')
+	if mode == "" {
+		mode = "S"
+	}
+	if !strings.Contains("SEARPseap", mode) || len(mode) != 1 {
+		c.clientError("invalid mode for ms")
+		return nil
+	}
+	mode = strings.ToUpper(mode)
+
+	e := coordinator.Entry{Key: key, Value: data, Flags: uint32(flags), TTL: ttl}
+	hasCAS := fs.has('C')
+	var unique uint64
+	switch {
+	case mode == "A" || mode == "P":
+		e, unique, err = s.concat(c, e, mode == "P", hasCAS, cas)
+	case mode == "E":
+		unique, err = s.storeIf(c, condAbsent, e, expired, 0)
+	case hasCAS:
+		unique, err = s.storeIf(c, condCAS, e, expired, cas)
+	case mode == "R":
+		unique, err = s.storeIf(c, condPresent, e, expired, 0)
+	default:
+		unique, err = s.store(c, e, expired)
+	}
+	switch {
+	case err == nil:
+		c.reply(quiet, "HD"+fs.ret(key, coordinator.Item{Value: e.Value, Flags: e.Flags, TTL: e.TTL, Version: int64(unique)}))
+	case hasCAS && mode != "E" && errors.Is(err, coordinator.ErrNotFound):
+		c.reply(false, "NF"+fs.echo(key))
+	case hasCAS && mode != "E" && errors.Is(err, coordinator.ErrConflict):
+		c.reply(false, "EX"+fs.echo(key))
+	case errors.Is(err, coordinator.ErrNotFound), errors.Is(err, coordinator.ErrConflict):
+		c.reply(false, "NS"+fs.echo(key))
+	default:
+		c.cacheError(err)
+	}
+	return nil
+}
+
+// casRetries bounds how often a read followed by a compare-and-set, as in
+// append, prepend and touch, is retried when the item changes in between.
+const casRetries = 8
+
+// concat appends (or prepends) e's value to the stored item, keeping its
+// flags and ttl, and writes it back only if the item is unchanged since it
+// was read. With hasCAS the item must also be at cas, and a change is not
+// retried. It returns the entry written and its cas unique.
+func (s *Server) concat(c *conn, e coordinator.Entry, prepend, hasCAS bool, cas uint64) (coordinator.Entry, uint64, error) {
+	for attempt := 0; ; attempt++ {
+		cur, err := s.cd.Get(c.ctx, e.Key)
+		if err != nil {
+			return e, 0, err
+		}
+		if hasCAS && casToken(cur) != cas {
+			return e, 0, coordinator.ErrConflict
+		}
+		// cur.Value may be shared with concurrent readers.
+		next := coordinator.Entry{Key: e.Key, Value: slices.Concat(cur.Value, e.Value), Flags: cur.Flags, TTL: cur.TTL}
+		if prepend {
+			next.Value = slices.Concat(e.Value, cur.Value)
+		}
+		unique, err := s.storeIf(c, condCAS, next, false, casToken(cur))
+		if errors.Is(err, coordinator.ErrConflict) && !hasCAS && attempt < casRetries {
+			continue
+		}
+		return next, unique, err
+	}
+}
+
+// md <key> <flags>*
+func (s *Server) cmdMetaDelete(c *conn, args []string) {
+	if len(args) < 2 || !validKey(args[1]) {
+		c.clientError(errClient.Error())
+		return
+	}
+	key := args[1]
+	fs, err := parseMetaFlags(args[2:], "CkOq")
+	if err != nil {
+		c.clientError(err.Error())
+		return
+	}
+	quiet := fs.has('q')
+
+	if tok, ok := fs.get('C'); ok {
+		cas, err := strconv.ParseUint(tok, 10, 64)
+		if err != nil {
+			c.clientError("bad token in command line format")
+			return
+		}
+		_, err = s.cd.CompareAndDelete(c.ctx, key, int64(cas))
+		switch {
+		case errors.Is(err, coordinator.ErrNotFound):
+			c.reply(quiet, "NF"+fs.echo(key))
+		case errors.Is(err, coordinator.ErrConflict):
+			c.reply(false, "EX"+fs.echo(key))
+		case err != nil:
+			c.cacheError(err)
+		default:
+			c.reply(quiet, "HD"+fs.echo(key))
+		}
+		return
+	}
+
+	found, err := s.cd.Delete(c.ctx, key)
+	switch {
+	case err != nil:
+		c.cacheError(err)
+	case found:
+		c.reply(quiet, "HD"+fs.echo(key))
+	default:
+		c.reply(quiet, "NF"+fs.echo(key))
+	}
+}
+
+// ma <key> <flags>*
+func (s *Server) cmdMetaArith(c *conn, args []string) {
+	if len(args) < 2 || !validKey(args[1]) {
+		c.clientError(errClient.Error())
+		return
+	}
+	key := args[1]
+	fs, err := parseMetaFlags(args[2:], "NJDTMqOktcv")
+	if err != nil {
+		c.clientError(err.Error())
+		return
+	}
+	quiet := fs.has('q')
+	delta, derr := fs.uint('D', 1)
+	initial, jerr := fs.uint('J', 0)
+	if derr != nil || jerr != nil {
+		c.clientError("bad token in command line format")
+		return
+	}
+	incr := true
+	if mode, ok := fs.get('M'); ok {
+		switch mode {
+		case "I", "i", "+":
+		case "D", "d", "-":
+			incr = false
+		default:
+			c.clientError("invalid mode for ma")
+			return
+		}
+	}
+	var viv *vivify
+	if tok, ok := fs.get('N'); ok {
+		ttl, _, err := parseExptime(tok)
+		if err != nil {
+			c.clientError("bad token in command line format")
+			return
+		}
+		viv = &vivify{initial: initial, ttl: ttl}
+	}
+
+	n, it, err := s.arith(c, key, incr, delta, viv)
+	switch {
+	case errors.Is(err, coordinator.ErrNotFound):
+		c.reply(quiet, "NF"+fs.echo(key))
+		return
+	case errors.Is(err, errNonNumeric):
+		c.clientError(err.Error())
+		return
+	case err != nil:
+		c.cacheError(err)
+		return
+	}
+
+	if tok, ok := fs.get('T'); ok {
+		ttl, expired, err := parseExptime(tok)
+		if err != nil {
+			c.clientError("bad token in command line format")
+			return
+		}
+		if it, err = s.touch(c, key, ttl, expired); err != nil {
+			c.cacheError(err)
+			return
+		}
+	}
+
+	if fs.has('v') {
+		v := strconv.FormatUint(n, 10)
+		fmt.Fprintf(c.bw, "VA %d%s\r\n%s\r\n", len(v), fs.ret(key, it), v)
+		return
+	}
+	c.reply(quiet, "HD"+fs.ret(key, it))
+}
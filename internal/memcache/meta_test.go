@@ -0,0 +1,74 @@
+package memcache
+
+import "testing"
+
+func TestMetaCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"ms and mg", []step{
+			{"ms k 2 F7 T100\r\nhi\r\n", "HD\r\n"},
+			{"mg k v f t s\r\n", "VA 2 f7 t100 s2\r\nhi\r\n"},
+			{"mg k k O123 c\r\n", "HD kk O123 c{cas}\r\n"},
+			{"mg nope v\r\n", "EN\r\n"},
+			{"mg nope v q\r\nmn\r\n", "MN\r\n"},
+			{"ms n 1 q\r\na\r\nmg n t v\r\n", "VA 1 t-1\r\na\r\n"},
+		}},
+		{"mg touch", []step{
+			{"ms k 1\r\na\r\n", "HD\r\n"},
+			{"mg k T50 t\r\n", "HD t50\r\n"},
+			{"mg k T-1\r\n", "HD\r\n"},
+			{"mg k\r\n", "EN\r\n"},
+		}},
+		{"ms modes", []step{
+			{"ms k 1 MR\r\na\r\n", "NS\r\n"},
+			{"ms k 1 ME\r\na\r\n", "HD\r\n"},
+			{"ms k 1 ME\r\nb\r\n", "NS\r\n"},
+			{"ms k 1 MA\r\nb\r\n", "HD\r\n"},
+			{"ms k 1 MP\r\nz\r\n", "HD\r\n"},
+			{"mg k v\r\n", "VA 3\r\nzab\r\n"},
+			{"ms k 1 MR\r\nr\r\n", "HD\r\n"},
+			{"ms k 1 MX\r\nr\r\n", "CLIENT_ERROR invalid mode for ms\r\n"},
+		}},
+		{"ms compare", []step{
+			{"ms k 1 C1\r\na\r\n", "NF\r\n"},
+			{"ms k 1 c\r\na\r\n", "HD c{cas}\r\n"},
+			{"ms k 1 C{cas} k\r\nb\r\n", "HD kk\r\n"},
+			{"ms k 1 C{cas} k\r\nc\r\n", "EX kk\r\n"}, // the cas unique moved on
+			{"mg k v\r\n", "VA 1\r\nb\r\n"},
+		}},
+		{"md", []step{
+			{"md k\r\n", "NF\r\n"},
+			{"ms k 1\r\na\r\n", "HD\r\n"},
+			{"mg k c\r\n", "HD c{cas}\r\n"},
+			{"ms k 1\r\nb\r\n", "HD\r\n"},
+			{"md k C{cas} q\r\nmn\r\n", "EX\r\nMN\r\n"}, // failures are not quieted
+			{"mg k c\r\n", "HD c{cas}\r\n"},
+			{"md k C{cas} k\r\n", "HD kk\r\n"},
+			{"md k q\r\nmn\r\n", "MN\r\n"},
+		}},
+		{"ma", []step{
+			{"ma n\r\n", "NF\r\n"},
+			{"ma n N0 J5 v\r\n", "VA 1\r\n5\r\n"},
+			{"ma n D3 v\r\n", "VA 1\r\n8\r\n"},
+			{"ma n MD D10 v\r\n", "VA 1\r\n0\r\n"},
+			{"ma n q\r\nmn\r\n", "MN\r\n"},
+			{"ma n T100 t v\r\n", "VA 1 t100\r\n2\r\n"},
+			{"ma n MX\r\n", "CLIENT_ERROR invalid mode for ma\r\n"},
+			{"ms s 1\r\na\r\n", "HD\r\n"},
+			{"ma s\r\n", "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"},
+		}},
+		{"bad flags", []step{
+			{"mg k x\r\n", "CLIENT_ERROR invalid flag\r\n"},
+			{"ms k 1 Fx\r\na\r\n", "CLIENT_ERROR bad token in command line format\r\n"},
+			{"ma n Dx\r\n", "CLIENT_ERROR bad token in command line format\r\n"},
+			{"mn\r\n", "MN\r\n"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run(t, 1<<20, tt.steps)
+		})
+	}
+}
@@ -0,0 +1,273 @@
+// Package memcache serves the coordinator over the memcached text protocol,
+// including the meta commands, so memcached clients can use a cachy cluster
+// by pointing at a new address.
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+const (
+	// maxKeyLen is memcached's key length limit.
+	maxKeyLen = 250
+	// relativeExptimeMax is the largest exptime memcached treats as a number
+	// of seconds; anything larger is a unix timestamp.
+	relativeExptimeMax = 60 * 60 * 24 * 30
+)
+
+// errClient is a malformed request. The client is told and the connection
+// stays open unless the data block could not be framed.
+var errClient = errors.New("bad command line format")
+
+// errTooLarge closes the connection for a data block too far over the item
+// size limit to be worth reading through.
+var errTooLarge = errors.New("object too large for cache")
+
+// Server accepts memcached connections and forwards their commands to a
+// Coordinator.
+type Server struct {
+	cd          *coordinator.Coordinator
+	maxItemSize int64 // largest data block a client may send
+}
+
+func NewServer(cd *coordinator.Coordinator, maxItemSize int64) *Server {
+	return &Server{cd: cd, maxItemSize: maxItemSize}
+}
+
+// ListenAndServe listens on addr and serves connections until the listener
+// fails.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("memcache listening", "addr", ln.Addr().String())
+	return s.Serve(ln)
+}
+
+func (s *Server) Serve(ln net.Listener) error {
+	defer ln.Close()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// conn is the per-connection state.
+type conn struct {
+	ctx context.Context // cancelled once the connection is closed
+	br  *bufio.Reader
+	bw  *bufio.Writer
+}
+
+func (s *Server) serveConn(nc net.Conn) {
+	defer nc.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &conn{
+		ctx: ctx,
+		br:  bufio.NewReaderSize(nc, 8<<10),
+		bw:  bufio.NewWriter(nc),
+	}
+
+	for {
+		line, err := c.br.ReadSlice('\n')
+		if err != nil {
+			if errors.Is(err, bufio.ErrBufferFull) {
+				c.bw.WriteString("CLIENT_ERROR line too long\r\n")
+				c.bw.Flush()
+			} else if !errors.Is(err, io.EOF) {
+				slog.Debug("memcache connection closed", "remote", nc.RemoteAddr().String(), "err", err)
+			}
+			return
+		}
+		args := splitLine(line)
+		if len(args) == 0 {
+			c.bw.WriteString("ERROR\r\n")
+			continue
+		}
+
+		quit, err := s.dispatch(c, args)
+		if err != nil {
+			// The data block of a storage command could not be read, or was
+			// too large to read through, so the stream is out of step with
+			// the client.
+			fmt.Fprintf(c.bw, "CLIENT_ERROR %v\r\n", err)
+			c.bw.Flush()
+			return
+		}
+
+		// Flushed only once the client has sent nothing further, as the RESP
+		// server does.
+		if quit || c.br.Buffered() == 0 {
+			if err := c.bw.Flush(); err != nil {
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// dispatch runs one command. It reports whether the connection should be
+// closed, and returns an error only when the connection can not continue.
+func (s *Server) dispatch(c *conn, args []string) (quit bool, err error) {
+	switch args[0] {
+	case "get", "gets":
+		s.cmdGet(c, args)
+	case "set", "add", "replace", "cas":
+		return false, s.cmdStore(c, args)
+	case "delete":
+		s.cmdDelete(c, args)
+	case "incr", "decr":
+		s.cmdArith(c, args)
+	case "touch":
+		s.cmdTouch(c, args)
+	case "mg":
+		s.cmdMetaGet(c, args)
+	case "ms":
+		return false, s.cmdMetaSet(c, args)
+	case "md":
+		s.cmdMetaDelete(c, args)
+	case "ma":
+		s.cmdMetaArith(c, args)
+	case "mn":
+		c.bw.WriteString("MN\r\n")
+	case "version":
+		c.bw.WriteString("VERSION cachy\r\n")
+	case "verbosity":
+		c.reply(noreply(args), "OK")
+	case "quit":
+		return true, nil
+	default:
+		c.bw.WriteString("ERROR\r\n")
+	}
+	return false, nil
+}
+
+// reply writes msg unless the client asked for no reply.
+func (c *conn) reply(quiet bool, msg string) {
+	if quiet {
+		return
+	}
+	c.bw.WriteString(msg)
+	c.bw.WriteString("\r\n")
+}
+
+func (c *conn) clientError(msg string) {
+	fmt.Fprintf(c.bw, "CLIENT_ERROR %s\r\n", msg)
+}
+
+// cacheError reports a coordinator failure.
+func (c *conn) cacheError(err error) {
+	if errors.Is(err, coordinator.ErrEntryTooLarge) {
+		c.bw.WriteString("SERVER_ERROR object too large for cache\r\n")
+		return
+	}
+	fmt.Fprintf(c.bw, "SERVER_ERROR %v\r\n", err)
+}
+
+// skipData reads past the data block of an item over the size limit, of n
+// bytes followed by CRLF, and tells the client. A block declared more than
+// twice the limit is not read at all: that could take arbitrarily long, so
+// the connection is closed instead.
+func (s *Server) skipData(c *conn, n int64) error {
+	if n/2 > s.maxItemSize {
+		return errTooLarge
+	}
+	if _, err := c.br.Discard(int(n) + 2); err != nil {
+		return err
+	}
+	c.bw.WriteString("SERVER_ERROR object too large for cache\r\n")
+	return nil
+}
+
+// readData reads a data block of n bytes followed by CRLF.
+func (c *conn) readData(n int64) ([]byte, error) {
+	buf := make([]byte, n+2)
+	if _, err := io.ReadFull(c.br, buf); err != nil {
+		return nil, err
+	}
+	if buf[n] != '\r' || buf[n+1] != '\n' {
+		return nil, errors.New("bad data chunk")
+	}
+	return buf[:n], nil
+}
+
+// splitLine splits a command line on spaces, dropping the line terminator.
+func splitLine(line []byte) []string {
+	var args []string
+	start := -1
+	for i, b := range line {
+		if b == ' ' || b == '\r' || b == '\n' {
+			if start >= 0 {
+				args = append(args, string(line[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		args = append(args, string(line[start:]))
+	}
+	return args
+}
+
+func noreply(args []string) bool {
+	return args[len(args)-1] == "noreply"
+}
+
+func validKey(key string) bool {
+	if len(key) == 0 || len(key) > maxKeyLen {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// parseExptime converts a memcached expiration time into a ttl. expired is
+// true for times already in the past, which memcached treats as an
+// immediate expiry.
+func parseExptime(s string) (ttl time.Duration, expired bool, err error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false, errClient
+	}
+	switch {
+	case n == 0:
+		return 0, false, nil
+	case n < 0:
+		return 0, true, nil
+	case n <= relativeExptimeMax:
+		return time.Duration(n) * time.Second, false, nil
+	}
+	ttl = time.Until(time.Unix(n, 0))
+	return ttl, ttl <= 0, nil
+}
+
+// casToken is an item's cas unique: its version, which the cache nodes
+// raise on every write of the key.
+func casToken(it coordinator.Item) uint64 {
+	return uint64(it.Version)
+}
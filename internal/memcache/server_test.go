@@ -0,0 +1,130 @@
+package memcache
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/cache"
+	"github.com/sakshamg567/cachy/internal/coordinator"
+	"github.com/sakshamg567/cachy/shared/proto/cacheNodepb"
+	"google.golang.org/grpc"
+)
+
+// step sends a request and expects a reply. {cas} in a reply matches a
+// number, which replaces {cas} in the requests after it.
+type step struct {
+	send, want string
+}
+
+// hangup is the reply of a connection the server has closed.
+const hangup = "<closed>"
+
+// run plays steps against a one-node cluster served over the memcached
+// protocol with the given item size limit.
+func run(t *testing.T, maxItemSize int64, steps []step) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	cacheNodepb.RegisterCacheServer(srv, cache.NewCacheNode(cache.Options{Capacity: 100}))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	cd, err := coordinator.NewCoordinator(coordinator.Config{
+		Nodes:    []coordinator.Member{{Addr: lis.Addr().String(), Weight: 1}},
+		Replicas: 1,
+		Vnodes:   16,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cd.Close)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go NewServer(cd, maxItemSize).Serve(ln)
+	t.Cleanup(func() { ln.Close() })
+
+	nc, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nc.Close() })
+	nc.SetDeadline(time.Now().Add(10 * time.Second))
+	br := bufio.NewReader(nc)
+
+	cas := ""
+	for i, s := range steps {
+		_, err := io.WriteString(nc, strings.ReplaceAll(s.send, "{cas}", cas))
+		if s.want == hangup {
+			if err == nil {
+				_, err = br.ReadString('\n')
+			}
+			if err == nil {
+				t.Fatalf("step %d: %q answered, want the connection closed", i, s.send)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		var got strings.Builder
+		for range strings.Count(s.want, "\r\n") {
+			line, err := br.ReadString('\n')
+			got.WriteString(line)
+			if err != nil {
+				break
+			}
+		}
+		re := regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(s.want), `\{cas\}`, `(\d+)`) + "$")
+		m := re.FindStringSubmatch(got.String())
+		if m == nil {
+			t.Fatalf("step %d: %q answered %q, want %q", i, s.send, got.String(), s.want)
+		}
+		if len(m) > 1 {
+			cas = m[1]
+		}
+	}
+}
+
+func TestOversizedItems(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"set over the limit", []step{
+			{"set k 0 0 5\r\nhello\r\n", "SERVER_ERROR object too large for cache\r\n"},
+			{"get k\r\n", "END\r\n"},
+		}},
+		{"ms over the limit", []step{
+			{"ms k 5\r\nhello\r\n", "SERVER_ERROR object too large for cache\r\n"},
+			{"mn\r\n", "MN\r\n"},
+		}},
+		{"set far over the limit", []step{
+			{"set k 0 0 100\r\n", "CLIENT_ERROR object too large for cache\r\n"},
+			{"get k\r\n", hangup},
+		}},
+		{"ms far over the limit", []step{
+			{"ms k 9223372036854775807\r\n", "CLIENT_ERROR object too large for cache\r\n"},
+			{"get k\r\n", hangup},
+		}},
+		{"bad data chunk", []step{
+			{"set k 0 0 1\r\nvv\r\n", "CLIENT_ERROR bad data chunk\r\n"},
+			{"get k\r\n", hangup},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run(t, 4, tt.steps)
+		})
+	}
+}
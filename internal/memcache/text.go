@@ -0,0 +1,304 @@
+package memcache
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+// errNonNumeric is returned by arith for values that are not a decimal
+// unsigned integer.
+var errNonNumeric = errors.New("cannot increment or decrement non-numeric value")
+
+// get <key>* and gets <key>*
+func (s *Server) cmdGet(c *conn, args []string) {
+	keys := args[1:]
+	if len(keys) == 0 {
+		c.bw.WriteString("ERROR\r\n")
+		return
+	}
+	for _, key := range keys {
+		if !validKey(key) {
+			c.clientError(errClient.Error())
+			return
+		}
+	}
+
+	results := s.cd.MGet(c.ctx, keys)
+	for _, r := range results {
+		if r.Err != nil && !errors.Is(r.Err, coordinator.ErrNotFound) {
+			c.cacheError(r.Err)
+			return
+		}
+	}
+	for i, r := range results {
+		if r.Err != nil {
+			continue
+		}
+		if args[0] == "gets" {
+			fmt.Fprintf(c.bw, "VALUE %s %d %d %d\r\n", keys[i], r.Flags, len(r.Value), casToken(r.Item))
+		} else {
+			fmt.Fprintf(c.bw, "VALUE %s %d %d\r\n", keys[i], r.Flags, len(r.Value))
+		}
+		c.bw.Write(r.Value)
+		c.bw.WriteString("\r\n")
+	}
+	c.bw.WriteString("END\r\n")
+}
+
+// set|add|replace <key> <flags> <exptime> <bytes> [noreply]
+// cas <key> <flags> <exptime> <bytes> <cas unique> [noreply]
+func (s *Server) cmdStore(c *conn, args []string) error {
+	want := 5
+	if args[0] == "cas" {
+		want = 6
+	}
+	if len(args) != want && !(len(args) == want+1 && noreply(args)) {
+		c.bw.WriteString("ERROR\r\n")
+		return nil
+	}
+	// The length has to be understood before anything else, or the data
+	// block can not be skipped.
+	size, err := strconv.ParseInt(args[4], 10, 64)
+	if err != nil || size < 0 {
+		return errClient
+	}
+	if size > s.maxItemSize {
+		return s.skipData(c, size)
+	}
+	data, err := c.readData(size)
+	if err != nil {
+		return err
+	}
+
+	quiet := noreply(args)
+	key := args[1]
+	flags, err := strconv.ParseUint(args[2], 10, 32)
+	if err != nil || !validKey(key) {
+		c.clientError(errClient.Error())
+		return nil
+	}
+	ttl, expired, err := parseExptime(args[3])
+	if err != nil {
+		c.clientError(err.Error())
+		return nil
+	}
+
+	e := coordinator.Entry{Key: key, Value: data, Flags: uint32(flags), TTL: ttl}
+	switch args[0] {
+	case "set":
+		_, err = s.store(c, e, expired)
+	case "add":
+		_, err = s.storeIf(c, condAbsent, e, expired, 0)
+	case "replace":
+		_, err = s.storeIf(c, condPresent, e, expired, 0)
+	case "cas":
+		unique, perr := strconv.ParseUint(args[5], 10, 64)
+		if perr != nil {
+			c.clientError(errClient.Error())
+			return nil
+		}
+		_, err = s.storeIf(c, condCAS, e, expired, unique)
+	}
+	switch {
+	case err == nil:
+		c.reply(quiet, "STORED")
+	case args[0] == "cas" && errors.Is(err, coordinator.ErrNotFound):
+		c.reply(quiet, "NOT_FOUND")
+	case args[0] == "cas" && errors.Is(err, coordinator.ErrConflict):
+		c.reply(quiet, "EXISTS")
+	case errors.Is(err, coordinator.ErrNotFound), errors.Is(err, coordinator.ErrConflict):
+		c.reply(quiet, "NOT_STORED")
+	default:
+		c.cacheError(err)
+	}
+	return nil
+}
+
+// store writes e, or removes the key when the client gave an expiry time
+// that has already passed. It returns the cas unique of the stored item.
+func (s *Server) store(c *conn, e coordinator.Entry, expired bool) (uint64, error) {
+	if expired {
+		_, err := s.cd.Delete(c.ctx, e.Key)
+		return 0, err
+	}
+	// Versioned here so that its cas unique is known.
+	e.Version = time.Now().UnixNano()
+	return uint64(e.Version), s.cd.SetEntry(c.ctx, e)
+}
+
+// Conditions of storeIf.
+const (
+	condAbsent  = iota // the key is not stored (add)
+	condPresent        // the key is stored (replace)
+	condCAS            // the key is stored at the given cas unique (cas)
+)
+
+// storeIf writes e only if cond holds, checked and written atomically by
+// the key's owner. An item whose expiry time has already passed is
+// written to lapse at once rather than deleted, which could not be made
+// conditional. It fails with coordinator.ErrNotFound or ErrConflict when
+// cond does not hold, and otherwise returns the item's new cas unique.
+func (s *Server) storeIf(c *conn, cond int, e coordinator.Entry, expired bool, cas uint64) (uint64, error) {
+	if expired {
+		e.TTL = time.Millisecond
+	}
+	var (
+		version int64
+		err     error
+	)
+	switch cond {
+	case condAbsent:
+		version, err = s.cd.SetIfAbsent(c.ctx, e)
+	case condPresent:
+		version, err = s.cd.SetIfPresent(c.ctx, e)
+	default:
+		version, err = s.cd.CompareAndSet(c.ctx, e, int64(cas))
+	}
+	if err != nil {
+		return 0, err
+	}
+	return uint64(version), nil
+}
+
+// delete <key> [noreply]
+func (s *Server) cmdDelete(c *conn, args []string) {
+	if len(args) < 2 || len(args) > 3 || (len(args) == 3 && !noreply(args)) {
+		c.clientError("bad command line format. Usage: delete <key> [noreply]")
+		return
+	}
+	quiet := noreply(args)
+	found, err := s.cd.Delete(c.ctx, args[1])
+	switch {
+	case err != nil:
+		c.cacheError(err)
+	case found:
+		c.reply(quiet, "DELETED")
+	default:
+		c.reply(quiet, "NOT_FOUND")
+	}
+}
+
+// incr|decr <key> <value> [noreply]
+func (s *Server) cmdArith(c *conn, args []string) {
+	if len(args) < 3 || len(args) > 4 || (len(args) == 4 && !noreply(args)) {
+		c.bw.WriteString("ERROR\r\n")
+		return
+	}
+	quiet := noreply(args)
+	delta, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		c.clientError("invalid numeric delta argument")
+		return
+	}
+	n, _, err := s.arith(c, args[1], args[0] == "incr", delta, nil)
+	switch {
+	case errors.Is(err, coordinator.ErrNotFound):
+		c.reply(quiet, "NOT_FOUND")
+	case errors.Is(err, errNonNumeric):
+		c.clientError(err.Error())
+	case err != nil:
+		c.cacheError(err)
+	default:
+		c.reply(quiet, strconv.FormatUint(n, 10))
+	}
+}
+
+// vivify describes the item arith creates when the key is missing.
+type vivify struct {
+	initial uint64
+	ttl     time.Duration
+}
+
+// arith adds delta to, or subtracts it from, the decimal number stored under
+// key, keeping its flags and remaining ttl. Increments wrap at 2^64 and
+// decrements stop at 0, as in memcached; both are applied atomically by
+// the node that holds the key. A missing key is created from viv if given,
+// with the initial value as is, and is ErrNotFound otherwise.
+func (s *Server) arith(c *conn, key string, incr bool, delta uint64, viv *vivify) (uint64, coordinator.Item, error) {
+	it, err := s.counter(c, key, incr, delta)
+	if errors.Is(err, coordinator.ErrNotFound) && viv != nil {
+		e := coordinator.Entry{Key: key, Value: []byte(strconv.FormatUint(viv.initial, 10)), TTL: viv.ttl}
+		version, err := s.cd.SetIfAbsent(c.ctx, e)
+		if err == nil {
+			return viv.initial, coordinator.Item{Value: e.Value, TTL: e.TTL, Version: version}, nil
+		}
+		if !errors.Is(err, coordinator.ErrConflict) {
+			return 0, coordinator.Item{}, err
+		}
+		// Someone else created it meanwhile; count on from their value.
+		it, err = s.counter(c, key, incr, delta)
+	}
+	if errors.Is(err, coordinator.ErrNotNumeric) {
+		return 0, coordinator.Item{}, errNonNumeric
+	}
+	if err != nil {
+		return 0, coordinator.Item{}, err
+	}
+	n, err := strconv.ParseUint(string(it.Value), 10, 64)
+	if err != nil {
+		return 0, coordinator.Item{}, errNonNumeric
+	}
+	return n, it, nil
+}
+
+func (s *Server) counter(c *conn, key string, incr bool, delta uint64) (coordinator.Item, error) {
+	opts := coordinator.CounterOptions{Overflow: coordinator.OverflowUnsigned}
+	if incr {
+		return s.cd.Incr(c.ctx, key, delta, opts)
+	}
+	return s.cd.Decr(c.ctx, key, delta, opts)
+}
+
+// touch <key> <exptime> [noreply]
+func (s *Server) cmdTouch(c *conn, args []string) {
+	if len(args) < 3 || len(args) > 4 || (len(args) == 4 && !noreply(args)) {
+		c.bw.WriteString("ERROR\r\n")
+		return
+	}
+	quiet := noreply(args)
+	ttl, expired, err := parseExptime(args[2])
+	if err != nil {
+		c.clientError("invalid exptime argument")
+		return
+	}
+	_, err = s.touch(c, args[1], ttl, expired)
+	switch {
+	case errors.Is(err, coordinator.ErrNotFound):
+		c.reply(quiet, "NOT_FOUND")
+	case err != nil:
+		c.cacheError(err)
+	default:
+		c.reply(quiet, "TOUCHED")
+	}
+}
+
+// touch rewrites key with a new ttl, or deletes it for an expiry time that
+// has already passed, and returns the item as it now stands. Either is a
+// compare-and-set on the version read, retried if the item changed
+// meanwhile, so a concurrent update is never overwritten.
+func (s *Server) touch(c *conn, key string, ttl time.Duration, expired bool) (coordinator.Item, error) {
+	for attempt := 0; ; attempt++ {
+		it, err := s.cd.Get(c.ctx, key)
+		if err != nil {
+			return it, err
+		}
+		var version int64
+		if expired {
+			_, err = s.cd.CompareAndDelete(c.ctx, key, it.Version)
+		} else {
+			version, err = s.cd.CompareAndSet(c.ctx, coordinator.Entry{Key: key, Value: it.Value, Flags: it.Flags, TTL: ttl}, it.Version)
+		}
+		if errors.Is(err, coordinator.ErrConflict) && attempt < casRetries {
+			continue
+		}
+		if err != nil {
+			return coordinator.Item{}, err
+		}
+		it.TTL, it.Version = ttl, version
+		return it, nil
+	}
+}
@@ -0,0 +1,78 @@
+package memcache
+
+import "testing"
+
+func TestTextCommands(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"set and get", []step{
+			{"set k 5 0 1\r\nv\r\n", "STORED\r\n"},
+			{"get k nope\r\n", "VALUE k 5 1\r\nv\r\nEND\r\n"},
+			{"get nope\r\n", "END\r\n"},
+			{"get\r\n", "ERROR\r\n"},
+		}},
+		{"noreply", []step{
+			{"set k 0 0 1 noreply\r\nv\r\n", ""},
+			{"delete k noreply\r\n", ""},
+			{"get k\r\n", "END\r\n"},
+		}},
+		{"add and replace", []step{
+			{"replace k 0 0 1\r\na\r\n", "NOT_STORED\r\n"},
+			{"add k 0 0 1\r\na\r\n", "STORED\r\n"},
+			{"add k 0 0 1\r\nb\r\n", "NOT_STORED\r\n"},
+			{"replace k 0 0 1\r\nc\r\n", "STORED\r\n"},
+			{"get k\r\n", "VALUE k 0 1\r\nc\r\nEND\r\n"},
+		}},
+		{"gets and cas", []step{
+			{"cas k 0 0 1 1\r\na\r\n", "NOT_FOUND\r\n"},
+			{"set k 0 0 1\r\na\r\n", "STORED\r\n"},
+			{"gets k\r\n", "VALUE k 0 1 {cas}\r\na\r\nEND\r\n"},
+			{"cas k 3 0 1 {cas}\r\nb\r\n", "STORED\r\n"},
+			{"cas k 0 0 1 {cas}\r\nc\r\n", "EXISTS\r\n"}, // the cas unique moved on
+			{"get k\r\n", "VALUE k 3 1\r\nb\r\nEND\r\n"},
+		}},
+		{"delete", []step{
+			{"delete k\r\n", "NOT_FOUND\r\n"},
+			{"set k 0 0 1\r\na\r\n", "STORED\r\n"},
+			{"delete k\r\n", "DELETED\r\n"},
+			{"get k\r\n", "END\r\n"},
+		}},
+		{"touch", []step{
+			{"touch k 10\r\n", "NOT_FOUND\r\n"},
+			{"set k 0 0 1\r\na\r\n", "STORED\r\n"},
+			{"touch k 100\r\n", "TOUCHED\r\n"},
+			{"mg k t v\r\n", "VA 1 t100\r\na\r\n"},
+			{"touch k -1\r\n", "TOUCHED\r\n"},
+			{"get k\r\n", "END\r\n"},
+		}},
+		{"expired on write", []step{
+			{"set k 0 -1 1\r\na\r\n", "STORED\r\n"},
+			{"get k\r\n", "END\r\n"},
+		}},
+		{"incr and decr", []step{
+			{"incr k 1\r\n", "NOT_FOUND\r\n"},
+			{"set k 0 0 2\r\n10\r\n", "STORED\r\n"},
+			{"incr k 5\r\n", "15\r\n"},
+			{"decr k 20\r\n", "0\r\n"}, // stops at 0
+			{"incr k x\r\n", "CLIENT_ERROR invalid numeric delta argument\r\n"},
+			{"set k 0 0 20\r\n18446744073709551615\r\n", "STORED\r\n"},
+			{"incr k 2\r\n", "1\r\n"}, // wraps at 2^64
+			{"set k 0 0 1\r\na\r\n", "STORED\r\n"},
+			{"incr k 1\r\n", "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"},
+		}},
+		{"malformed", []step{
+			{"set k 0 0\r\n", "ERROR\r\n"},
+			{"set k x 0 1\r\na\r\n", "CLIENT_ERROR bad command line format\r\n"},
+			{"bogus\r\n", "ERROR\r\n"},
+			{"version\r\n", "VERSION cachy\r\n"},
+			{"quit\r\n", hangup},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run(t, 1<<20, tt.steps)
+		})
+	}
+}
@@ -0,0 +1,423 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+// command describes one supported command. arity follows Redis: a positive
+// number is the exact argument count including the command name, a negative
+// one the minimum.
+type command struct {
+	arity   int
+	handler func(s *Server, c *conn, args [][]byte) (quit bool)
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"ping":    {-1, cmdPing},
+		"echo":    {2, cmdEcho},
+		"hello":   {-1, cmdHello},
+		"quit":    {1, cmdQuit},
+		"command": {-1, cmdCommand},
+		"client":  {-2, cmdClient},
+		"select":  {2, cmdSelect},
+		"info":    {-1, cmdInfo},
+		"get":     {2, cmdGet},
+		"set":     {-3, cmdSet},
+		"del":     {-2, cmdDel},
+		"exists":  {-2, cmdExists},
+		"mget":    {-2, cmdMGet},
+		"mset":    {-3, cmdMSet},
+
+		"incr":        {2, cmdIncr},
+		"decr":        {2, cmdDecr},
+		"incrby":      {3, cmdIncrBy},
+		"decrby":      {3, cmdDecrBy},
+		"incrbyfloat": {3, cmdIncrByFloat},
+	}
+}
+
+// dispatch runs one command and writes its reply. It reports whether the
+// connection should be closed.
+func (s *Server) dispatch(c *conn, args [][]byte) bool {
+	name := strings.ToLower(string(args[0]))
+	cmd, ok := commands[name]
+	if !ok {
+		c.w.error(fmt.Sprintf("ERR unknown command '%s', with args beginning with: %s", args[0], quoteArgs(args[1:])))
+		return false
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || len(args) < -cmd.arity {
+		c.w.error(fmt.Sprintf("ERR wrong number of arguments for '%s' command", name))
+		return false
+	}
+	return cmd.handler(s, c, args)
+}
+
+func quoteArgs(args [][]byte) string {
+	var sb strings.Builder
+	for _, a := range args {
+		fmt.Fprintf(&sb, "'%s' ", a)
+	}
+	return sb.String()
+}
+
+// writeCacheError reports a coordinator failure.
+func writeCacheError(c *conn, err error) {
+	switch {
+	case errors.Is(err, coordinator.ErrNoNodes), errors.Is(err, coordinator.ErrNodeUnavailable):
+		c.w.error("CLUSTERDOWN " + err.Error())
+	default:
+		c.w.error("ERR " + err.Error())
+	}
+}
+
+func cmdPing(s *Server, c *conn, args [][]byte) bool {
+	switch len(args) {
+	case 1:
+		c.w.simple("PONG")
+	case 2:
+		c.w.bulk(args[1])
+	default:
+		c.w.error("ERR wrong number of arguments for 'ping' command")
+	}
+	return false
+}
+
+func cmdEcho(s *Server, c *conn, args [][]byte) bool {
+	c.w.bulk(args[1])
+	return false
+}
+
+// HELLO [protover [AUTH username password] [SETNAME clientname]] switches
+// the connection's protocol version and describes the server.
+func cmdHello(s *Server, c *conn, args [][]byte) bool {
+	proto := c.w.proto
+	if len(args) > 1 {
+		v, err := strconv.Atoi(string(args[1]))
+		if err != nil {
+			c.w.error("ERR Protocol version is not an integer or out of range")
+			return false
+		}
+		if v != 2 && v != 3 {
+			c.w.error("NOPROTO unsupported protocol version")
+			return false
+		}
+		proto = v
+		// AUTH and SETNAME are accepted and ignored; there is no auth.
+		for i := 2; i < len(args); i++ {
+			switch strings.ToLower(string(args[i])) {
+			case "auth":
+				i += 2
+			case "setname":
+				i++
+			default:
+				c.w.error(fmt.Sprintf("ERR Syntax error in HELLO option '%s'", args[i]))
+				return false
+			}
+			if i >= len(args) {
+				c.w.error("ERR Syntax error in HELLO")
+				return false
+			}
+		}
+	}
+	c.w.proto = proto
+
+	c.w.mapHeader(7)
+	c.w.bulk([]byte("server"))
+	c.w.bulk([]byte("cachy"))
+	c.w.bulk([]byte("version"))
+	c.w.bulk([]byte(redisVersion))
+	c.w.bulk([]byte("proto"))
+	c.w.int(int64(proto))
+	c.w.bulk([]byte("id"))
+	c.w.int(c.id)
+	c.w.bulk([]byte("mode"))
+	c.w.bulk([]byte("standalone"))
+	c.w.bulk([]byte("role"))
+	c.w.bulk([]byte("master"))
+	c.w.bulk([]byte("modules"))
+	c.w.array(0)
+	return false
+}
+
+func cmdQuit(s *Server, c *conn, args [][]byte) bool {
+	c.w.simple("OK")
+	return true
+}
+
+// COMMAND is queried by redis-cli and some client libraries on connect to
+// learn command metadata. An empty reply makes them fall back to defaults.
+func cmdCommand(s *Server, c *conn, args [][]byte) bool {
+	if len(args) > 1 && strings.EqualFold(string(args[1]), "count") {
+		c.w.int(int64(len(commands)))
+		return false
+	}
+	c.w.array(0)
+	return false
+}
+
+// CLIENT SETNAME/SETINFO are sent by client libraries on connect; they are
+// acknowledged but not recorded.
+func cmdClient(s *Server, c *conn, args [][]byte) bool {
+	switch strings.ToLower(string(args[1])) {
+	case "setname", "setinfo":
+		c.w.simple("OK")
+	case "id":
+		c.w.int(c.id)
+	default:
+		c.w.error(fmt.Sprintf("ERR unknown subcommand '%s'", args[1]))
+	}
+	return false
+}
+
+// SELECT only accepts database 0; cachy has a single keyspace.
+func cmdSelect(s *Server, c *conn, args [][]byte) bool {
+	if string(args[1]) != "0" {
+		c.w.error("ERR DB index is out of range")
+		return false
+	}
+	c.w.simple("OK")
+	return false
+}
+
+func cmdGet(s *Server, c *conn, args [][]byte) bool {
+	item, err := s.cd.Get(c.ctx, string(args[1]))
+	switch {
+	case errors.Is(err, coordinator.ErrNotFound):
+		c.w.null()
+	case err != nil:
+		writeCacheError(c, err)
+	default:
+		c.w.bulk(item.Value)
+	}
+	return false
+}
+
+// SET key value [NX | XX] [EX seconds | PX milliseconds]
+func cmdSet(s *Server, c *conn, args [][]byte) bool {
+	key, value := string(args[1]), args[2]
+	var (
+		ttl       time.Duration
+		nx, xx    bool
+		ttlGiven  bool
+		syntaxErr = func() bool { c.w.error("ERR syntax error"); return false }
+	)
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToLower(string(args[i])); opt {
+		case "nx":
+			nx = true
+		case "xx":
+			xx = true
+		case "ex", "px":
+			if ttlGiven || i+1 >= len(args) {
+				return syntaxErr()
+			}
+			i++
+			n, err := strconv.ParseInt(string(args[i]), 10, 64)
+			if err != nil {
+				c.w.error("ERR value is not an integer or out of range")
+				return false
+			}
+			if n <= 0 {
+				c.w.error("ERR invalid expire time in 'set' command")
+				return false
+			}
+			unit := time.Second
+			if opt == "px" {
+				unit = time.Millisecond
+			}
+			ttl, ttlGiven = time.Duration(n)*unit, true
+		default:
+			return syntaxErr()
+		}
+	}
+	if nx && xx {
+		return syntaxErr()
+	}
+
+	e := coordinator.Entry{Key: key, Value: value, TTL: ttl}
+	var err error
+	switch {
+	case nx:
+		_, err = s.cd.SetIfAbsent(c.ctx, e)
+	case xx:
+		_, err = s.cd.SetIfPresent(c.ctx, e)
+	default:
+		err = s.cd.SetEntry(c.ctx, e)
+	}
+	switch {
+	case errors.Is(err, coordinator.ErrConflict), errors.Is(err, coordinator.ErrNotFound):
+		c.w.null()
+	case err != nil:
+		writeCacheError(c, err)
+	default:
+		c.w.simple("OK")
+	}
+	return false
+}
+
+func cmdDel(s *Server, c *conn, args [][]byte) bool {
+	results := s.cd.MDelete(c.ctx, keyArgs(args[1:]))
+	var n int64
+	for _, r := range results {
+		if r.Err != nil {
+			writeCacheError(c, r.Err)
+			return false
+		}
+		if r.Deleted {
+			n++
+		}
+	}
+	c.w.int(n)
+	return false
+}
+
+// EXISTS counts a key once per time it is named, as Redis does.
+func cmdExists(s *Server, c *conn, args [][]byte) bool {
+	var n int64
+	for _, key := range args[1:] {
+		found, err := s.cd.Exists(c.ctx, string(key))
+		if err != nil {
+			writeCacheError(c, err)
+			return false
+		}
+		if found {
+			n++
+		}
+	}
+	c.w.int(n)
+	return false
+}
+
+func cmdMGet(s *Server, c *conn, args [][]byte) bool {
+	results := s.cd.MGet(c.ctx, keyArgs(args[1:]))
+	for _, r := range results {
+		if r.Err != nil && !errors.Is(r.Err, coordinator.ErrNotFound) {
+			writeCacheError(c, r.Err)
+			return false
+		}
+	}
+	c.w.array(len(results))
+	for _, r := range results {
+		if r.Err != nil {
+			c.w.null()
+			continue
+		}
+		c.w.bulk(r.Value)
+	}
+	return false
+}
+
+func cmdMSet(s *Server, c *conn, args [][]byte) bool {
+	if len(args)%2 != 1 {
+		c.w.error("ERR wrong number of arguments for 'mset' command")
+		return false
+	}
+	entries := make([]coordinator.Entry, 0, len(args)/2)
+	for i := 1; i < len(args); i += 2 {
+		entries = append(entries, coordinator.Entry{Key: string(args[i]), Value: args[i+1]})
+	}
+	for _, err := range s.cd.MSet(c.ctx, entries) {
+		if err != nil {
+			writeCacheError(c, err)
+			return false
+		}
+	}
+	c.w.simple("OK")
+	return false
+}
+
+func cmdIncr(s *Server, c *conn, args [][]byte) bool {
+	return incrBy(s, c, string(args[1]), 1)
+}
+
+func cmdDecr(s *Server, c *conn, args [][]byte) bool {
+	return incrBy(s, c, string(args[1]), -1)
+}
+
+func cmdIncrBy(s *Server, c *conn, args [][]byte) bool {
+	by, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		c.w.error("ERR value is not an integer or out of range")
+		return false
+	}
+	return incrBy(s, c, string(args[1]), by)
+}
+
+func cmdDecrBy(s *Server, c *conn, args [][]byte) bool {
+	by, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		c.w.error("ERR value is not an integer or out of range")
+		return false
+	}
+	if by == math.MinInt64 {
+		c.w.error("ERR decrement would overflow")
+		return false
+	}
+	return incrBy(s, c, string(args[1]), -by)
+}
+
+// incrBy adds by to the signed 64-bit integer stored under key, counting a
+// missing key as 0, as Redis does.
+func incrBy(s *Server, c *conn, key string, by int64) bool {
+	opts := coordinator.CounterOptions{Create: true}
+	delta := uint64(by)
+	var (
+		item coordinator.Item
+		err  error
+	)
+	if by < 0 {
+		item, err = s.cd.Decr(c.ctx, key, -delta, opts)
+	} else {
+		item, err = s.cd.Incr(c.ctx, key, delta, opts)
+	}
+	switch {
+	case errors.Is(err, coordinator.ErrNotNumeric):
+		c.w.error("ERR value is not an integer or out of range")
+	case errors.Is(err, coordinator.ErrOverflow):
+		c.w.error("ERR increment or decrement would overflow")
+	case err != nil:
+		writeCacheError(c, err)
+	default:
+		n, _ := strconv.ParseInt(string(item.Value), 10, 64)
+		c.w.int(n)
+	}
+	return false
+}
+
+func cmdIncrByFloat(s *Server, c *conn, args [][]byte) bool {
+	by, err := strconv.ParseFloat(string(args[2]), 64)
+	if err != nil || math.IsNaN(by) || math.IsInf(by, 0) {
+		c.w.error("ERR value is not a valid float")
+		return false
+	}
+	item, err := s.cd.IncrByFloat(c.ctx, string(args[1]), by, coordinator.FloatCounterOptions{Create: true})
+	switch {
+	case errors.Is(err, coordinator.ErrNotNumeric):
+		c.w.error("ERR value is not a valid float")
+	case errors.Is(err, coordinator.ErrOverflow):
+		c.w.error("ERR increment would produce NaN or Infinity")
+	case err != nil:
+		writeCacheError(c, err)
+	default:
+		c.w.bulk(item.Value)
+	}
+	return false
+}
+
+func keyArgs(args [][]byte) []string {
+	keys := make([]string, len(args))
+	for i, a := range args {
+		keys[i] = string(a)
+	}
+	return keys
+}
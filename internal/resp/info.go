@@ -0,0 +1,64 @@
+package resp
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// redisVersion is the Redis release whose command behaviour is emulated.
+// Clients read it from HELLO and INFO to decide which features to use.
+const redisVersion = "7.0.0"
+
+var startTime = time.Now()
+
+// INFO [section ...] reports server and cluster state in Redis's
+// "# Section" / "field:value" format.
+func cmdInfo(s *Server, c *conn, args [][]byte) bool {
+	want := func(section string) bool {
+		if len(args) == 1 {
+			return true
+		}
+		for _, a := range args[1:] {
+			name := strings.ToLower(string(a))
+			if name == section || name == "all" || name == "everything" || name == "default" {
+				return true
+			}
+		}
+		return false
+	}
+
+	var sb strings.Builder
+	if want("server") {
+		fmt.Fprintf(&sb, "# Server\r\n")
+		fmt.Fprintf(&sb, "redis_version:%s\r\n", redisVersion)
+		fmt.Fprintf(&sb, "redis_mode:standalone\r\n")
+		fmt.Fprintf(&sb, "server_name:cachy\r\n")
+		fmt.Fprintf(&sb, "os:%s %s\r\n", runtime.GOOS, runtime.GOARCH)
+		fmt.Fprintf(&sb, "go_version:%s\r\n", runtime.Version())
+		fmt.Fprintf(&sb, "process_id:%d\r\n", os.Getpid())
+		fmt.Fprintf(&sb, "uptime_in_seconds:%d\r\n", int64(time.Since(startTime).Seconds()))
+		sb.WriteString("\r\n")
+	}
+	if want("clients") {
+		fmt.Fprintf(&sb, "# Clients\r\n")
+		fmt.Fprintf(&sb, "total_connections_received:%d\r\n", s.nextID.Load())
+		sb.WriteString("\r\n")
+	}
+	if want("cluster") {
+		nodes := s.cd.Nodes()
+		fmt.Fprintf(&sb, "# Cluster\r\n")
+		fmt.Fprintf(&sb, "cluster_enabled:0\r\n")
+		fmt.Fprintf(&sb, "cachy_nodes:%d\r\n", len(nodes))
+		for i, n := range nodes {
+			fmt.Fprintf(&sb, "cachy_node%d:addr=%s,weight=%d,state=%s,failures=%d\r\n",
+				i, n.Addr, n.Weight, n.State, n.Failures)
+		}
+		sb.WriteString("\r\n")
+	}
+
+	c.w.bulk([]byte(sb.String()))
+	return false
+}
@@ -0,0 +1,161 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// errProtocol marks a malformed request. The connection is closed after
+// replying, since the stream can no longer be framed.
+var errProtocol = errors.New("protocol error")
+
+const maxArgs = 1 << 20
+
+// preallocArgs caps the arguments space is reserved for up front, so that a
+// client announcing many cannot make the server allocate before sending them.
+const preallocArgs = 64
+
+// reader parses client commands: RESP arrays of bulk strings, or inline
+// commands typed into telnet.
+type reader struct {
+	br         *bufio.Reader
+	maxBulkLen int64
+}
+
+// readCommand returns the next command's arguments. An empty slice is an
+// empty inline line and should be ignored.
+func (r *reader) readCommand() ([][]byte, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 || line[0] != '*' {
+		return inlineArgs(line), nil
+	}
+
+	n, err := strconv.Atoi(string(line[1:]))
+	if err != nil || n > maxArgs {
+		return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+	}
+	args := make([][]byte, 0, min(max(n, 0), preallocArgs))
+	for range n {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("%w: expected '$', got '%s'", errProtocol, firstByte(line))
+		}
+		size, err := strconv.ParseInt(string(line[1:]), 10, 64)
+		if err != nil || size < 0 || size > r.maxBulkLen {
+			return nil, fmt.Errorf("%w: invalid bulk length", errProtocol)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r.br, buf); err != nil {
+			return nil, err
+		}
+		if buf[size] != '\r' || buf[size+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string not terminated by CRLF", errProtocol)
+		}
+		args = append(args, buf[:size])
+	}
+	return args, nil
+}
+
+// readLine reads up to CRLF (or a bare LF, for inline commands) and returns
+// the line without its terminator.
+func (r *reader) readLine() ([]byte, error) {
+	line, err := r.br.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("%w: line too long", errProtocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	line = line[:len(line)-1]
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line, nil
+}
+
+func inlineArgs(line []byte) [][]byte {
+	fields := strings.Fields(string(line))
+	args := make([][]byte, len(fields))
+	for i, f := range fields {
+		args[i] = []byte(f)
+	}
+	return args
+}
+
+func firstByte(line []byte) string {
+	if len(line) == 0 {
+		return ""
+	}
+	return string(line[:1])
+}
+
+// writer encodes replies for the protocol version the client negotiated
+// with HELLO. RESP2 has no null or map types, so those fall back to the null
+// bulk string and a flat array.
+type writer struct {
+	bw    *bufio.Writer
+	proto int
+}
+
+func (w *writer) simple(s string) {
+	w.bw.WriteByte('+')
+	w.bw.WriteString(s)
+	w.bw.WriteString("\r\n")
+}
+
+// error writes an error reply. msg should start with an error code such as
+// ERR or WRONGTYPE.
+func (w *writer) error(msg string) {
+	w.bw.WriteByte('-')
+	w.bw.WriteString(strings.NewReplacer("\r", " ", "\n", " ").Replace(msg))
+	w.bw.WriteString("\r\n")
+}
+
+func (w *writer) int(n int64) {
+	w.bw.WriteByte(':')
+	w.bw.WriteString(strconv.FormatInt(n, 10))
+	w.bw.WriteString("\r\n")
+}
+
+func (w *writer) bulk(b []byte) {
+	w.bw.WriteByte('$')
+	w.bw.WriteString(strconv.Itoa(len(b)))
+	w.bw.WriteString("\r\n")
+	w.bw.Write(b)
+	w.bw.WriteString("\r\n")
+}
+
+func (w *writer) null() {
+	if w.proto >= 3 {
+		w.bw.WriteString("_\r\n")
+		return
+	}
+	w.bw.WriteString("$-1\r\n")
+}
+
+func (w *writer) array(n int) {
+	w.bw.WriteByte('*')
+	w.bw.WriteString(strconv.Itoa(n))
+	w.bw.WriteString("\r\n")
+}
+
+// mapHeader starts a map of n pairs; the caller writes 2n elements.
+func (w *writer) mapHeader(n int) {
+	if w.proto >= 3 {
+		w.bw.WriteByte('%')
+		w.bw.WriteString(strconv.Itoa(n))
+		w.bw.WriteString("\r\n")
+		return
+	}
+	w.array(2 * n)
+}
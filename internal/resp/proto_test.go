@@ -0,0 +1,103 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReadCommand(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string
+		err  error
+	}{
+		{"inline", "GET k\r\n", []string{"GET", "k"}, nil},
+		{"inline bare LF", "PING\n", []string{"PING"}, nil},
+		{"inline extra spaces", "  SET  k   v \r\n", []string{"SET", "k", "v"}, nil},
+		{"inline empty", "\r\n", []string{}, nil},
+		{"multibulk", "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n", []string{"GET", "k"}, nil},
+		{"empty bulk", "*2\r\n$3\r\nGET\r\n$0\r\n\r\n", []string{"GET", ""}, nil},
+		{"binary bulk", "*1\r\n$4\r\na\r\nb\r\n", []string{"a\r\nb"}, nil},
+		{"null multibulk", "*-1\r\n", []string{}, nil},
+		{"zero multibulk", "*0\r\n", []string{}, nil},
+		{"bad multibulk length", "*x\r\n", nil, errProtocol},
+		{"too many args", "*2000000\r\n", nil, errProtocol},
+		{"missing dollar", "*1\r\n:1\r\n", nil, errProtocol},
+		{"bad bulk length", "*1\r\n$x\r\n", nil, errProtocol},
+		{"negative bulk length", "*1\r\n$-1\r\n", nil, errProtocol},
+		{"bulk over limit", "*1\r\n$17\r\n", nil, errProtocol},
+		{"bulk without CRLF", "*1\r\n$1\r\nkx\r\n", nil, errProtocol},
+		{"cut short", "*2\r\n$3\r\nGET\r\n", nil, io.EOF},
+		{"bulk cut short", "*1\r\n$3\r\nGE", nil, io.ErrUnexpectedEOF},
+		{"line too long", strings.Repeat("a", 100), nil, errProtocol},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := reader{br: bufio.NewReaderSize(strings.NewReader(tt.in), 16), maxBulkLen: 16}
+			args, err := r.readCommand()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("readCommand = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			got := make([]string, len(args))
+			for i, a := range args {
+				got[i] = string(a)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCommandAnnouncedArgs(t *testing.T) {
+	// A client announcing a million arguments and sending none gets
+	// nothing reserved for them.
+	r := reader{br: bufio.NewReader(strings.NewReader("*1000000\r\n")), maxBulkLen: 16}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := r.readCommand(); !errors.Is(err, io.EOF) {
+		t.Fatalf("readCommand = %v, want EOF", err)
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 1<<16 {
+		t.Errorf("readCommand allocated %d bytes", n)
+	}
+}
+
+func TestWriterReplies(t *testing.T) {
+	tests := []struct {
+		name         string
+		write        func(w *writer)
+		resp2, resp3 string
+	}{
+		{"simple", func(w *writer) { w.simple("OK") }, "+OK\r\n", "+OK\r\n"},
+		{"error", func(w *writer) { w.error("ERR bad\r\nthing") }, "-ERR bad  thing\r\n", "-ERR bad  thing\r\n"},
+		{"int", func(w *writer) { w.int(-42) }, ":-42\r\n", ":-42\r\n"},
+		{"bulk", func(w *writer) { w.bulk([]byte("a\r\nb")) }, "$4\r\na\r\nb\r\n", "$4\r\na\r\nb\r\n"},
+		{"empty bulk", func(w *writer) { w.bulk(nil) }, "$0\r\n\r\n", "$0\r\n\r\n"},
+		{"null", func(w *writer) { w.null() }, "$-1\r\n", "_\r\n"},
+		{"array", func(w *writer) { w.array(2); w.int(1); w.null() }, "*2\r\n:1\r\n$-1\r\n", "*2\r\n:1\r\n_\r\n"},
+		{"map", func(w *writer) { w.mapHeader(1); w.simple("k"); w.int(1) }, "*2\r\n+k\r\n:1\r\n", "%1\r\n+k\r\n:1\r\n"},
+	}
+	for _, tt := range tests {
+		for proto, want := range map[int]string{2: tt.resp2, 3: tt.resp3} {
+			var buf bytes.Buffer
+			w := &writer{bw: bufio.NewWriter(&buf), proto: proto}
+			tt.write(w)
+			w.bw.Flush()
+			if got := buf.String(); got != want {
+				t.Errorf("%s over RESP%d = %q, want %q", tt.name, proto, got, want)
+			}
+		}
+	}
+}
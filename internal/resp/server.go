@@ -0,0 +1,99 @@
+// Package resp serves the coordinator over the Redis protocol (RESP2 and
+// RESP3), so redis-cli and ordinary Redis client libraries can talk to a
+// cachy cluster.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync/atomic"
+
+	"github.com/sakshamg567/cachy/internal/coordinator"
+)
+
+// Server accepts Redis connections and forwards their commands to a
+// Coordinator.
+type Server struct {
+	cd         *coordinator.Coordinator
+	maxBulkLen int64 // largest bulk string a client may send
+	nextID     atomic.Int64
+}
+
+func NewServer(cd *coordinator.Coordinator, maxBulkLen int64) *Server {
+	return &Server{cd: cd, maxBulkLen: maxBulkLen}
+}
+
+// ListenAndServe listens on addr and serves connections until the listener
+// fails.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	slog.Info("resp listening", "addr", ln.Addr().String())
+	return s.Serve(ln)
+}
+
+func (s *Server) Serve(ln net.Listener) error {
+	defer ln.Close()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// conn is the per-connection state.
+type conn struct {
+	id  int64
+	ctx context.Context // cancelled once the connection is closed
+	r   reader
+	w   writer
+}
+
+func (s *Server) serveConn(nc net.Conn) {
+	defer nc.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := &conn{
+		id:  s.nextID.Add(1),
+		ctx: ctx,
+		r:   reader{br: bufio.NewReaderSize(nc, 64<<10), maxBulkLen: s.maxBulkLen},
+		w:   writer{bw: bufio.NewWriter(nc), proto: 2},
+	}
+
+	for {
+		args, err := c.r.readCommand()
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				c.w.error("ERR " + err.Error())
+				c.w.bw.Flush()
+			} else if !errors.Is(err, io.EOF) {
+				slog.Debug("resp connection closed", "conn", c.id, "remote", nc.RemoteAddr().String(), "err", err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := s.dispatch(c, args)
+
+		// Pipelined commands are answered together: only flush once the
+		// client has nothing more buffered.
+		if quit || c.r.br.Buffered() == 0 {
+			if err := c.w.bw.Flush(); err != nil {
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
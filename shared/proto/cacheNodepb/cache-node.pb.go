@@ -21,6 +21,57 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Overflow says how a counter behaves at the ends of its range.
+type Overflow int32
+
+const (
+	// signed 64-bit; a result out of range fails and the value is left as
+	// it was
+	Overflow_OVERFLOW_FAIL Overflow = 0
+	// unsigned 64-bit; increments wrap around past the maximum and
+	// decrements stop at 0
+	Overflow_OVERFLOW_UNSIGNED Overflow = 1
+)
+
+// Enum value maps for Overflow.
+var (
+	Overflow_name = map[int32]string{
+		0: "OVERFLOW_FAIL",
+		1: "OVERFLOW_UNSIGNED",
+	}
+	Overflow_value = map[string]int32{
+		"OVERFLOW_FAIL":     0,
+		"OVERFLOW_UNSIGNED": 1,
+	}
+)
+
+func (x Overflow) Enum() *Overflow {
+	p := new(Overflow)
+	*p = x
+	return p
+}
+
+func (x Overflow) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Overflow) Descriptor() protoreflect.EnumDescriptor {
+	return file_shared_proto_cache_node_proto_enumTypes[0].Descriptor()
+}
+
+func (Overflow) Type() protoreflect.EnumType {
+	return &file_shared_proto_cache_node_proto_enumTypes[0]
+}
+
+func (x Overflow) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Overflow.Descriptor instead.
+func (Overflow) EnumDescriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{0}
+}
+
 type GetRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
@@ -66,9 +117,28 @@ func (x *GetRequest) GetKey() string {
 }
 
 type GetResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Value         string                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
-	Found         bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Value []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	Found bool                   `protobuf:"varint,2,opt,name=found,proto3" json:"found,omitempty"`
+	// remaining time to live in milliseconds, 0 if the key never expires
+	TtlMs int64 `protobuf:"varint,3,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	// opaque client flags stored alongside the value (memcached)
+	Flags uint32 `protobuf:"varint,4,opt,name=flags,proto3" json:"flags,omitempty"`
+	// the value is past its soft ttl and should be refreshed
+	Stale bool `protobuf:"varint,5,opt,name=stale,proto3" json:"stale,omitempty"`
+	// this caller was chosen to refresh the stale value; others are not told
+	// to until the refresh lease runs out
+	Revalidate bool `protobuf:"varint,6,opt,name=revalidate,proto3" json:"revalidate,omitempty"`
+	// remaining time before the value turns stale in milliseconds, 0 if it
+	// has no soft ttl or is already stale
+	SoftTtlMs int64 `protobuf:"varint,7,opt,name=soft_ttl_ms,json=softTtlMs,proto3" json:"soft_ttl_ms,omitempty"`
+	// version of the value: its writer's timestamp, or one the node chose
+	// if it had none. It increases with every write of the key, so it also
+	// serves as the key's CAS token.
+	Version int64 `protobuf:"varint,8,opt,name=version,proto3" json:"version,omitempty"`
+	// the key is not found because it was deleted at version; set while the
+	// node keeps the delete's tombstone
+	Deleted       bool `protobuf:"varint,9,opt,name=deleted,proto3" json:"deleted,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -103,11 +173,11 @@ func (*GetResponse) Descriptor() ([]byte, []int) {
 	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{1}
 }
 
-func (x *GetResponse) GetValue() string {
+func (x *GetResponse) GetValue() []byte {
 	if x != nil {
 		return x.Value
 	}
-	return ""
+	return nil
 }
 
 func (x *GetResponse) GetFound() bool {
@@ -117,10 +187,69 @@ func (x *GetResponse) GetFound() bool {
 	return false
 }
 
+func (x *GetResponse) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+func (x *GetResponse) GetFlags() uint32 {
+	if x != nil {
+		return x.Flags
+	}
+	return 0
+}
+
+func (x *GetResponse) GetStale() bool {
+	if x != nil {
+		return x.Stale
+	}
+	return false
+}
+
+func (x *GetResponse) GetRevalidate() bool {
+	if x != nil {
+		return x.Revalidate
+	}
+	return false
+}
+
+func (x *GetResponse) GetSoftTtlMs() int64 {
+	if x != nil {
+		return x.SoftTtlMs
+	}
+	return 0
+}
+
+func (x *GetResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *GetResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
 type SetRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value []byte                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// time to live in milliseconds, 0 means no expiry
+	TtlMs int64  `protobuf:"varint,3,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	Flags uint32 `protobuf:"varint,4,opt,name=flags,proto3" json:"flags,omitempty"`
+	// time in milliseconds after which the value is served as stale, 0
+	// means never; only meaningful when shorter than ttl_ms
+	SoftTtlMs int64 `protobuf:"varint,5,opt,name=soft_ttl_ms,json=softTtlMs,proto3" json:"soft_ttl_ms,omitempty"`
+	// write timestamp in unix nanoseconds, used to keep the newest of
+	// conflicting writes: a write older than the stored value is dropped.
+	// 0 always overwrites, with a version chosen by the node.
+	Version       int64 `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -162,16 +291,48 @@ func (x *SetRequest) GetKey() string {
 	return ""
 }
 
-func (x *SetRequest) GetValue() string {
+func (x *SetRequest) GetValue() []byte {
 	if x != nil {
 		return x.Value
 	}
-	return ""
+	return nil
+}
+
+func (x *SetRequest) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+func (x *SetRequest) GetFlags() uint32 {
+	if x != nil {
+		return x.Flags
+	}
+	return 0
+}
+
+func (x *SetRequest) GetSoftTtlMs() int64 {
+	if x != nil {
+		return x.SoftTtlMs
+	}
+	return 0
+}
+
+func (x *SetRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
 }
 
 type SetResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// why the write was refused, and the gRPC status code it would have
+	// failed with on its own; only set in MSet results
+	Error         string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Code          int32  `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -213,6 +374,20 @@ func (x *SetResponse) GetSuccess() bool {
 	return false
 }
 
+func (x *SetResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SetResponse) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
 type GetAllKeysRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -294,8 +469,16 @@ func (x *GetAllKeysResponse) GetKeys() []string {
 }
 
 type DeleteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// delete timestamp in unix nanoseconds, kept in the key's tombstone: a
+	// value written before it is removed and is not stored again, one
+	// written after it is kept. 0 is chosen by the node above the stored
+	// version.
+	Version int64 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	// remove the node's copy without leaving a tombstone, for a key that
+	// moved to other nodes rather than being deleted
+	Drop          bool `protobuf:"varint,3,opt,name=drop,proto3" json:"drop,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -337,6 +520,20 @@ func (x *DeleteRequest) GetKey() string {
 	return ""
 }
 
+func (x *DeleteRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *DeleteRequest) GetDrop() bool {
+	if x != nil {
+		return x.Drop
+	}
+	return false
+}
+
 type DeleteResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
@@ -381,36 +578,1239 @@ func (x *DeleteResponse) GetSuccess() bool {
 	return false
 }
 
-var File_shared_proto_cache_node_proto protoreflect.FileDescriptor
+type ExistsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_shared_proto_cache_node_proto_rawDesc = "" +
-	"\n" +
-	"\x1dshared/proto/cache-node.proto\x12\x05cache\"\x1e\n" +
-	"\n" +
-	"GetRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\"9\n" +
-	"\vGetResponse\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\tR\x05value\x12\x14\n" +
-	"\x05found\x18\x02 \x01(\bR\x05found\"4\n" +
-	"\n" +
-	"SetRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value\"'\n" +
-	"\vSetResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"\x13\n" +
-	"\x11GetAllKeysRequest\"(\n" +
-	"\x12GetAllKeysResponse\x12\x12\n" +
-	"\x04keys\x18\x01 \x03(\tR\x04keys\"!\n" +
-	"\rDeleteRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\"*\n" +
-	"\x0eDeleteResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess2\xdd\x01\n" +
-	"\x05Cache\x12,\n" +
-	"\x03Get\x12\x11.cache.GetRequest\x1a\x12.cache.GetResponse\x12,\n" +
-	"\x03Set\x12\x11.cache.SetRequest\x1a\x12.cache.SetResponse\x12A\n" +
-	"\n" +
-	"GetAllKeys\x12\x18.cache.GetAllKeysRequest\x1a\x19.cache.GetAllKeysResponse\x125\n" +
-	"\x06Delete\x12\x14.cache.DeleteRequest\x1a\x15.cache.DeleteResponseB\x1aZ\x18shared/proto/cacheNodepbb\x06proto3"
+func (x *ExistsRequest) Reset() {
+	*x = ExistsRequest{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExistsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExistsRequest) ProtoMessage() {}
+
+func (x *ExistsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExistsRequest.ProtoReflect.Descriptor instead.
+func (*ExistsRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ExistsRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type ExistsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Found         bool                   `protobuf:"varint,1,opt,name=found,proto3" json:"found,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExistsResponse) Reset() {
+	*x = ExistsResponse{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExistsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExistsResponse) ProtoMessage() {}
+
+func (x *ExistsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExistsResponse.ProtoReflect.Descriptor instead.
+func (*ExistsResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ExistsResponse) GetFound() bool {
+	if x != nil {
+		return x.Found
+	}
+	return false
+}
+
+type MGetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []string               `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MGetRequest) Reset() {
+	*x = MGetRequest{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MGetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MGetRequest) ProtoMessage() {}
+
+func (x *MGetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MGetRequest.ProtoReflect.Descriptor instead.
+func (*MGetRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MGetRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+// MGetResponse holds one result per requested key, in request order.
+type MGetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*GetResponse         `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MGetResponse) Reset() {
+	*x = MGetResponse{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MGetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MGetResponse) ProtoMessage() {}
+
+func (x *MGetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MGetResponse.ProtoReflect.Descriptor instead.
+func (*MGetResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *MGetResponse) GetItems() []*GetResponse {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type MSetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*SetRequest          `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MSetRequest) Reset() {
+	*x = MSetRequest{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MSetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MSetRequest) ProtoMessage() {}
+
+func (x *MSetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MSetRequest.ProtoReflect.Descriptor instead.
+func (*MSetRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *MSetRequest) GetItems() []*SetRequest {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// MSetResponse holds one result per item, in request order.
+type MSetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Items         []*SetResponse         `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MSetResponse) Reset() {
+	*x = MSetResponse{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MSetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MSetResponse) ProtoMessage() {}
+
+func (x *MSetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MSetResponse.ProtoReflect.Descriptor instead.
+func (*MSetResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *MSetResponse) GetItems() []*SetResponse {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+// MDeleteRequest deletes every key at version, as DeleteRequest does.
+type MDeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []string               `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MDeleteRequest) Reset() {
+	*x = MDeleteRequest{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MDeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MDeleteRequest) ProtoMessage() {}
+
+func (x *MDeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MDeleteRequest.ProtoReflect.Descriptor instead.
+func (*MDeleteRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *MDeleteRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *MDeleteRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// MDeleteResponse reports, per requested key and in request order, whether
+// the key was stored.
+type MDeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       []bool                 `protobuf:"varint,1,rep,packed,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MDeleteResponse) Reset() {
+	*x = MDeleteResponse{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MDeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MDeleteResponse) ProtoMessage() {}
+
+func (x *MDeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MDeleteResponse.ProtoReflect.Descriptor instead.
+func (*MDeleteResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *MDeleteResponse) GetDeleted() []bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return nil
+}
+
+// KeyRange is the half-open range (lo, hi] of key hashes on the ring,
+// wrapping past zero when lo > hi; lo == hi is the whole ring.
+type KeyRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Lo            uint32                 `protobuf:"varint,1,opt,name=lo,proto3" json:"lo,omitempty"`
+	Hi            uint32                 `protobuf:"varint,2,opt,name=hi,proto3" json:"hi,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyRange) Reset() {
+	*x = KeyRange{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyRange) ProtoMessage() {}
+
+func (x *KeyRange) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyRange.ProtoReflect.Descriptor instead.
+func (*KeyRange) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *KeyRange) GetLo() uint32 {
+	if x != nil {
+		return x.Lo
+	}
+	return 0
+}
+
+func (x *KeyRange) GetHi() uint32 {
+	if x != nil {
+		return x.Hi
+	}
+	return 0
+}
+
+// MerkleTreeRequest asks for a hash tree over the keys whose hash falls in
+// ranges. Keys are bucketed into 2^depth leaves by the top depth bits of
+// their hash.
+type MerkleTreeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ranges        []*KeyRange            `protobuf:"bytes,1,rep,name=ranges,proto3" json:"ranges,omitempty"`
+	Depth         uint32                 `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleTreeRequest) Reset() {
+	*x = MerkleTreeRequest{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleTreeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleTreeRequest) ProtoMessage() {}
+
+func (x *MerkleTreeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleTreeRequest.ProtoReflect.Descriptor instead.
+func (*MerkleTreeRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *MerkleTreeRequest) GetRanges() []*KeyRange {
+	if x != nil {
+		return x.Ranges
+	}
+	return nil
+}
+
+func (x *MerkleTreeRequest) GetDepth() uint32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+// MerkleTreeResponse holds the tree in heap order: the root first, and the
+// children of node i at 2i+1 and 2i+2. A leaf hashes the keys and versions
+// in its bucket, an inner node its two children.
+type MerkleTreeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Nodes         []uint64               `protobuf:"varint,1,rep,packed,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleTreeResponse) Reset() {
+	*x = MerkleTreeResponse{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleTreeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleTreeResponse) ProtoMessage() {}
+
+func (x *MerkleTreeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleTreeResponse.ProtoReflect.Descriptor instead.
+func (*MerkleTreeResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *MerkleTreeResponse) GetNodes() []uint64 {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+// ScanRequest selects the keys in ranges that fall in the given leaves of
+// a tree of the given depth, or in every leaf if none are given.
+type ScanRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ranges        []*KeyRange            `protobuf:"bytes,1,rep,name=ranges,proto3" json:"ranges,omitempty"`
+	Depth         uint32                 `protobuf:"varint,2,opt,name=depth,proto3" json:"depth,omitempty"`
+	Leaves        []uint32               `protobuf:"varint,3,rep,packed,name=leaves,proto3" json:"leaves,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanRequest) Reset() {
+	*x = ScanRequest{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanRequest) ProtoMessage() {}
+
+func (x *ScanRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanRequest.ProtoReflect.Descriptor instead.
+func (*ScanRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ScanRequest) GetRanges() []*KeyRange {
+	if x != nil {
+		return x.Ranges
+	}
+	return nil
+}
+
+func (x *ScanRequest) GetDepth() uint32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *ScanRequest) GetLeaves() []uint32 {
+	if x != nil {
+		return x.Leaves
+	}
+	return nil
+}
+
+// ScanEntry is a stored key and its version, or with deleted a tombstone
+// and the version the key was deleted at.
+type ScanEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Deleted       bool                   `protobuf:"varint,3,opt,name=deleted,proto3" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScanEntry) Reset() {
+	*x = ScanEntry{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScanEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScanEntry) ProtoMessage() {}
+
+func (x *ScanEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScanEntry.ProtoReflect.Descriptor instead.
+func (*ScanEntry) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ScanEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ScanEntry) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ScanEntry) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
+// CompareAndSetRequest writes item only if the key holds a value at
+// expected_version.
+type CompareAndSetRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Item            *SetRequest            `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
+	ExpectedVersion int64                  `protobuf:"varint,2,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *CompareAndSetRequest) Reset() {
+	*x = CompareAndSetRequest{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompareAndSetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareAndSetRequest) ProtoMessage() {}
+
+func (x *CompareAndSetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareAndSetRequest.ProtoReflect.Descriptor instead.
+func (*CompareAndSetRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CompareAndSetRequest) GetItem() *SetRequest {
+	if x != nil {
+		return x.Item
+	}
+	return nil
+}
+
+func (x *CompareAndSetRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+// ConditionalSetResponse reports whether a conditional write went ahead.
+// A successful one is stored at a version above the one it replaced.
+type ConditionalSetResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// the version now stored: the new one on success, else the current
+	// one, 0 if the key is not stored
+	Version int64 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	// on success, the entry the write replaced, unset if there was none;
+	// it lets the write be undone if the backend refuses it
+	Previous      *GetResponse `protobuf:"bytes,3,opt,name=previous,proto3" json:"previous,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConditionalSetResponse) Reset() {
+	*x = ConditionalSetResponse{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConditionalSetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConditionalSetResponse) ProtoMessage() {}
+
+func (x *ConditionalSetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConditionalSetResponse.ProtoReflect.Descriptor instead.
+func (*ConditionalSetResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ConditionalSetResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ConditionalSetResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ConditionalSetResponse) GetPrevious() *GetResponse {
+	if x != nil {
+		return x.Previous
+	}
+	return nil
+}
+
+// CompareAndDeleteRequest deletes key only if it holds a value at
+// expected_version.
+type CompareAndDeleteRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Key             string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	ExpectedVersion int64                  `protobuf:"varint,2,opt,name=expected_version,json=expectedVersion,proto3" json:"expected_version,omitempty"`
+	// remove the node's copy without leaving a tombstone, as for a
+	// DeleteRequest with drop set
+	Drop          bool `protobuf:"varint,3,opt,name=drop,proto3" json:"drop,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompareAndDeleteRequest) Reset() {
+	*x = CompareAndDeleteRequest{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompareAndDeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareAndDeleteRequest) ProtoMessage() {}
+
+func (x *CompareAndDeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareAndDeleteRequest.ProtoReflect.Descriptor instead.
+func (*CompareAndDeleteRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *CompareAndDeleteRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *CompareAndDeleteRequest) GetExpectedVersion() int64 {
+	if x != nil {
+		return x.ExpectedVersion
+	}
+	return 0
+}
+
+func (x *CompareAndDeleteRequest) GetDrop() bool {
+	if x != nil {
+		return x.Drop
+	}
+	return false
+}
+
+// CompareAndDeleteResponse reports whether a conditional delete went ahead.
+type CompareAndDeleteResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// on success, the version of the delete's tombstone, above the one it
+	// removed, or 0 for a drop; else the version stored, 0 if the key is not stored
+	Version       int64 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CompareAndDeleteResponse) Reset() {
+	*x = CompareAndDeleteResponse{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CompareAndDeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompareAndDeleteResponse) ProtoMessage() {}
+
+func (x *CompareAndDeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompareAndDeleteResponse.ProtoReflect.Descriptor instead.
+func (*CompareAndDeleteResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *CompareAndDeleteResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CompareAndDeleteResponse) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// IncrRequest adds delta to, or for Decr subtracts it from, the integer
+// stored as a decimal string under key.
+type IncrRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Key      string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Delta    uint64                 `protobuf:"varint,2,opt,name=delta,proto3" json:"delta,omitempty"`
+	Overflow Overflow               `protobuf:"varint,3,opt,name=overflow,proto3,enum=cache.Overflow" json:"overflow,omitempty"`
+	// with create, a missing key counts as initial and is created with a
+	// time to live of ttl_ms (0 meaning none); otherwise it is not found.
+	// An existing key keeps its ttl.
+	Create        bool  `protobuf:"varint,4,opt,name=create,proto3" json:"create,omitempty"`
+	Initial       int64 `protobuf:"varint,5,opt,name=initial,proto3" json:"initial,omitempty"`
+	TtlMs         int64 `protobuf:"varint,6,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IncrRequest) Reset() {
+	*x = IncrRequest{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IncrRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrRequest) ProtoMessage() {}
+
+func (x *IncrRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrRequest.ProtoReflect.Descriptor instead.
+func (*IncrRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *IncrRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *IncrRequest) GetDelta() uint64 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+func (x *IncrRequest) GetOverflow() Overflow {
+	if x != nil {
+		return x.Overflow
+	}
+	return Overflow_OVERFLOW_FAIL
+}
+
+func (x *IncrRequest) GetCreate() bool {
+	if x != nil {
+		return x.Create
+	}
+	return false
+}
+
+func (x *IncrRequest) GetInitial() int64 {
+	if x != nil {
+		return x.Initial
+	}
+	return 0
+}
+
+func (x *IncrRequest) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+// CounterResponse holds the entry a counter operation left, not found if
+// the key was missing and not created, and the entry it replaced, unset if
+// there was none.
+type CounterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Item          *GetResponse           `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
+	Previous      *GetResponse           `protobuf:"bytes,2,opt,name=previous,proto3" json:"previous,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CounterResponse) Reset() {
+	*x = CounterResponse{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CounterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CounterResponse) ProtoMessage() {}
+
+func (x *CounterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CounterResponse.ProtoReflect.Descriptor instead.
+func (*CounterResponse) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *CounterResponse) GetItem() *GetResponse {
+	if x != nil {
+		return x.Item
+	}
+	return nil
+}
+
+func (x *CounterResponse) GetPrevious() *GetResponse {
+	if x != nil {
+		return x.Previous
+	}
+	return nil
+}
+
+// IncrByFloatRequest adds delta to the number stored under key, as
+// IncrRequest does.
+type IncrByFloatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Delta         float64                `protobuf:"fixed64,2,opt,name=delta,proto3" json:"delta,omitempty"`
+	Create        bool                   `protobuf:"varint,3,opt,name=create,proto3" json:"create,omitempty"`
+	Initial       float64                `protobuf:"fixed64,4,opt,name=initial,proto3" json:"initial,omitempty"`
+	TtlMs         int64                  `protobuf:"varint,5,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IncrByFloatRequest) Reset() {
+	*x = IncrByFloatRequest{}
+	mi := &file_shared_proto_cache_node_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IncrByFloatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IncrByFloatRequest) ProtoMessage() {}
+
+func (x *IncrByFloatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_shared_proto_cache_node_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IncrByFloatRequest.ProtoReflect.Descriptor instead.
+func (*IncrByFloatRequest) Descriptor() ([]byte, []int) {
+	return file_shared_proto_cache_node_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *IncrByFloatRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *IncrByFloatRequest) GetDelta() float64 {
+	if x != nil {
+		return x.Delta
+	}
+	return 0
+}
+
+func (x *IncrByFloatRequest) GetCreate() bool {
+	if x != nil {
+		return x.Create
+	}
+	return false
+}
+
+func (x *IncrByFloatRequest) GetInitial() float64 {
+	if x != nil {
+		return x.Initial
+	}
+	return 0
+}
+
+func (x *IncrByFloatRequest) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+var File_shared_proto_cache_node_proto protoreflect.FileDescriptor
+
+const file_shared_proto_cache_node_proto_rawDesc = "" +
+	"\n" +
+	"\x1dshared/proto/cache-node.proto\x12\x05cache\"\x1e\n" +
+	"\n" +
+	"GetRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"\xf0\x01\n" +
+	"\vGetResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\x12\x14\n" +
+	"\x05found\x18\x02 \x01(\bR\x05found\x12\x15\n" +
+	"\x06ttl_ms\x18\x03 \x01(\x03R\x05ttlMs\x12\x14\n" +
+	"\x05flags\x18\x04 \x01(\rR\x05flags\x12\x14\n" +
+	"\x05stale\x18\x05 \x01(\bR\x05stale\x12\x1e\n" +
+	"\n" +
+	"revalidate\x18\x06 \x01(\bR\n" +
+	"revalidate\x12\x1e\n" +
+	"\vsoft_ttl_ms\x18\a \x01(\x03R\tsoftTtlMs\x12\x18\n" +
+	"\aversion\x18\b \x01(\x03R\aversion\x12\x18\n" +
+	"\adeleted\x18\t \x01(\bR\adeleted\"\x9b\x01\n" +
+	"\n" +
+	"SetRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\fR\x05value\x12\x15\n" +
+	"\x06ttl_ms\x18\x03 \x01(\x03R\x05ttlMs\x12\x14\n" +
+	"\x05flags\x18\x04 \x01(\rR\x05flags\x12\x1e\n" +
+	"\vsoft_ttl_ms\x18\x05 \x01(\x03R\tsoftTtlMs\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\x03R\aversion\"Q\n" +
+	"\vSetResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\x05R\x04code\"\x13\n" +
+	"\x11GetAllKeysRequest\"(\n" +
+	"\x12GetAllKeysResponse\x12\x12\n" +
+	"\x04keys\x18\x01 \x03(\tR\x04keys\"O\n" +
+	"\rDeleteRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\x12\x12\n" +
+	"\x04drop\x18\x03 \x01(\bR\x04drop\"*\n" +
+	"\x0eDeleteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"!\n" +
+	"\rExistsRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"&\n" +
+	"\x0eExistsResponse\x12\x14\n" +
+	"\x05found\x18\x01 \x01(\bR\x05found\"!\n" +
+	"\vMGetRequest\x12\x12\n" +
+	"\x04keys\x18\x01 \x03(\tR\x04keys\"8\n" +
+	"\fMGetResponse\x12(\n" +
+	"\x05items\x18\x01 \x03(\v2\x12.cache.GetResponseR\x05items\"6\n" +
+	"\vMSetRequest\x12'\n" +
+	"\x05items\x18\x01 \x03(\v2\x11.cache.SetRequestR\x05items\"8\n" +
+	"\fMSetResponse\x12(\n" +
+	"\x05items\x18\x01 \x03(\v2\x12.cache.SetResponseR\x05items\">\n" +
+	"\x0eMDeleteRequest\x12\x12\n" +
+	"\x04keys\x18\x01 \x03(\tR\x04keys\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"+\n" +
+	"\x0fMDeleteResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x03(\bR\adeleted\"*\n" +
+	"\bKeyRange\x12\x0e\n" +
+	"\x02lo\x18\x01 \x01(\rR\x02lo\x12\x0e\n" +
+	"\x02hi\x18\x02 \x01(\rR\x02hi\"R\n" +
+	"\x11MerkleTreeRequest\x12'\n" +
+	"\x06ranges\x18\x01 \x03(\v2\x0f.cache.KeyRangeR\x06ranges\x12\x14\n" +
+	"\x05depth\x18\x02 \x01(\rR\x05depth\"*\n" +
+	"\x12MerkleTreeResponse\x12\x14\n" +
+	"\x05nodes\x18\x01 \x03(\x04R\x05nodes\"d\n" +
+	"\vScanRequest\x12'\n" +
+	"\x06ranges\x18\x01 \x03(\v2\x0f.cache.KeyRangeR\x06ranges\x12\x14\n" +
+	"\x05depth\x18\x02 \x01(\rR\x05depth\x12\x16\n" +
+	"\x06leaves\x18\x03 \x03(\rR\x06leaves\"Q\n" +
+	"\tScanEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\x12\x18\n" +
+	"\adeleted\x18\x03 \x01(\bR\adeleted\"h\n" +
+	"\x14CompareAndSetRequest\x12%\n" +
+	"\x04item\x18\x01 \x01(\v2\x11.cache.SetRequestR\x04item\x12)\n" +
+	"\x10expected_version\x18\x02 \x01(\x03R\x0fexpectedVersion\"|\n" +
+	"\x16ConditionalSetResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\x12.\n" +
+	"\bprevious\x18\x03 \x01(\v2\x12.cache.GetResponseR\bprevious\"j\n" +
+	"\x17CompareAndDeleteRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12)\n" +
+	"\x10expected_version\x18\x02 \x01(\x03R\x0fexpectedVersion\x12\x12\n" +
+	"\x04drop\x18\x03 \x01(\bR\x04drop\"N\n" +
+	"\x18CompareAndDeleteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"\xab\x01\n" +
+	"\vIncrRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05delta\x18\x02 \x01(\x04R\x05delta\x12+\n" +
+	"\boverflow\x18\x03 \x01(\x0e2\x0f.cache.OverflowR\boverflow\x12\x16\n" +
+	"\x06create\x18\x04 \x01(\bR\x06create\x12\x18\n" +
+	"\ainitial\x18\x05 \x01(\x03R\ainitial\x12\x15\n" +
+	"\x06ttl_ms\x18\x06 \x01(\x03R\x05ttlMs\"i\n" +
+	"\x0fCounterResponse\x12&\n" +
+	"\x04item\x18\x01 \x01(\v2\x12.cache.GetResponseR\x04item\x12.\n" +
+	"\bprevious\x18\x02 \x01(\v2\x12.cache.GetResponseR\bprevious\"\x85\x01\n" +
+	"\x12IncrByFloatRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05delta\x18\x02 \x01(\x01R\x05delta\x12\x16\n" +
+	"\x06create\x18\x03 \x01(\bR\x06create\x12\x18\n" +
+	"\ainitial\x18\x04 \x01(\x01R\ainitial\x12\x15\n" +
+	"\x06ttl_ms\x18\x05 \x01(\x03R\x05ttlMs*4\n" +
+	"\bOverflow\x12\x11\n" +
+	"\rOVERFLOW_FAIL\x10\x00\x12\x15\n" +
+	"\x11OVERFLOW_UNSIGNED\x10\x012\xf2\a\n" +
+	"\x05Cache\x12,\n" +
+	"\x03Get\x12\x11.cache.GetRequest\x1a\x12.cache.GetResponse\x12,\n" +
+	"\x03Set\x12\x11.cache.SetRequest\x1a\x12.cache.SetResponse\x12A\n" +
+	"\n" +
+	"GetAllKeys\x12\x18.cache.GetAllKeysRequest\x1a\x19.cache.GetAllKeysResponse\x125\n" +
+	"\x06Delete\x12\x14.cache.DeleteRequest\x1a\x15.cache.DeleteResponse\x125\n" +
+	"\x06Exists\x12\x14.cache.ExistsRequest\x1a\x15.cache.ExistsResponse\x12/\n" +
+	"\x04MGet\x12\x12.cache.MGetRequest\x1a\x13.cache.MGetResponse\x12/\n" +
+	"\x04MSet\x12\x12.cache.MSetRequest\x1a\x13.cache.MSetResponse\x128\n" +
+	"\aMDelete\x12\x15.cache.MDeleteRequest\x1a\x16.cache.MDeleteResponse\x12A\n" +
+	"\n" +
+	"MerkleTree\x12\x18.cache.MerkleTreeRequest\x1a\x19.cache.MerkleTreeResponse\x12.\n" +
+	"\x04Scan\x12\x12.cache.ScanRequest\x1a\x10.cache.ScanEntry0\x01\x12K\n" +
+	"\rCompareAndSet\x12\x1b.cache.CompareAndSetRequest\x1a\x1d.cache.ConditionalSetResponse\x12?\n" +
+	"\vSetIfAbsent\x12\x11.cache.SetRequest\x1a\x1d.cache.ConditionalSetResponse\x12@\n" +
+	"\fSetIfPresent\x12\x11.cache.SetRequest\x1a\x1d.cache.ConditionalSetResponse\x12S\n" +
+	"\x10CompareAndDelete\x12\x1e.cache.CompareAndDeleteRequest\x1a\x1f.cache.CompareAndDeleteResponse\x122\n" +
+	"\x04Incr\x12\x12.cache.IncrRequest\x1a\x16.cache.CounterResponse\x122\n" +
+	"\x04Decr\x12\x12.cache.IncrRequest\x1a\x16.cache.CounterResponse\x12@\n" +
+	"\vIncrByFloat\x12\x19.cache.IncrByFloatRequest\x1a\x16.cache.CounterResponseB\x1aZ\x18shared/proto/cacheNodepbb\x06proto3"
 
 var (
 	file_shared_proto_cache_node_proto_rawDescOnce sync.Once
@@ -424,31 +1824,89 @@ func file_shared_proto_cache_node_proto_rawDescGZIP() []byte {
 	return file_shared_proto_cache_node_proto_rawDescData
 }
 
-var file_shared_proto_cache_node_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_shared_proto_cache_node_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_shared_proto_cache_node_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_shared_proto_cache_node_proto_goTypes = []any{
-	(*GetRequest)(nil),         // 0: cache.GetRequest
-	(*GetResponse)(nil),        // 1: cache.GetResponse
-	(*SetRequest)(nil),         // 2: cache.SetRequest
-	(*SetResponse)(nil),        // 3: cache.SetResponse
-	(*GetAllKeysRequest)(nil),  // 4: cache.GetAllKeysRequest
-	(*GetAllKeysResponse)(nil), // 5: cache.GetAllKeysResponse
-	(*DeleteRequest)(nil),      // 6: cache.DeleteRequest
-	(*DeleteResponse)(nil),     // 7: cache.DeleteResponse
+	(Overflow)(0),                    // 0: cache.Overflow
+	(*GetRequest)(nil),               // 1: cache.GetRequest
+	(*GetResponse)(nil),              // 2: cache.GetResponse
+	(*SetRequest)(nil),               // 3: cache.SetRequest
+	(*SetResponse)(nil),              // 4: cache.SetResponse
+	(*GetAllKeysRequest)(nil),        // 5: cache.GetAllKeysRequest
+	(*GetAllKeysResponse)(nil),       // 6: cache.GetAllKeysResponse
+	(*DeleteRequest)(nil),            // 7: cache.DeleteRequest
+	(*DeleteResponse)(nil),           // 8: cache.DeleteResponse
+	(*ExistsRequest)(nil),            // 9: cache.ExistsRequest
+	(*ExistsResponse)(nil),           // 10: cache.ExistsResponse
+	(*MGetRequest)(nil),              // 11: cache.MGetRequest
+	(*MGetResponse)(nil),             // 12: cache.MGetResponse
+	(*MSetRequest)(nil),              // 13: cache.MSetRequest
+	(*MSetResponse)(nil),             // 14: cache.MSetResponse
+	(*MDeleteRequest)(nil),           // 15: cache.MDeleteRequest
+	(*MDeleteResponse)(nil),          // 16: cache.MDeleteResponse
+	(*KeyRange)(nil),                 // 17: cache.KeyRange
+	(*MerkleTreeRequest)(nil),        // 18: cache.MerkleTreeRequest
+	(*MerkleTreeResponse)(nil),       // 19: cache.MerkleTreeResponse
+	(*ScanRequest)(nil),              // 20: cache.ScanRequest
+	(*ScanEntry)(nil),                // 21: cache.ScanEntry
+	(*CompareAndSetRequest)(nil),     // 22: cache.CompareAndSetRequest
+	(*ConditionalSetResponse)(nil),   // 23: cache.ConditionalSetResponse
+	(*CompareAndDeleteRequest)(nil),  // 24: cache.CompareAndDeleteRequest
+	(*CompareAndDeleteResponse)(nil), // 25: cache.CompareAndDeleteResponse
+	(*IncrRequest)(nil),              // 26: cache.IncrRequest
+	(*CounterResponse)(nil),          // 27: cache.CounterResponse
+	(*IncrByFloatRequest)(nil),       // 28: cache.IncrByFloatRequest
 }
 var file_shared_proto_cache_node_proto_depIdxs = []int32{
-	0, // 0: cache.Cache.Get:input_type -> cache.GetRequest
-	2, // 1: cache.Cache.Set:input_type -> cache.SetRequest
-	4, // 2: cache.Cache.GetAllKeys:input_type -> cache.GetAllKeysRequest
-	6, // 3: cache.Cache.Delete:input_type -> cache.DeleteRequest
-	1, // 4: cache.Cache.Get:output_type -> cache.GetResponse
-	3, // 5: cache.Cache.Set:output_type -> cache.SetResponse
-	5, // 6: cache.Cache.GetAllKeys:output_type -> cache.GetAllKeysResponse
-	7, // 7: cache.Cache.Delete:output_type -> cache.DeleteResponse
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2,  // 0: cache.MGetResponse.items:type_name -> cache.GetResponse
+	3,  // 1: cache.MSetRequest.items:type_name -> cache.SetRequest
+	4,  // 2: cache.MSetResponse.items:type_name -> cache.SetResponse
+	17, // 3: cache.MerkleTreeRequest.ranges:type_name -> cache.KeyRange
+	17, // 4: cache.ScanRequest.ranges:type_name -> cache.KeyRange
+	3,  // 5: cache.CompareAndSetRequest.item:type_name -> cache.SetRequest
+	2,  // 6: cache.ConditionalSetResponse.previous:type_name -> cache.GetResponse
+	0,  // 7: cache.IncrRequest.overflow:type_name -> cache.Overflow
+	2,  // 8: cache.CounterResponse.item:type_name -> cache.GetResponse
+	2,  // 9: cache.CounterResponse.previous:type_name -> cache.GetResponse
+	1,  // 10: cache.Cache.Get:input_type -> cache.GetRequest
+	3,  // 11: cache.Cache.Set:input_type -> cache.SetRequest
+	5,  // 12: cache.Cache.GetAllKeys:input_type -> cache.GetAllKeysRequest
+	7,  // 13: cache.Cache.Delete:input_type -> cache.DeleteRequest
+	9,  // 14: cache.Cache.Exists:input_type -> cache.ExistsRequest
+	11, // 15: cache.Cache.MGet:input_type -> cache.MGetRequest
+	13, // 16: cache.Cache.MSet:input_type -> cache.MSetRequest
+	15, // 17: cache.Cache.MDelete:input_type -> cache.MDeleteRequest
+	18, // 18: cache.Cache.MerkleTree:input_type -> cache.MerkleTreeRequest
+	20, // 19: cache.Cache.Scan:input_type -> cache.ScanRequest
+	22, // 20: cache.Cache.CompareAndSet:input_type -> cache.CompareAndSetRequest
+	3,  // 21: cache.Cache.SetIfAbsent:input_type -> cache.SetRequest
+	3,  // 22: cache.Cache.SetIfPresent:input_type -> cache.SetRequest
+	24, // 23: cache.Cache.CompareAndDelete:input_type -> cache.CompareAndDeleteRequest
+	26, // 24: cache.Cache.Incr:input_type -> cache.IncrRequest
+	26, // 25: cache.Cache.Decr:input_type -> cache.IncrRequest
+	28, // 26: cache.Cache.IncrByFloat:input_type -> cache.IncrByFloatRequest
+	2,  // 27: cache.Cache.Get:output_type -> cache.GetResponse
+	4,  // 28: cache.Cache.Set:output_type -> cache.SetResponse
+	6,  // 29: cache.Cache.GetAllKeys:output_type -> cache.GetAllKeysResponse
+	8,  // 30: cache.Cache.Delete:output_type -> cache.DeleteResponse
+	10, // 31: cache.Cache.Exists:output_type -> cache.ExistsResponse
+	12, // 32: cache.Cache.MGet:output_type -> cache.MGetResponse
+	14, // 33: cache.Cache.MSet:output_type -> cache.MSetResponse
+	16, // 34: cache.Cache.MDelete:output_type -> cache.MDeleteResponse
+	19, // 35: cache.Cache.MerkleTree:output_type -> cache.MerkleTreeResponse
+	21, // 36: cache.Cache.Scan:output_type -> cache.ScanEntry
+	23, // 37: cache.Cache.CompareAndSet:output_type -> cache.ConditionalSetResponse
+	23, // 38: cache.Cache.SetIfAbsent:output_type -> cache.ConditionalSetResponse
+	23, // 39: cache.Cache.SetIfPresent:output_type -> cache.ConditionalSetResponse
+	25, // 40: cache.Cache.CompareAndDelete:output_type -> cache.CompareAndDeleteResponse
+	27, // 41: cache.Cache.Incr:output_type -> cache.CounterResponse
+	27, // 42: cache.Cache.Decr:output_type -> cache.CounterResponse
+	27, // 43: cache.Cache.IncrByFloat:output_type -> cache.CounterResponse
+	27, // [27:44] is the sub-list for method output_type
+	10, // [10:27] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
 }
 
 func init() { file_shared_proto_cache_node_proto_init() }
@@ -461,13 +1919,14 @@ func file_shared_proto_cache_node_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_shared_proto_cache_node_proto_rawDesc), len(file_shared_proto_cache_node_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   8,
+			NumEnums:      1,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_shared_proto_cache_node_proto_goTypes,
 		DependencyIndexes: file_shared_proto_cache_node_proto_depIdxs,
+		EnumInfos:         file_shared_proto_cache_node_proto_enumTypes,
 		MessageInfos:      file_shared_proto_cache_node_proto_msgTypes,
 	}.Build()
 	File_shared_proto_cache_node_proto = out.File
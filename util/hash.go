@@ -3,6 +3,7 @@ package util
 import (
 	"crypto/sha256"
 	"math/big"
+	"strconv"
 )
 
 const total_slots = uint64(1) << 32
@@ -12,3 +13,20 @@ func Hash(id string) uint32 {
 	num := new(big.Int).SetBytes(h[:])
 	return uint32(new(big.Int).Mod(num, big.NewInt(int64(total_slots))).Int64())
 }
+
+// VnodeHash returns the ring position of the i-th virtual node of addr.
+func VnodeHash(addr string, i int) uint32 {
+	return Hash(addr + "#" + strconv.Itoa(i))
+}
+
+// InRange reports whether h falls in the ring range (lo, hi], wrapping past
+// zero when lo > hi. lo == hi denotes the whole ring.
+func InRange(h, lo, hi uint32) bool {
+	if lo == hi {
+		return true
+	}
+	if lo > hi {
+		return h > lo || h <= hi
+	}
+	return h > lo && h <= hi
+}